@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pah-an/infohub/internal/bulk"
+	"github.com/pah-an/infohub/internal/config"
+	"github.com/pah-an/infohub/internal/domain"
+	"github.com/pah-an/infohub/internal/storage"
+)
+
+const defaultConfigPath = "configs/config.yaml"
+
+// runCommand выполняет служебную команду CLI и возвращает код выхода.
+// Второе значение false означает, что аргументы не являются командой
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	switch args[0] {
+	case "export":
+		return runExport(args[1:]), true
+	case "import":
+		return runImport(args[1:]), true
+	default:
+		return 0, false
+	}
+}
+
+// runExport выгружает новости из хранилища в файл или stdout
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "path to config file")
+	formatName := fs.String("format", "ndjson", "output format: ndjson or csv")
+	from := fs.String("from", "", "export news published at or after this time (RFC3339)")
+	to := fs.String("to", "", "export news published at or before this time (RFC3339)")
+	output := fs.String("output", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	format, err := bulk.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var timeRange bulk.TimeRange
+	if timeRange.From, err = parseOptionalTime(*from); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -from: %v\n", err)
+		return 2
+	}
+	if timeRange.To, err = parseOptionalTime(*to); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -to: %v\n", err)
+		return 2
+	}
+
+	repo, err := openNewsRepository(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open storage: %v\n", err)
+		return 1
+	}
+	defer closeNewsRepository(repo)
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create output file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := bulk.Export(w, repo, format, timeRange)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export failed after %d records: %v\n", count, err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d news\n", count)
+	return 0
+}
+
+// runImport загружает новости из файла или stdin в хранилище.
+// Файловое и журнальное хранилища не рассчитаны на запись из двух процессов,
+// поэтому для них команду следует выполнять при остановленном сервере;
+// работающий сервер принимает тот же поток через POST /api/v1/admin/import
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "path to config file")
+	formatName := fs.String("format", "ndjson", "input format: ndjson or csv")
+	input := fs.String("input", "-", "input file, - for stdin")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	format, err := bulk.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	repo, err := openNewsRepository(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open storage: %v\n", err)
+		return 1
+	}
+	defer closeNewsRepository(repo)
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to open input file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+
+	result, err := bulk.Import(r, repo, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import failed after %d records: %v\n", result.Imported, err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %d news, %d total in storage\n", result.Imported, result.Total)
+	return 0
+}
+
+// openNewsRepository открывает хранилище новостей согласно файлу конфигурации
+func openNewsRepository(configPath string) (domain.NewsRepository, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return newNewsRepository(cfg.Cache)
+}
+
+// newNewsRepository создает хранилище новостей выбранного типа
+func newNewsRepository(cfg config.CacheConfig) (domain.NewsRepository, error) {
+	switch cfg.Backend {
+	case "file", "":
+		fileCache, err := storage.NewFileCache(storage.FileConfig{
+			Path:        cfg.FilePath,
+			Backups:     cfg.Backups,
+			Compression: cfg.Compression,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return fileCache, nil
+	case "wal":
+		wal, err := storage.NewWAL(cfg.WAL)
+		if err != nil {
+			return nil, err
+		}
+		return wal, nil
+	case "sqlite":
+		store, err := storage.NewSQLStore(cfg.SQL)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+	}
+}
+
+// closeNewsRepository закрывает хранилище, если оно держит ресурсы
+func closeNewsRepository(repo domain.NewsRepository) error {
+	if closer, ok := repo.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// parseOptionalTime разбирает время в формате RFC3339, пустая строка дает нулевое время
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -15,6 +15,7 @@ import (
 
 	_ "github.com/pah-an/infohub/docs"
 	"github.com/pah-an/infohub/internal/aggregator"
+	"github.com/pah-an/infohub/internal/audit"
 	"github.com/pah-an/infohub/internal/auth"
 	"github.com/pah-an/infohub/internal/cache"
 	"github.com/pah-an/infohub/internal/collector"
@@ -23,8 +24,10 @@ import (
 	"github.com/pah-an/infohub/internal/health"
 	"github.com/pah-an/infohub/internal/logger"
 	"github.com/pah-an/infohub/internal/metrics"
+	"github.com/pah-an/infohub/internal/middleware"
 	"github.com/pah-an/infohub/internal/server"
 	"github.com/pah-an/infohub/internal/storage"
+	"github.com/pah-an/infohub/internal/usage"
 )
 
 // Package main InfoHub API
@@ -63,6 +66,11 @@ var (
 )
 
 func main() {
+	// Служебные команды (export, import) выполняются без запуска сервера
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
 	fmt.Printf("Starting InfoHub API v%s (commit: %s, built: %s)\n",
 		version, gitCommit, buildTime)
 	fmt.Printf("Go version: %s\n", runtime.Version())
@@ -105,19 +113,39 @@ func main() {
 	}
 
 	// Инициализируем кэш
+	newMemoryCache := func() *cache.MemoryCache {
+		memoryCache, err := cache.NewMemoryCache(cfg.MemoryCache)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to create memory cache")
+		}
+		if appMetrics != nil {
+			memoryCache.SetStatsRecorder(appMetrics)
+		}
+		return memoryCache
+	}
+
 	var cacheSystem cache.Cache
+	var redisConnected bool
 	if cfg.Redis.Enabled {
 		cfg.Redis.Address = cfg.GetRedisAddress()
 		redisCache, err := cache.NewRedisCache(cfg.Redis)
+		redisConnected = err == nil
 		if err != nil {
 			appLogger.WithError(err).Warn("Failed to connect to Redis, using memory cache")
-			cacheSystem = cache.NewMemoryCache(cfg.Redis.TTL, 10*time.Minute)
+			cacheSystem = newMemoryCache()
+		} else if cfg.Redis.Tiered.Enabled {
+			tieredCache, err := cache.NewTieredCache(newMemoryCache(), redisCache, cfg.Redis.Tiered)
+			if err != nil {
+				appLogger.WithError(err).Fatal("Failed to create tiered cache")
+			}
+			cacheSystem = tieredCache
+			appLogger.WithField("address", cfg.Redis.Address).Info("Connected to Redis cache with in-memory L1")
 		} else {
 			cacheSystem = redisCache
 			appLogger.WithField("address", cfg.Redis.Address).Info("Connected to Redis cache")
 		}
 	} else {
-		cacheSystem = cache.NewMemoryCache(5*time.Minute, 10*time.Minute)
+		cacheSystem = newMemoryCache()
 		appLogger.Info("Using in-memory cache")
 	}
 
@@ -125,8 +153,8 @@ func main() {
 	healthManager := health.NewManager(cfg.Health.Timeout)
 
 	if cfg.Health.Checks.Redis && cfg.Redis.Enabled {
-		if redisCache, ok := cacheSystem.(*cache.RedisCache); ok {
-			healthManager.RegisterCheck("redis", health.RedisCheck(redisCache.Ping))
+		if pinger, ok := cacheSystem.(interface{ Ping(context.Context) error }); ok {
+			healthManager.RegisterCheck("redis", health.RedisCheck(pinger.Ping))
 		}
 	}
 
@@ -140,26 +168,69 @@ func main() {
 
 	// Создаем менеджер аутентификации
 	var authManager *auth.Manager
+	var authStore cache.Cache
 	if cfg.Auth.Enabled {
 		authManager, err = auth.NewManager(cfg.Auth)
 		if err != nil {
 			appLogger.WithError(err).Fatal("Failed to initialize authentication")
 		}
+		// Отзыв токенов, сессии и nonce хранятся отдельно от кэша данных: записи
+		// нельзя вытеснить по max_entries или удалить через /admin/cache.
+		// С Redis хранилище общее, чтобы выход действовал на всех репликах
+		authStore, err = cache.NewAuthStore(cfg.Redis, redisConnected)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to create authentication store")
+		}
+		authManager.SetTokenStore(authStore)
 		appLogger.Info("Authentication system enabled")
 	} else {
 		authManager, _ = auth.NewManager(auth.Config{Enabled: false})
 		appLogger.Info("Authentication disabled (development mode)")
 	}
 
+	// Учет использования API и квоты
+	var usageTracker *usage.Tracker
+	if cfg.Usage.Enabled {
+		usageTracker, err = usage.NewTracker(cfg.Usage)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to initialize usage accounting")
+		}
+		appLogger.Info("API usage accounting enabled")
+	}
+
+	// Журнал аудита входов и действий администраторов
+	var auditLog *audit.Log
+	if cfg.Audit.Enabled {
+		auditLog, err = audit.Open(cfg.Audit.FilePath)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to open audit log")
+		}
+		appLogger.WithField("path", cfg.Audit.FilePath).Info("Audit log enabled")
+	}
+
 	newsChannel := make(chan domain.NewsList, 100)
 	errorChannel := make(chan error, 100)
 
 	// Создаем хранилище с кэшированием
-	fileStorage := storage.NewFileCache(cfg.Cache.FilePath)
-	cachedStorage := cache.NewCachedNewsRepository(cacheSystem, fileStorage, 5*time.Minute)
+	newsStorage, err := newNewsRepository(cfg.Cache)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to open news storage")
+	}
+	appLogger.WithField("backend", cfg.Cache.Backend).Info("News storage opened")
+
+	if sqlStore, ok := newsStorage.(*storage.SQLStore); ok {
+		healthManager.RegisterCheck("database", health.DatabaseCheck(sqlStore.Ping))
+	}
+	cachedStorage := cache.NewCachedNewsRepository(cacheSystem, newsStorage, cache.RepositoryConfig{
+		TTL:      5 * time.Minute,
+		StaleTTL: cfg.Redis.StaleTTL,
+	})
 
 	// Создаем агрегатор и загружаем кэшированные новости при старте
-	agg := aggregator.New(cachedStorage)
+	agg, err := aggregator.New(cachedStorage, cfg.Retention)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to create aggregator")
+	}
 	if err = agg.LoadFromRepository(); err != nil {
 		appLogger.WithError(err).Warn("Failed to load cached news")
 	} else {
@@ -180,20 +251,32 @@ func main() {
 		}
 	}
 
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.Security.TrustedProxies)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Invalid trusted proxies")
+	}
+
 	srv := server.NewInfoHubServer(server.Config{
-		Host:          cfg.Server.Host,
-		Port:          cfg.Server.Port,
-		ReadTimeout:   cfg.Server.ReadTimeout,
-		WriteTimeout:  cfg.Server.WriteTimeout,
-		IdleTimeout:   cfg.Server.IdleTimeout,
-		NewsProvider:  agg,
-		Logger:        appLogger,
-		Metrics:       appMetrics,
-		AuthManager:   authManager,
-		HealthManager: healthManager,
-		RateLimiting:  cfg.RateLimiting,
-		CORS:          cfg.CORS,
-		Security:      cfg.Security,
+		Host:           cfg.Server.Host,
+		Port:           cfg.Server.Port,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
+		IdleTimeout:    cfg.Server.IdleTimeout,
+		TLS:            cfg.Server.TLS,
+		NewsProvider:   agg,
+		NewsRepository: cachedStorage,
+		Cache:          cacheSystem,
+		Logger:         appLogger,
+		Metrics:        appMetrics,
+		AuthManager:    authManager,
+		Usage:          usageTracker,
+		Audit:          auditLog,
+		HealthManager:  healthManager,
+		RateLimiting:   cfg.RateLimiting,
+		CORS:           cfg.CORS,
+		Security:       cfg.Security,
+		ResponseCache:  cfg.HTTPCache,
+		TrustedProxies: trustedProxies,
 	})
 
 	// Запускаем профилирование, если включено
@@ -245,6 +328,22 @@ func main() {
 		}()
 	}
 
+	if authManager != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			authManager.RunKeyRotation(ctx)
+		}()
+	}
+
+	if usageTracker != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			usageTracker.Run(ctx)
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -276,10 +375,38 @@ func main() {
 		appLogger.Info("News saved to cache successfully")
 	}
 
+	if err = closeNewsRepository(newsStorage); err != nil {
+		appLogger.WithError(err).Error("Error closing news storage")
+	}
+
 	if err = cacheSystem.Close(); err != nil {
 		appLogger.WithError(err).Error("Error closing cache")
 	}
 
+	if authManager != nil {
+		if err = authManager.Close(); err != nil {
+			appLogger.WithError(err).Error("Error saving API key store")
+		}
+	}
+
+	if authStore != nil {
+		if err = authStore.Close(); err != nil {
+			appLogger.WithError(err).Error("Error closing authentication store")
+		}
+	}
+
+	if usageTracker != nil {
+		if err = usageTracker.Flush(); err != nil {
+			appLogger.WithError(err).Error("Error saving API usage")
+		}
+	}
+
+	if auditLog != nil {
+		if err = auditLog.Close(); err != nil {
+			appLogger.WithError(err).Error("Error closing audit log")
+		}
+	}
+
 	// Закрываем каналы
 	close(newsChannel)
 	close(errorChannel)
@@ -15,6 +15,87 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает записи журнала аудита о входах, ошибках аутентификации и действиях администраторов, от новых к старым (область admin:audit)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Журнал аудита",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Начало периода (RFC3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец периода (RFC3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Исполнитель",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Действие, например cache.clear",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимум записей (по умолчанию 100, не более 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.AuditResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/admin/cache/clear": {
             "post": {
                 "security": [
@@ -22,7 +103,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Очищает кэш новостей (только для администраторов)",
+                "description": "Удаляет ключи кэша по glob паттерну (по умолчанию все ключи). Требует область admin:cache",
                 "consumes": [
                     "application/json"
                 ],
@@ -33,6 +114,15 @@ const docTemplate = `{
                     "admin"
                 ],
                 "summary": "Очистить кэш",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "*",
+                        "description": "Glob паттерн ключей",
+                        "name": "pattern",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -51,18 +141,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/v1.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/admin/sources": {
+        "/admin/cache/keys": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает информацию о всех источниках новостей (только для администраторов)",
+                "description": "Возвращает ключи кэша по glob паттерну и, при values=true, их значения (область admin:cache)",
                 "consumes": [
                     "application/json"
                 ],
@@ -72,15 +174,40 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Получить информацию об источниках",
+                "summary": "Просмотреть ключи кэша",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "*",
+                        "description": "Glob паттерн ключей",
+                        "name": "pattern",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Вернуть значения ключей",
+                        "name": "values",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Максимум ключей в ответе",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/v1.AdminSourceResponse"
-                            }
+                            "$ref": "#/definitions/v1.AdminCacheKeysResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
                         }
                     },
                     "401": {
@@ -94,20 +221,105 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/v1.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/admin/stats": {
+        "/admin/export": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает статистику работы системы (только для администраторов)",
+                "description": "Потоково выгружает все новости из хранилища в формате NDJSON или CSV (область news:export)",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Выгрузить новости",
+                "parameters": [
+                    {
+                        "enum": [
+                            "ndjson",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Формат выгрузки (по умолчанию ndjson)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало диапазона published_at (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец диапазона published_at (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток новостей",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Потоково импортирует новости в формате NDJSON или CSV пачками и объединяет их с хранилищем по ID (область ingest). При ошибке сохраненные до нее пачки остаются в хранилище",
                 "consumes": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "produces": [
                     "application/json"
@@ -115,12 +327,30 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Получить статистику системы",
+                "summary": "Загрузить новости",
+                "parameters": [
+                    {
+                        "enum": [
+                            "ndjson",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Формат данных (по умолчанию определяется по Content-Type)",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/v1.AdminStatsResponse"
+                            "$ref": "#/definitions/bulk.ImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
                         }
                     },
                     "401": {
@@ -134,36 +364,65 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/v1.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/healthz": {
+        "/admin/keys": {
             "get": {
-                "description": "Возвращает статус работы сервиса",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает метаданные выпущенных API ключей, включая отозванные (область admin:keys)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "admin"
                 ],
-                "summary": "Проверка состояния сервиса",
+                "summary": "Список API ключей",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/v1.HealthResponse"
+                            "$ref": "#/definitions/v1.APIKeysResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/login": {
+            },
             "post": {
-                "description": "Авторизация по API ключу и получение JWT токена",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает новый API ключ. Полный ключ возвращается только в этом ответе, в хранилище сохраняется его хэш (область admin:keys)",
                 "consumes": [
                     "application/json"
                 ],
@@ -171,25 +430,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Авторизация пользователя",
+                "summary": "Выпустить API ключ",
                 "parameters": [
                     {
-                        "description": "Данные для авторизации",
+                        "description": "Параметры ключа",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1.LoginRequest"
+                            "$ref": "#/definitions/v1.CreateAPIKeyRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/v1.LoginResponse"
+                            "$ref": "#/definitions/v1.CreateAPIKeyResponse"
                         }
                     },
                     "400": {
@@ -203,13 +462,655 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/v1.ErrorResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/news": {
-            "get": {
-                "description": "Возвращает последние новости, агрегированные из всех источников",
+        "/admin/keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Немедленно отзывает API ключ. Метаданные ключа сохраняются (область admin:keys)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Отозвать API ключ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор ключа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.APIKeyInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/sources": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает информацию о всех источниках новостей (область sources:read)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Получить информацию об источниках",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/v1.AdminSourceResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/stats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает статистику работы системы (область admin:stats)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Получить статистику системы",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.AdminStatsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает число запросов и объем ответов за текущие сутки и месяц по каждому ключу и пользователю вместе с их квотами (область admin:usage)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Использование API по ключам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор ключа или пользователя",
+                        "name": "consumer",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.UsageListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/feeds/{format}": {
+            "get": {
+                "description": "Возвращает агрегированные новости в формате RSS 2.0, Atom 1.0 или JSON Feed 1.1",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "feeds"
+                ],
+                "summary": "Получить ленту новостей",
+                "parameters": [
+                    {
+                        "enum": [
+                            "rss",
+                            "atom",
+                            "jsonfeed"
+                        ],
+                        "type": "string",
+                        "description": "Формат ленты",
+                        "name": "format",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Количество новостей (по умолчанию 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по источнику",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по категории",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Лента новостей",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/feeds/{format}/categories/{category}": {
+            "get": {
+                "description": "Возвращает новости одной категории в формате RSS 2.0, Atom 1.0 или JSON Feed 1.1",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "feeds"
+                ],
+                "summary": "Получить ленту новостей категории",
+                "parameters": [
+                    {
+                        "enum": [
+                            "rss",
+                            "atom",
+                            "jsonfeed"
+                        ],
+                        "type": "string",
+                        "description": "Формат ленты",
+                        "name": "format",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория",
+                        "name": "category",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Количество новостей (по умолчанию 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Лента новостей",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/feeds/{format}/sources/{source}": {
+            "get": {
+                "description": "Возвращает новости одного источника в формате RSS 2.0, Atom 1.0 или JSON Feed 1.1",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "feeds"
+                ],
+                "summary": "Получить ленту новостей источника",
+                "parameters": [
+                    {
+                        "enum": [
+                            "rss",
+                            "atom",
+                            "jsonfeed"
+                        ],
+                        "type": "string",
+                        "description": "Формат ленты",
+                        "name": "format",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Название источника",
+                        "name": "source",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Количество новостей (по умолчанию 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Лента новостей",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Возвращает статус работы сервиса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Проверка состояния сервиса",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Авторизация по API ключу. Возвращает короткоживущий access токен и refresh токен для его обновления",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Авторизация пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные для авторизации",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает access токен из заголовка Authorization и, если передан, refresh токен вместе со всей сессией",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Выйти",
+                "parameters": [
+                    {
+                        "description": "Refresh токен",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/v1.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.LogoutResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает число запросов и объем ответов текущего ключа за сутки и месяц и его квоты. Запрос не расходует квоту",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Собственное использование API",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/usage.Usage"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/news": {
+            "get": {
+                "description": "Возвращает последние новости, агрегированные из всех источников. Параметры offset, from или to включают постраничную выборку из хранилища с историей за пределами окна (для sqlite) и возвращают total",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "news"
+                ],
+                "summary": "Получить список новостей",
+                "parameters": [
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Количество новостей (по умолчанию 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по источнику",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фильтр по категории",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Смещение от начала выборки",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Начало диапазона published_at (RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Конец диапазона published_at (RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag ранее полученного ответа",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Время ранее полученного ответа",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.NewsResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Новости не изменились"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v1.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "post": {
+                "description": "Обменивает refresh токен на новую пару токенов. Refresh токен одноразовый: повторное использование отзывает всю сессию",
                 "consumes": [
                     "application/json"
                 ],
@@ -217,24 +1118,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "news"
+                    "auth"
                 ],
-                "summary": "Получить список новостей",
+                "summary": "Обновить токены",
                 "parameters": [
                     {
-                        "maximum": 1000,
-                        "minimum": 1,
-                        "type": "integer",
-                        "description": "Количество новостей (по умолчанию 100)",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Refresh токен",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/v1.RefreshRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/v1.NewsResponse"
+                            "$ref": "#/definitions/v1.LoginResponse"
                         }
                     },
                     "400": {
@@ -243,8 +1145,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/v1.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/v1.ErrorResponse"
                         }
@@ -288,9 +1190,103 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "audit.Event": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "cache.clear"
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "admin"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "10.0.0.5"
+                },
+                "outcome": {
+                    "type": "string",
+                    "example": "success"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string",
+                    "example": "/api/v1/admin/cache/clear?pattern=news:*"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.APIKeyInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Mobile app"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f9a1c0b7d2e"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string",
+                    "example": "team-mobile"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "infohub_3f9a1c0b7d2e"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "news:read",
+                        "admin:cache"
+                    ]
+                }
+            }
+        },
+        "bulk.ImportResult": {
+            "type": "object",
+            "properties": {
+                "imported": {
+                    "type": "integer",
+                    "example": 250
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 1000
+                }
+            }
+        },
         "domain.News": {
             "type": "object",
             "properties": {
+                "category": {
+                    "type": "string",
+                    "example": "technology"
+                },
                 "description": {
                     "type": "string",
                     "example": "This breakthrough announcement changes the landscape..."
@@ -317,13 +1313,130 @@ const docTemplate = `{
                 }
             }
         },
+        "usage.Counter": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer",
+                    "example": 524288
+                },
+                "requests": {
+                    "type": "integer",
+                    "example": 120
+                }
+            }
+        },
+        "usage.Quota": {
+            "type": "object",
+            "properties": {
+                "daily_bytes": {
+                    "type": "integer"
+                },
+                "daily_requests": {
+                    "type": "integer"
+                },
+                "monthly_bytes": {
+                    "type": "integer"
+                },
+                "monthly_requests": {
+                    "type": "integer"
+                }
+            }
+        },
+        "usage.Usage": {
+            "type": "object",
+            "properties": {
+                "consumer": {
+                    "type": "string",
+                    "example": "3f9a1c0b7d2e"
+                },
+                "daily": {
+                    "$ref": "#/definitions/usage.Counter"
+                },
+                "day": {
+                    "type": "string",
+                    "example": "2026-10-18"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string",
+                    "example": "2026-10"
+                },
+                "monthly": {
+                    "$ref": "#/definitions/usage.Counter"
+                },
+                "quota": {
+                    "$ref": "#/definitions/usage.Quota"
+                }
+            }
+        },
+        "v1.APIKeysResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.APIKeyInfo"
+                    }
+                }
+            }
+        },
+        "v1.AdminCacheEntry": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "news:1700000000000000000:latest:100"
+                },
+                "value": {
+                    "type": "object"
+                }
+            }
+        },
+        "v1.AdminCacheKeysResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 12
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.AdminCacheEntry"
+                    }
+                },
+                "pattern": {
+                    "type": "string",
+                    "example": "news:*"
+                },
+                "truncated": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
         "v1.AdminClearCacheResponse": {
             "type": "object",
             "properties": {
+                "deleted": {
+                    "type": "integer",
+                    "example": 12
+                },
                 "message": {
                     "type": "string",
                     "example": "Cache cleared successfully"
                 },
+                "pattern": {
+                    "type": "string",
+                    "example": "news:*"
+                },
                 "success": {
                     "type": "boolean",
                     "example": true
@@ -394,6 +1507,97 @@ const docTemplate = `{
                 }
             }
         },
+        "v1.AuditResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.Event"
+                    }
+                }
+            }
+        },
+        "v1.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Mobile app"
+                },
+                "expires_in": {
+                    "description": "ExpiresIn - срок действия ключа в формате Go duration; пустое значение - бессрочный ключ",
+                    "type": "string",
+                    "example": "720h"
+                },
+                "owner": {
+                    "type": "string",
+                    "example": "team-mobile"
+                },
+                "scopes": {
+                    "description": "Scopes - области доступа ключа; пустой список - области по умолчанию",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "news:read",
+                        "admin:cache"
+                    ]
+                }
+            }
+        },
+        "v1.CreateAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Mobile app"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f9a1c0b7d2e"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "infohub_3f9a1c0b7d2e_5b0c..."
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string",
+                    "example": "team-mobile"
+                },
+                "prefix": {
+                    "type": "string",
+                    "example": "infohub_3f9a1c0b7d2e"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "news:read",
+                        "admin:cache"
+                    ]
+                }
+            }
+        },
         "v1.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -445,22 +1649,47 @@ const docTemplate = `{
             "properties": {
                 "expires_in": {
                     "type": "string",
-                    "example": "24h"
+                    "example": "15m0s"
                 },
                 "is_admin": {
                     "type": "boolean",
                     "example": false
                 },
+                "refresh_expires_in": {
+                    "type": "string",
+                    "example": "168h0m0s"
+                },
+                "refresh_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
                 "token": {
                     "type": "string",
                     "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
                 },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                },
                 "user_id": {
                     "type": "string",
                     "example": "user123"
                 }
             }
         },
+        "v1.LogoutResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Tokens revoked"
+                },
+                "success": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
         "v1.NewsResponse": {
             "type": "object",
             "properties": {
@@ -474,12 +1703,41 @@ const docTemplate = `{
                         "$ref": "#/definitions/domain.News"
                     }
                 },
+                "total": {
+                    "description": "Total - общее количество совпадений при постраничной выборке из истории",
+                    "type": "integer",
+                    "example": 2500
+                },
                 "version": {
                     "type": "string",
                     "example": "v1"
                 }
             }
         },
+        "v1.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                }
+            }
+        },
+        "v1.UsageListResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "usage": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/usage.Usage"
+                    }
+                }
+            }
+        },
         "v1.ValidateTokenResponse": {
             "type": "object",
             "properties": {
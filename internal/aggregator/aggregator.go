@@ -2,8 +2,14 @@ package aggregator
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"log"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/pah-an/infohub/internal/domain"
 )
@@ -13,24 +19,52 @@ type Aggregator struct {
 	news       domain.NewsList
 	mutex      sync.RWMutex
 	repository domain.NewsRepository
+	retention  RetentionConfig
+
+	// version - хэш содержимого окна, updatedAt - время его последнего изменения
+	version   string
+	updatedAt time.Time
 }
 
-// New создает новый агрегатор
-func New(repo domain.NewsRepository) *Aggregator {
-	return &Aggregator{
+// New создает новый агрегатор. При включенном архиве хранилище должно
+// поддерживать domain.NewsUpserter
+func New(repo domain.NewsRepository, retention RetentionConfig) (*Aggregator, error) {
+	retention = retention.withDefaults()
+
+	if retention.Archive {
+		if _, ok := repo.(domain.NewsUpserter); !ok {
+			return nil, fmt.Errorf("news archive requires a repository that supports upserts")
+		}
+	}
+
+	a := &Aggregator{
 		news:       make(domain.NewsList, 0),
 		repository: repo,
+		retention:  retention,
 	}
+	a.updateVersion()
+
+	return a, nil
 }
 
 // Start запускает агрегатор для прослушивания каналов
 func (a *Aggregator) Start(ctx context.Context, newsChannel <-chan domain.NewsList, errorChannel <-chan error) {
+	// Новости вытесняются по возрасту, даже если новые не поступают
+	var retentionTick <-chan time.Time
+	if a.retention.MaxAge > 0 {
+		ticker := time.NewTicker(a.retention.CheckInterval)
+		defer ticker.Stop()
+		retentionTick = ticker.C
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case newNews := <-newsChannel:
 			a.addNews(newNews)
+		case <-retentionTick:
+			a.enforceRetention()
 		case err := <-errorChannel:
 			log.Printf("Collector error: %v", err)
 		}
@@ -48,15 +82,116 @@ func (a *Aggregator) addNews(newNews domain.NewsList) {
 
 	a.news = a.news.SortByDate()
 
-	if len(a.news) > 1000 {
-		a.news = a.news[:1000]
+	var evicted domain.NewsList
+	a.news, evicted = a.retention.apply(a.news, time.Now())
+	a.updateVersion()
+
+	// В архивном режиме в хранилище дописываются только новые записи,
+	// поэтому вытесненные из памяти новости в нем остаются
+	if a.retention.Archive {
+		a.persist(newNews)
+	} else {
+		a.persistWindow(evicted)
+	}
+}
+
+// enforceRetention вытесняет новости, переставшие удовлетворять политике хранения
+func (a *Aggregator) enforceRetention() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	if !a.retention.Archive && a.retention.MaxAge > 0 {
+		a.pruneRepository(now.Add(-a.retention.MaxAge))
+	}
+
+	var evicted domain.NewsList
+	a.news, evicted = a.retention.apply(a.news, now)
+	if len(evicted) == 0 {
+		return
+	}
+
+	a.updateVersion()
+	if !a.retention.Archive {
+		a.persistWindow(evicted)
+	}
+}
+
+// persist сохраняет новости в хранилище согласно режиму хранения
+func (a *Aggregator) persist(news domain.NewsList) {
+	if a.repository == nil {
+		return
+	}
+
+	var err error
+	if upserter, ok := a.repository.(domain.NewsUpserter); ok && a.retention.Archive {
+		err = upserter.UpsertNews(news)
+	} else {
+		err = a.repository.SaveNews(news)
+	}
+
+	if err != nil {
+		log.Printf("Error saving news to repository: %v", err)
+	}
+}
+
+// pruneRepository удаляет из хранилища новости старше before. Нужен хранилищам,
+// которые накапливают историю (sqlite): без архива max_age ограничивает и их
+func (a *Aggregator) pruneRepository(before time.Time) {
+	pruner, ok := a.repository.(domain.NewsPruner)
+	if !ok {
+		return
 	}
 
-	if a.repository != nil {
-		if err := a.repository.SaveNews(a.news); err != nil {
-			log.Printf("Error saving news to repository: %v", err)
+	deleted, err := pruner.DeleteNewsBefore(before)
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+	case err != nil:
+		log.Printf("Error pruning news repository: %v", err)
+	case deleted > 0:
+		log.Printf("Pruned %d news older than %s from repository", deleted, a.retention.MaxAge)
+	}
+}
+
+// persistWindow сохраняет окно без архива: окно дописывается в хранилище,
+// а вытесненные из него новости удаляются. Записи, которые никогда не были
+// в окне (например, импортированные), при этом не затрагиваются. Хранилища
+// без удаления перезаписываются окном целиком
+func (a *Aggregator) persistWindow(evicted domain.NewsList) {
+	if a.repository == nil {
+		return
+	}
+
+	err := a.syncWindow(evicted)
+	if errors.Is(err, errors.ErrUnsupported) {
+		err = a.repository.SaveNews(a.news)
+	}
+
+	if err != nil {
+		log.Printf("Error saving news to repository: %v", err)
+	}
+}
+
+// syncWindow удаляет вытесненные новости и дописывает окно. Возвращает
+// errors.ErrUnsupported до каких-либо изменений, если хранилище не умеет удалять
+func (a *Aggregator) syncWindow(evicted domain.NewsList) error {
+	deleter, canDelete := a.repository.(domain.NewsDeleter)
+	upserter, canUpsert := a.repository.(domain.NewsUpserter)
+	if !canDelete || !canUpsert {
+		return errors.ErrUnsupported
+	}
+
+	if len(evicted) > 0 {
+		ids := make([]string, len(evicted))
+		for i, item := range evicted {
+			ids[i] = item.ID
+		}
+		if err := deleter.DeleteNews(ids); err != nil {
+			return err
 		}
 	}
+
+	return upserter.UpsertNews(a.news)
 }
 
 // removeDuplicates удаляет дубликаты новостей по ID
@@ -82,6 +217,34 @@ func (a *Aggregator) GetLatestNews(limit int) domain.NewsList {
 	return a.news.LimitTo(limit)
 }
 
+// ContentVersion возвращает версию содержимого и время его последнего изменения.
+// Версия зависит только от набора новостей, поэтому совпадает на всех репликах
+// с одинаковым содержимым и сохраняется между перезапусками
+func (a *Aggregator) ContentVersion() (string, time.Time) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.version, a.updatedAt
+}
+
+// updateVersion пересчитывает версию содержимого. Вызывается под блокировкой
+func (a *Aggregator) updateVersion() {
+	hash := fnv.New64a()
+	for _, item := range a.news {
+		for _, field := range []string{item.ID, item.Title, item.Description, item.URL, item.Source, item.Category} {
+			hash.Write([]byte(field))
+			hash.Write([]byte{0})
+		}
+		binary.Write(hash, binary.BigEndian, item.PublishedAt.UnixNano())
+	}
+
+	version := strconv.FormatUint(hash.Sum64(), 36)
+	if version != a.version {
+		a.version = version
+		a.updatedAt = time.Now().UTC()
+	}
+}
+
 // GetNews возвращает все новости
 func (a *Aggregator) GetNews() domain.NewsList {
 	a.mutex.RLock()
@@ -96,7 +259,7 @@ func (a *Aggregator) LoadFromRepository() error {
 		return nil
 	}
 
-	news, err := a.repository.GetLatestNews(1000)
+	news, err := a.repository.GetLatestNews(a.retention.MaxItems)
 	if err != nil {
 		return err
 	}
@@ -104,7 +267,8 @@ func (a *Aggregator) LoadFromRepository() error {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	a.news = news.SortByDate()
+	a.news, _ = a.retention.apply(news.SortByDate(), time.Now())
+	a.updateVersion()
 	return nil
 }
 
@@ -117,5 +281,13 @@ func (a *Aggregator) SaveToRepository() error {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
 
-	return a.repository.SaveNews(a.news)
+	if upserter, ok := a.repository.(domain.NewsUpserter); ok && a.retention.Archive {
+		return upserter.UpsertNews(a.news)
+	}
+
+	err := a.syncWindow(nil)
+	if errors.Is(err, errors.ErrUnsupported) {
+		return a.repository.SaveNews(a.news)
+	}
+	return err
 }
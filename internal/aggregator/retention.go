@@ -0,0 +1,52 @@
+package aggregator
+
+import (
+	"time"
+
+	"github.com/pah-an/infohub/internal/domain"
+)
+
+// RetentionConfig задает правила хранения новостей в памяти агрегатора
+type RetentionConfig struct {
+	MaxAge       time.Duration `yaml:"max_age" json:"max_age"`               // 0 - без ограничения по возрасту
+	MaxItems     int           `yaml:"max_items" json:"max_items"`           // размер горячего окна
+	MaxPerSource int           `yaml:"max_per_source" json:"max_per_source"` // 0 - без ограничения на источник
+	// CheckInterval - период вытеснения устаревших новостей по возрасту (по умолчанию 1 минута)
+	CheckInterval time.Duration `yaml:"check_interval" json:"check_interval"`
+	// Archive сохраняет вытесненные из памяти новости в хранилище.
+	// Без него вытесненные из окна новости удаляются из хранилища
+	Archive bool `yaml:"archive" json:"archive"`
+}
+
+// withDefaults возвращает политику с заполненными значениями по умолчанию
+func (rc RetentionConfig) withDefaults() RetentionConfig {
+	if rc.MaxItems <= 0 {
+		rc.MaxItems = 1000
+	}
+	if rc.CheckInterval <= 0 {
+		rc.CheckInterval = time.Minute
+	}
+	return rc
+}
+
+// apply разделяет отсортированные по дате новости на оставляемые и вытесняемые
+func (rc RetentionConfig) apply(news domain.NewsList, now time.Time) (kept, evicted domain.NewsList) {
+	kept = make(domain.NewsList, 0, len(news))
+	perSource := make(map[string]int)
+
+	for _, item := range news {
+		switch {
+		case rc.MaxAge > 0 && now.Sub(item.PublishedAt) > rc.MaxAge:
+			evicted = append(evicted, item)
+		case rc.MaxPerSource > 0 && perSource[item.Source] >= rc.MaxPerSource:
+			evicted = append(evicted, item)
+		case len(kept) >= rc.MaxItems:
+			evicted = append(evicted, item)
+		default:
+			perSource[item.Source]++
+			kept = append(kept, item)
+		}
+	}
+
+	return kept, evicted
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Результаты действий
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Параметры выборки по умолчанию
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+	maxEventSize      = 64 * 1024
+)
+
+// Config содержит настройки журнала аудита
+type Config struct {
+	Enabled  bool   `yaml:"enabled"`
+	FilePath string `yaml:"file_path"`
+}
+
+// Event - запись журнала аудита
+type Event struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor" example:"admin"`
+	Action    string    `json:"action" example:"cache.clear"`
+	Target    string    `json:"target,omitempty" example:"/api/v1/admin/cache/clear?pattern=news:*"`
+	IP        string    `json:"ip" example:"10.0.0.5"`
+	RequestID string    `json:"request_id,omitempty"`
+	Outcome   string    `json:"outcome" example:"success"`
+	Details   string    `json:"details,omitempty"`
+}
+
+// Filter отбирает записи журнала. Пустые поля не ограничивают выборку
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	Actor  string
+	Action string
+	Limit  int
+}
+
+// matches проверяет запись на соответствие фильтру
+func (f Filter) matches(event Event) bool {
+	switch {
+	case !f.Since.IsZero() && event.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !event.Time.Before(f.Until):
+		return false
+	case f.Actor != "" && event.Actor != f.Actor:
+		return false
+	case f.Action != "" && event.Action != f.Action:
+		return false
+	}
+	return true
+}
+
+// Log - журнал аудита в файле, открытом только на дозапись.
+// Каждая запись - одна строка JSON
+type Log struct {
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// Open открывает журнал, создавая файл при необходимости
+func Open(path string) (*Log, error) {
+	if path == "" {
+		return nil, fmt.Errorf("audit log path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{path: path, file: file}, nil
+}
+
+// Record дописывает событие в журнал и сбрасывает его на диск
+func (l *Log) Record(event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, err = l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Query возвращает последние записи, подходящие под фильтр, от новых к старым
+func (l *Log) Query(filter Filter) ([]Event, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultQueryLimit
+	}
+	if filter.Limit > maxQueryLimit {
+		filter.Limit = maxQueryLimit
+	}
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	// Кольцевой буфер хранит только последние Limit совпадений
+	ring := make([]Event, filter.Limit)
+	count := 0
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Оборванная при сбое последняя строка не мешает чтению остальных
+			continue
+		}
+		if filter.matches(event) {
+			ring[count%filter.Limit] = event
+			count++
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	size := min(count, filter.Limit)
+	events := make([]Event, 0, size)
+	for i := 0; i < size; i++ {
+		events = append(events, ring[(count-1-i)%filter.Limit])
+	}
+	return events, nil
+}
+
+// Close закрывает журнал
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+// contextKey - ключ события аудита в контексте запроса
+type contextKey struct{}
+
+// WithEvent добавляет в контекст пустое событие, которое обработчики
+// заполняют через Mark, а middleware записывает после ответа
+func WithEvent(ctx context.Context) (context.Context, *Event) {
+	event := &Event{}
+	return context.WithValue(ctx, contextKey{}, event), event
+}
+
+// Mark отмечает запрос как подлежащий аудиту. Пустые actor и target не
+// перезаписывают ранее заданные значения. Без журнала вызов ничего не делает
+func Mark(ctx context.Context, action, actor, target string) {
+	event, ok := ctx.Value(contextKey{}).(*Event)
+	if !ok {
+		return
+	}
+	if action != "" {
+		event.Action = action
+	}
+	if actor != "" {
+		event.Actor = actor
+	}
+	if target != "" {
+		event.Target = target
+	}
+}
+
+// SetOutcome задает результат и подробности действия. Без явного вызова
+// результат определяется по коду ответа
+func SetOutcome(ctx context.Context, outcome, details string) {
+	if event, ok := ctx.Value(contextKey{}).(*Event); ok {
+		event.Outcome = outcome
+		event.Details = details
+	}
+}
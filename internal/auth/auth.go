@@ -1,30 +1,67 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/pah-an/infohub/internal/cache"
 )
 
 // Config содержит конфигурацию аутентификации
 type Config struct {
 	JWTSecret   string            `yaml:"jwt_secret" json:"jwt_secret"`
-	JWTTTL      time.Duration     `yaml:"jwt_ttl" json:"jwt_ttl"`
+	JWTTTL      time.Duration     `yaml:"jwt_ttl" json:"jwt_ttl"` // срок жизни access токена
+	RefreshTTL  time.Duration     `yaml:"refresh_ttl" json:"refresh_ttl"`
 	APIKeys     map[string]string `yaml:"api_keys" json:"api_keys"` // key -> description
 	AdminAPIKey string            `yaml:"admin_api_key" json:"admin_api_key"`
 	Enabled     bool              `yaml:"enabled" json:"enabled"`
 	PublicPaths []string          `yaml:"public_paths" json:"public_paths"`
+	// MaxSessionAge ограничивает продление сессии через refresh от момента входа
+	MaxSessionAge time.Duration `yaml:"max_session_age" json:"max_session_age"`
+	// DefaultScopes выдаются ключам, для которых области доступа не заданы
+	DefaultScopes []string `yaml:"default_scopes" json:"default_scopes"`
+	// KeyScopes задает области доступа ключей из APIKeys
+	KeyScopes map[string][]string `yaml:"key_scopes" json:"key_scopes"`
+	// Signing задает алгоритм и ключи подписи JWT
+	Signing SigningConfig `yaml:"signing" json:"signing"`
+	// OIDC задает вход в админ-панель через провайдера единого входа
+	OIDC OIDCConfig `yaml:"oidc" json:"oidc"`
+	// ClientCerts задает аутентификацию сервисов клиентскими сертификатами
+	ClientCerts ClientCertConfig `yaml:"client_certs" json:"client_certs"`
+	// RequestSigning задает подпись запросов HMAC вместо передачи ключа
+	RequestSigning RequestSigningConfig `yaml:"request_signing" json:"request_signing"`
+	// DisableQueryAPIKey запрещает передавать ключ в параметре api_key, который
+	// попадает в логи прокси и историю браузера
+	DisableQueryAPIKey bool `yaml:"disable_query_api_key" json:"disable_query_api_key"`
+	// TrustedIssuers - сторонние издатели, чьи JWT принимаются наравне с собственными
+	TrustedIssuers []IssuerConfig `yaml:"trusted_issuers" json:"trusted_issuers"`
+	// KeyStorePath - файл хранилища ключей, выпущенных через API. Пустое значение отключает хранилище
+	KeyStorePath string `yaml:"key_store_path" json:"key_store_path"`
 }
 
 // Manager управляет аутентификацией
 type Manager struct {
 	config    Config
 	jwtSecret []byte
+	keys      *KeyStore
+	signing   *KeyRing // nil при подписи HS256
+	oidc      *OIDCProvider
+	issuers   map[string]*trustedIssuer // по значению "iss"
+
+	// tokens хранит список отзыва JWT; ownTokenStore - кэш создан менеджером
+	tokens        cache.Cache
+	ownTokenStore bool
 }
 
 // Claims представляет JWT claims
@@ -33,6 +70,13 @@ type Claims struct {
 	APIKey  string   `json:"api_key"`
 	IsAdmin bool     `json:"is_admin"`
 	Scopes  []string `json:"scopes"`
+	// TokenType - "access" или "refresh"; пустой у токенов предыдущих версий
+	TokenType string `json:"typ,omitempty"`
+	// Family связывает токены, полученные ротацией от одного входа
+	Family string `json:"fam,omitempty"`
+	KeyID  string `json:"key_id,omitempty"`
+	// AuthTime - момент входа, от которого отсчитывается MaxSessionAge
+	AuthTime int64 `json:"auth_time,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -42,35 +86,124 @@ type User struct {
 	APIKey  string   `json:"api_key"`
 	IsAdmin bool     `json:"is_admin"`
 	Scopes  []string `json:"scopes"`
+	// KeyID - идентификатор ключа из хранилища, если пользователь вошел по нему
+	KeyID string `json:"key_id,omitempty"`
 }
 
 // NewManager создает новый менеджер аутентификации
 func NewManager(config Config) (*Manager, error) {
-	if config.JWTSecret == "" {
+	if config.Signing.Algorithm == "" {
+		config.Signing.Algorithm = AlgorithmHS256
+	}
+	if config.JWTSecret == "" && config.Signing.Algorithm == AlgorithmHS256 {
 		return nil, fmt.Errorf("JWT secret is required")
 	}
+	if len(config.Signing.AllowedAlgorithms) == 0 {
+		config.Signing.AllowedAlgorithms = []string{config.Signing.Algorithm}
+	}
+	if err := config.Signing.validateAllowed(config.JWTSecret != ""); err != nil {
+		return nil, err
+	}
 
 	if config.JWTTTL == 0 {
-		config.JWTTTL = 24 * time.Hour // по умолчанию 24 часа
+		config.JWTTTL = 15 * time.Minute
+	}
+	if config.RefreshTTL == 0 {
+		config.RefreshTTL = 7 * 24 * time.Hour
+	}
+	if config.MaxSessionAge == 0 {
+		config.MaxSessionAge = 30 * 24 * time.Hour
 	}
 
 	if config.APIKeys == nil {
 		config.APIKeys = make(map[string]string)
 	}
 
-	return &Manager{
-		config:    config,
-		jwtSecret: []byte(config.JWTSecret),
-	}, nil
+	if len(config.DefaultScopes) == 0 {
+		config.DefaultScopes = []string{ScopeNewsRead}
+	}
+	if err := ValidateScopes(config.DefaultScopes); err != nil {
+		return nil, err
+	}
+	for _, scopes := range config.KeyScopes {
+		if err := ValidateScopes(scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.ClientCerts.validate(); err != nil {
+		return nil, err
+	}
+	if err := config.RequestSigning.validate(); err != nil {
+		return nil, err
+	}
+
+	issuers := make(map[string]*trustedIssuer, len(config.TrustedIssuers))
+	for _, issuerConfig := range config.TrustedIssuers {
+		if _, exists := issuers[issuerConfig.Issuer]; exists {
+			return nil, fmt.Errorf("trusted issuer %q is configured twice", issuerConfig.Issuer)
+		}
+		issuer, err := newTrustedIssuer(issuerConfig)
+		if err != nil {
+			return nil, err
+		}
+		issuers[issuerConfig.Issuer] = issuer
+	}
+
+	var signing *KeyRing
+	if config.Signing.Algorithm != AlgorithmHS256 {
+		// Выведенный ключ нужен, пока живут подписанные им токены
+		ring, err := NewKeyRing(config.Signing, max(config.JWTTTL, config.RefreshTTL))
+		if err != nil {
+			return nil, err
+		}
+		signing = ring
+	}
+
+	// До вызова SetTokenStore список отзыва хранится в памяти процесса
+	tokens, err := cache.NewMemoryCache(cache.MemoryConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	manager := &Manager{
+		config:        config,
+		jwtSecret:     []byte(config.JWTSecret),
+		signing:       signing,
+		issuers:       issuers,
+		tokens:        tokens,
+		ownTokenStore: true,
+	}
+
+	if config.OIDC.Enabled {
+		provider, err := newOIDCProvider(config.OIDC, manager)
+		if err != nil {
+			tokens.Close()
+			return nil, err
+		}
+		manager.oidc = provider
+	}
+
+	if config.KeyStorePath != "" {
+		keys, err := NewKeyStore(config.KeyStorePath)
+		if err != nil {
+			tokens.Close()
+			return nil, err
+		}
+		manager.keys = keys
+	}
+
+	return manager, nil
 }
 
-// GenerateAPIKey генерирует новый API ключ
+// GenerateAPIKey генерирует новый API ключ вида infohub_<id>_<secret>.
+// Открытая часть id позволяет опознать ключ в логах и хранилище
 func GenerateAPIKey() string {
-	bytes := make([]byte, 32)
+	bytes := make([]byte, 38)
 	if _, err := rand.Read(bytes); err != nil {
 		return ""
 	}
-	return "infohub_" + hex.EncodeToString(bytes)
+	return apiKeyPrefix + hex.EncodeToString(bytes[:6]) + "_" + hex.EncodeToString(bytes[6:])
 }
 
 // ValidateAPIKey проверяет API ключ
@@ -80,79 +213,151 @@ func (m *Manager) ValidateAPIKey(apiKey string) (*User, error) {
 			ID:      "anonymous",
 			APIKey:  "none",
 			IsAdmin: false,
-			Scopes:  []string{"read"},
+			Scopes:  []string{ScopeNewsRead},
 		}, nil
 	}
 
-	// Проверяем admin API key
+	// Проверяем admin API key и ключи из конфигурации
+	if user, ok := m.configKeyUser(apiKey); ok {
+		return user, nil
+	}
+
+	// Проверяем ключи, выпущенные через API
+	if m.keys != nil {
+		info, err := m.keys.Verify(apiKey)
+		switch {
+		case err == nil:
+			id := info.Owner
+			if id == "" {
+				id = info.ID
+			}
+			scopes := info.Scopes
+			if len(scopes) == 0 {
+				scopes = m.config.DefaultScopes
+			}
+			return &User{
+				ID:      id,
+				APIKey:  info.Prefix,
+				IsAdmin: false,
+				Scopes:  scopes,
+				KeyID:   info.ID,
+			}, nil
+		case !errors.Is(err, ErrKeyNotFound):
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("invalid API key")
+}
+
+// configKeyUser возвращает пользователя admin API key или ключа из конфигурации
+// с их текущими правами
+func (m *Manager) configKeyUser(apiKey string) (*User, bool) {
 	if apiKey == m.config.AdminAPIKey && m.config.AdminAPIKey != "" {
 		return &User{
 			ID:      "admin",
 			APIKey:  apiKey,
 			IsAdmin: true,
-			Scopes:  []string{"read", "write", "admin"},
-		}, nil
+			Scopes:  []string{ScopeAll},
+		}, true
 	}
 
-	// Проверяем обычные API keys
-	if description, exists := m.config.APIKeys[apiKey]; exists {
-		return &User{
-			ID:      description,
-			APIKey:  apiKey,
-			IsAdmin: false,
-			Scopes:  []string{"read"},
-		}, nil
+	description, exists := m.config.APIKeys[apiKey]
+	if !exists {
+		return nil, false
 	}
-
-	return nil, fmt.Errorf("invalid API key")
+	scopes, ok := m.config.KeyScopes[apiKey]
+	if !ok {
+		scopes = m.config.DefaultScopes
+	}
+	return &User{
+		ID:      description,
+		APIKey:  apiKey,
+		IsAdmin: false,
+		Scopes:  scopes,
+	}, true
 }
 
-// GenerateJWT генерирует JWT токен
+// GenerateJWT генерирует access токен без refresh токена
 func (m *Manager) GenerateJWT(user *User) (string, error) {
-	now := time.Now()
-	claims := &Claims{
-		UserID:  user.ID,
-		APIKey:  user.APIKey,
-		IsAdmin: user.IsAdmin,
-		Scopes:  user.Scopes,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.JWTTTL)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    "infohub",
-			Subject:   user.ID,
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(m.jwtSecret)
+	return m.signToken(user, TokenTypeAccess, "", m.config.JWTTTL, time.Now())
 }
 
-// ValidateJWT проверяет JWT токен
+// ValidateJWT проверяет access токен, включая список отзыва.
+// Токены доверенных сторонних издателей проверяются их ключами
 func (m *Manager) ValidateJWT(tokenString string) (*User, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return m.jwtSecret, nil
-	})
+	if issuer := m.issuerOf(tokenString); issuer != nil {
+		return issuer.validate(context.Background(), tokenString)
+	}
+
+	claims, err := m.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType == TokenTypeRefresh {
+		return nil, ErrWrongTokenType
+	}
+	if err = m.checkRevoked(context.Background(), claims); err != nil {
+		return nil, err
+	}
+	// Отзыв ключа завершает и выданные по нему сессии
+	return m.currentUser(claims)
+}
+
+// parseToken проверяет подпись и срок действия токена
+func (m *Manager) parseToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return &User{
-			ID:      claims.UserID,
-			APIKey:  claims.APIKey,
-			IsAdmin: claims.IsAdmin,
-			Scopes:  claims.Scopes,
-		}, nil
+		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
+// verificationKey выбирает ключ проверки подписи. Принимаются только алгоритмы
+// из signing.allowed_algorithms: при асимметричной подписи токены HS256
+// принимаются, лишь если HS256 указан там явно на время перехода
+func (m *Manager) verificationKey(token *jwt.Token) (interface{}, error) {
+	if !slices.Contains(m.config.Signing.AllowedAlgorithms, token.Method.Alg()) {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if token.Method.Alg() != AlgorithmHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return m.jwtSecret, nil
+	}
+
+	if m.signing == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	return m.signing.VerificationKey(kid, token.Method.Alg())
+}
+
+// JWKS возвращает открытые ключи проверки токенов. При подписи HS256 набор пуст
+func (m *Manager) JWKS() JWKS {
+	if m.signing == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return m.signing.JWKS()
+}
+
+// RunKeyRotation выполняет плановую ротацию ключей подписи до отмены контекста
+func (m *Manager) RunKeyRotation(ctx context.Context) {
+	if m.signing == nil || m.config.Signing.RotationInterval <= 0 {
+		return
+	}
+	m.signing.Run(ctx)
+}
+
 // AuthenticateRequest аутентифицирует HTTP запрос
 func (m *Manager) AuthenticateRequest(r *http.Request) (*User, error) {
 	// Проверяем публичные пути
@@ -162,14 +367,17 @@ func (m *Manager) AuthenticateRequest(r *http.Request) (*User, error) {
 				ID:      "public",
 				APIKey:  "none",
 				IsAdmin: false,
-				Scopes:  []string{"read"},
+				Scopes:  []string{ScopeNewsRead},
 			}, nil
 		}
 	}
 
 	// Пытаемся получить API ключ из заголовка
 	apiKey := r.Header.Get("X-API-Key")
-	if apiKey == "" {
+	if apiKey == "" && r.URL.Query().Has("api_key") {
+		if m.config.DisableQueryAPIKey {
+			return nil, fmt.Errorf("api_key query parameter is disabled, use the X-API-Key header")
+		}
 		// Пытаемся получить из query параметра
 		apiKey = r.URL.Query().Get("api_key")
 	}
@@ -185,6 +393,23 @@ func (m *Manager) AuthenticateRequest(r *http.Request) (*User, error) {
 		if len(parts) == 2 && parts[0] == "Bearer" {
 			return m.ValidateJWT(parts[1])
 		}
+		if len(parts) == 2 && parts[0] == SignatureScheme {
+			return m.signedRequestUser(r, parts[1])
+		}
+	}
+
+	// Внутренние сервисы аутентифицируются проверенным клиентским сертификатом
+	if user, err := m.clientCertUser(r); err == nil {
+		return user, nil
+	} else if !errors.Is(err, errNoClientCert) {
+		return nil, err
+	}
+
+	// Браузер админ-панели аутентифицируется cookie сессии OIDC
+	if user, err := m.sessionUser(r); err == nil {
+		return user, nil
+	} else if !errors.Is(err, errNoSession) {
+		return nil, err
 	}
 
 	if !m.config.Enabled {
@@ -192,35 +417,74 @@ func (m *Manager) AuthenticateRequest(r *http.Request) (*User, error) {
 			ID:      "anonymous",
 			APIKey:  "none",
 			IsAdmin: false,
-			Scopes:  []string{"read"},
+			Scopes:  []string{ScopeNewsRead},
 		}, nil
 	}
 
 	return nil, fmt.Errorf("authentication required")
 }
 
-// HasScope проверяет, есть ли у пользователя определенная область доступа
+// HasScope проверяет, есть ли у пользователя определенная область доступа.
+// Администратору доступны все области, "*" разрешает все области,
+// а "news:*" - все области с префиксом "news:"
 func (u *User) HasScope(scope string) bool {
+	if u.IsAdmin {
+		return true
+	}
+
 	for _, s := range u.Scopes {
-		if s == scope {
+		if legacy, ok := legacyScopes[s]; ok {
+			s = legacy
+		}
+		switch {
+		case s == scope, s == ScopeAll:
+			return true
+		case strings.HasSuffix(s, ":*") && strings.HasPrefix(scope, strings.TrimSuffix(s, "*")):
 			return true
 		}
 	}
 	return false
 }
 
-// RequireScope создает middleware для проверки области доступа
+// UsageID возвращает идентификатор для учета использования API: ключ из
+// хранилища, хеш ключа из конфигурации, а если пользователь вошел иначе -
+// идентификатор пользователя. Описание ключа из конфигурации для этого не
+// подходит: оно может совпадать у разных ключей и меняться
+func (u *User) UsageID() string {
+	if u.KeyID != "" {
+		return u.KeyID
+	}
+	if u.APIKey != "" && u.APIKey != "none" {
+		return ConfigKeyID(u.APIKey)
+	}
+	return u.ID
+}
+
+// ConfigKeyID возвращает стабильный идентификатор ключа из конфигурации:
+// "config:" и первые 12 hex-символов SHA-256 ключа
+func ConfigKeyID(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "config:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// RequireScope создает middleware для проверки области доступа. Пользователь
+// берется из контекста, если запрос уже прошел middleware аутентификации
 func (m *Manager) RequireScope(scope string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			user, err := m.AuthenticateRequest(r)
-			if err != nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				var err error
+				if user, err = m.AuthenticateRequest(r); err != nil {
+					writeAuthError(w, http.StatusUnauthorized, "Unauthorized", "Authentication required", "")
+					return
+				}
+				r = r.WithContext(WithUser(r.Context(), user))
 			}
 
 			if !user.HasScope(scope) {
-				http.Error(w, "Forbidden", http.StatusForbidden)
+				writeAuthError(w, http.StatusForbidden, "Forbidden",
+					fmt.Sprintf("Missing required scope: %s", scope), scope)
 				return
 			}
 
@@ -233,25 +497,57 @@ func (m *Manager) RequireScope(scope string) func(http.Handler) http.Handler {
 	}
 }
 
-// RequireAdmin создает middleware для проверки прав администратора
-func (m *Manager) RequireAdmin() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			user, err := m.AuthenticateRequest(r)
-			if err != nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+// writeAuthError отправляет ошибку аутентификации или авторизации в формате API
+func writeAuthError(w http.ResponseWriter, code int, title, message, scope string) {
+	response := map[string]interface{}{
+		"error":   title,
+		"code":    code,
+		"message": message,
+	}
+	if scope != "" {
+		response["required_scope"] = scope
+	}
 
-			if !user.IsAdmin {
-				http.Error(w, "Admin access required", http.StatusForbidden)
-				return
-			}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
 
-			r.Header.Set("X-User-ID", user.ID)
-			r.Header.Set("X-User-Admin", "true")
+// ErrKeyStoreDisabled возвращается, если хранилище ключей не настроено
+var ErrKeyStoreDisabled = errors.New("api key store is not configured")
 
-			next.ServeHTTP(w, r)
-		})
+// CreateAPIKey выпускает новый API ключ
+func (m *Manager) CreateAPIKey(request APIKeyRequest) (string, APIKeyInfo, error) {
+	if m.keys == nil {
+		return "", APIKeyInfo{}, ErrKeyStoreDisabled
+	}
+	return m.keys.Create(request)
+}
+
+// ListAPIKeys возвращает выпущенные API ключи
+func (m *Manager) ListAPIKeys() ([]APIKeyInfo, error) {
+	if m.keys == nil {
+		return nil, ErrKeyStoreDisabled
+	}
+	return m.keys.List(), nil
+}
+
+// RevokeAPIKey отзывает API ключ по идентификатору
+func (m *Manager) RevokeAPIKey(id string) (APIKeyInfo, error) {
+	if m.keys == nil {
+		return APIKeyInfo{}, ErrKeyStoreDisabled
+	}
+	return m.keys.Revoke(id)
+}
+
+// Close сохраняет накопленное состояние хранилища ключей и освобождает
+// собственный список отзыва токенов
+func (m *Manager) Close() error {
+	if m.ownTokenStore {
+		m.tokens.Close()
+	}
+	if m.keys == nil {
+		return nil
 	}
+	return m.keys.Flush()
 }
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClaimMapping описывает, как claims стороннего токена превращаются в пользователя InfoHub
+type ClaimMapping struct {
+	// UserClaim - claim с идентификатором пользователя; при его отсутствии используется "sub"
+	UserClaim string `yaml:"user_claim" json:"user_claim"`
+	// GroupsClaim - claim со списком групп пользователя
+	GroupsClaim string `yaml:"groups_claim" json:"groups_claim"`
+	// AdminGroups - группы, членство в которых дает права администратора
+	AdminGroups []string `yaml:"admin_groups" json:"admin_groups"`
+	// GroupScopes - области доступа, выдаваемые членам групп
+	GroupScopes map[string][]string `yaml:"group_scopes" json:"group_scopes"`
+	// DefaultScopes выдаются любому пользователю издателя
+	DefaultScopes []string `yaml:"default_scopes" json:"default_scopes"`
+}
+
+// withDefaults заполняет имена claims по умолчанию
+func (c ClaimMapping) withDefaults() ClaimMapping {
+	if c.UserClaim == "" {
+		c.UserClaim = "email"
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+	return c
+}
+
+// validate проверяет области доступа в сопоставлении
+func (c ClaimMapping) validate() error {
+	if err := ValidateScopes(c.DefaultScopes); err != nil {
+		return err
+	}
+	for group, scopes := range c.GroupScopes {
+		if err := ValidateScopes(scopes); err != nil {
+			return fmt.Errorf("group %q: %w", group, err)
+		}
+	}
+	return nil
+}
+
+// user строит пользователя по claims токена
+func (c ClaimMapping) user(claims map[string]interface{}) (*User, error) {
+	id, _ := claims[c.UserClaim].(string)
+	if id == "" {
+		id, _ = claims["sub"].(string)
+	}
+	if id == "" {
+		return nil, fmt.Errorf("token has no %q or \"sub\" claim", c.UserClaim)
+	}
+
+	user := &User{ID: id, APIKey: "none"}
+	seen := make(map[string]bool)
+	addScopes := func(scopes []string) {
+		for _, scope := range scopes {
+			if !seen[scope] {
+				seen[scope] = true
+				user.Scopes = append(user.Scopes, scope)
+			}
+		}
+	}
+	addScopes(c.DefaultScopes)
+
+	for _, group := range claimStrings(claims[c.GroupsClaim]) {
+		addScopes(c.GroupScopes[group])
+		for _, admin := range c.AdminGroups {
+			if group == admin {
+				user.IsAdmin = true
+			}
+		}
+	}
+
+	if user.Scopes == nil {
+		user.Scopes = []string{}
+	}
+	return user, nil
+}
+
+// claimStrings читает claim-список: массив строк или строку через пробел
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	case []string:
+		return v
+	default:
+		return nil
+	}
+}
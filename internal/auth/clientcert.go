@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// ClientCertConfig задает аутентификацию внутренних сервисов по клиентским
+// сертификатам (mTLS). Сертификат должен пройти проверку по CA bundle сервера
+type ClientCertConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Identities - области доступа по идентичности сертификата: URI, DNS
+	// или email из SAN либо CN субъекта
+	Identities map[string][]string `yaml:"identities" json:"identities"`
+	// AdminIdentities - идентичности с правами администратора
+	AdminIdentities []string `yaml:"admin_identities" json:"admin_identities"`
+	// DefaultScopes выдаются сертификатам, не перечисленным в Identities.
+	// Пустое значение - такие сертификаты не аутентифицируются
+	DefaultScopes []string `yaml:"default_scopes" json:"default_scopes"`
+}
+
+// validate проверяет области доступа в настройках
+func (c ClientCertConfig) validate() error {
+	if err := ValidateScopes(c.DefaultScopes); err != nil {
+		return err
+	}
+	for identity, scopes := range c.Identities {
+		if err := ValidateScopes(scopes); err != nil {
+			return fmt.Errorf("client certificate %q: %w", identity, err)
+		}
+	}
+	return nil
+}
+
+// errNoClientCert означает, что запрос пришел без проверенного клиентского сертификата
+var errNoClientCert = errors.New("no verified client certificate")
+
+// clientCertUser строит пользователя по проверенному клиентскому сертификату
+func (m *Manager) clientCertUser(r *http.Request) (*User, error) {
+	config := m.config.ClientCerts
+	if !config.Enabled || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, errNoClientCert
+	}
+
+	identities := certIdentities(r.TLS.VerifiedChains[0][0])
+	for _, identity := range identities {
+		if slices.Contains(config.AdminIdentities, identity) {
+			return &User{ID: identity, APIKey: "none", IsAdmin: true, Scopes: []string{ScopeAll}}, nil
+		}
+		if scopes, ok := config.Identities[identity]; ok {
+			return &User{ID: identity, APIKey: "none", Scopes: scopes}, nil
+		}
+	}
+
+	if len(config.DefaultScopes) > 0 && len(identities) > 0 {
+		return &User{ID: identities[0], APIKey: "none", Scopes: config.DefaultScopes}, nil
+	}
+	return nil, fmt.Errorf("client certificate %v is not allowed", identities)
+}
+
+// certIdentities возвращает идентичности сертификата в порядке предпочтения:
+// SAN URI (например, SPIFFE ID), DNS имена, email и CN субъекта
+func certIdentities(cert *x509.Certificate) []string {
+	identities := make([]string, 0, len(cert.URIs)+len(cert.DNSNames)+len(cert.EmailAddresses)+1)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	identities = append(identities, cert.DNSNames...)
+	identities = append(identities, cert.EmailAddresses...)
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	return identities
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureScheme - схема заголовка Authorization для подписанных запросов:
+//
+//	Authorization: HMAC-SHA256 KeyId=<id>, Timestamp=<unix>, Nonce=<nonce>, Signature=<hex>
+const SignatureScheme = "HMAC-SHA256"
+
+const (
+	// usedNoncePrefix - ключи использованных nonce в хранилище аутентификации,
+	// отдельном от кэша данных, чтобы поток запросов не вытеснял их раньше срока
+	usedNoncePrefix = "auth:nonce:"
+	// maxSignedBodySize ограничивает тело, которое читается в память для
+	// проверки подписи. Импорт больших архивов выполняется с X-API-Key
+	maxSignedBodySize = 16 << 20
+	minNonceLength    = 16
+	maxNonceLength    = 128
+)
+
+// ErrSignatureReplay означает повторное предъявление подписанного запроса
+var ErrSignatureReplay = errors.New("signed request has already been used")
+
+// RequestSigningConfig задает подпись запросов HMAC вместо передачи ключа.
+// Секрет не покидает клиента, а перехваченный запрос нельзя повторить
+type RequestSigningConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// MaxSkew - допустимое расхождение часов клиента и сервера (по умолчанию 5 минут)
+	MaxSkew time.Duration `yaml:"max_skew" json:"max_skew"`
+	// Keys - ключи подписи по идентификатору KeyId
+	Keys map[string]SigningKeyConfig `yaml:"keys" json:"keys"`
+}
+
+// SigningKeyConfig описывает ключ подписи запросов
+type SigningKeyConfig struct {
+	Secret string `yaml:"secret" json:"secret"`
+	// Owner - идентификатор пользователя, по умолчанию KeyId
+	Owner string `yaml:"owner" json:"owner"`
+	// Scopes - области доступа; пустой список - Config.DefaultScopes
+	Scopes []string `yaml:"scopes" json:"scopes"`
+	Admin  bool     `yaml:"admin" json:"admin"`
+}
+
+// validate проверяет ключи подписи и задает значения по умолчанию
+func (c *RequestSigningConfig) validate() error {
+	if c.MaxSkew <= 0 {
+		c.MaxSkew = 5 * time.Minute
+	}
+	for id, key := range c.Keys {
+		if len(key.Secret) < 32 {
+			return fmt.Errorf("signing key %q: secret must be at least 32 characters", id)
+		}
+		if err := ValidateScopes(key.Scopes); err != nil {
+			return fmt.Errorf("signing key %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// SignRequest подписывает запрос ключом keyID. Тело запроса читается и
+// восстанавливается. Используется клиентами на Go и в тестах
+func SignRequest(r *http.Request, keyID, secret, nonce string, timestamp time.Time) error {
+	bodyHash, err := hashBody(r, maxSignedBodySize)
+	if err != nil {
+		return err
+	}
+
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	signature := computeSignature(secret, stringToSign(r, keyID, ts, nonce, bodyHash))
+	r.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%s, Timestamp=%s, Nonce=%s, Signature=%s",
+		SignatureScheme, keyID, ts, nonce, signature))
+	return nil
+}
+
+// signedRequestUser проверяет подпись запроса и возвращает владельца ключа.
+// params - заголовок Authorization без схемы
+func (m *Manager) signedRequestUser(r *http.Request, params string) (*User, error) {
+	config := m.config.RequestSigning
+	if !config.Enabled {
+		return nil, fmt.Errorf("request signing is not enabled")
+	}
+
+	fields := parseSignatureParams(params)
+	keyID, ts, nonce, signature := fields["KeyId"], fields["Timestamp"], fields["Nonce"], fields["Signature"]
+	if keyID == "" || ts == "" || nonce == "" || signature == "" {
+		return nil, fmt.Errorf("signature must include KeyId, Timestamp, Nonce and Signature")
+	}
+	if len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+		return nil, fmt.Errorf("nonce must be %d to %d characters", minNonceLength, maxNonceLength)
+	}
+
+	key, ok := config.Keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature timestamp")
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > config.MaxSkew || skew < -config.MaxSkew {
+		return nil, fmt.Errorf("signature timestamp is outside the allowed window")
+	}
+
+	// Тело читается только после проверки ключа и времени, чтобы запросы
+	// без действующего ключа не заставляли сервер читать его целиком
+	bodyHash, err := hashBody(r, maxSignedBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := computeSignature(key.Secret, stringToSign(r, keyID, ts, nonce, bodyHash))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return nil, fmt.Errorf("invalid request signature")
+	}
+
+	// Nonce проверяется после подписи, чтобы неподписанные запросы не
+	// занимали хранилище. Запись живет, пока запрос проходит по времени
+	if err = m.useNonce(r.Context(), keyID, nonce, 2*config.MaxSkew); err != nil {
+		return nil, err
+	}
+
+	owner := key.Owner
+	if owner == "" {
+		owner = keyID
+	}
+	if key.Admin {
+		return &User{ID: owner, APIKey: "none", IsAdmin: true, Scopes: []string{ScopeAll}, KeyID: keyID}, nil
+	}
+	scopes := key.Scopes
+	if len(scopes) == 0 {
+		scopes = m.config.DefaultScopes
+	}
+	return &User{ID: owner, APIKey: "none", Scopes: scopes, KeyID: keyID}, nil
+}
+
+// useNonce атомарно отмечает nonce ключа использованным и отклоняет повтор,
+// в том числе одновременный на разных репликах с общим хранилищем
+func (m *Manager) useNonce(ctx context.Context, keyID, nonce string, ttl time.Duration) error {
+	stored, err := m.tokens.SetNX(ctx, usedNoncePrefix+keyID+":"+nonce, true, ttl)
+	if err != nil {
+		// Без хранилища nonce нельзя исключить повтор запроса
+		return fmt.Errorf("failed to check request nonce: %w", err)
+	}
+	if !stored {
+		return ErrSignatureReplay
+	}
+	return nil
+}
+
+// stringToSign собирает подписываемую строку: схема, ключ, время, nonce,
+// метод, путь, отсортированные параметры запроса и SHA-256 тела в hex
+func stringToSign(r *http.Request, keyID, timestamp, nonce, bodyHash string) string {
+	return strings.Join([]string{
+		SignatureScheme,
+		keyID,
+		timestamp,
+		nonce,
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		bodyHash,
+	}, "\n")
+}
+
+// computeSignature вычисляет HMAC-SHA256 строки в hex
+func computeSignature(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hashBody вычисляет SHA-256 тела запроса и возвращает тело обработчику
+func hashBody(r *http.Request, limit int64) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	if r.ContentLength > limit {
+		return "", fmt.Errorf("signed request body exceeds %d bytes", limit)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	r.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read request body: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return "", fmt.Errorf("signed request body exceeds %d bytes", limit)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// parseSignatureParams разбирает пары Name=value, разделенные запятыми.
+// Значения могут быть в кавычках
+func parseSignatureParams(params string) map[string]string {
+	fields := make(map[string]string, 4)
+	for _, part := range strings.Split(params, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return fields
+}
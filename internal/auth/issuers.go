@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ownIssuer - значение "iss" в токенах, выпущенных InfoHub
+const ownIssuer = "infohub"
+
+// IssuerConfig описывает сторонний сервис, токены которого принимает InfoHub.
+// Ключи проверки берутся из JWKS издателя или из заранее выданных PEM файлов
+type IssuerConfig struct {
+	// Issuer - ожидаемое значение claim "iss"
+	Issuer string `yaml:"issuer" json:"issuer"`
+	// Audience - значение, которое должно присутствовать в claim "aud"
+	Audience string `yaml:"audience" json:"audience"`
+	// JWKSURL - адрес набора открытых ключей издателя
+	JWKSURL string `yaml:"jwks_url" json:"jwks_url"`
+	// JWKSRefresh - интервал планового обновления JWKS; 0 - один час
+	JWKSRefresh time.Duration `yaml:"jwks_refresh" json:"jwks_refresh"`
+	// PublicKeys - PEM файлы открытых ключей по kid, если издатель не публикует JWKS
+	PublicKeys map[string]string `yaml:"public_keys" json:"public_keys"`
+	// Algorithms - допустимые алгоритмы подписи; по умолчанию все асимметричные
+	Algorithms   []string `yaml:"algorithms" json:"algorithms"`
+	ClaimMapping `yaml:",inline"`
+}
+
+// trustedIssuer проверяет токены одного стороннего издателя
+type trustedIssuer struct {
+	config IssuerConfig
+	jwks   *JWKSClient
+	keys   map[string]crypto.PublicKey
+}
+
+// newTrustedIssuer проверяет настройки издателя и загружает его статические ключи
+func newTrustedIssuer(config IssuerConfig) (*trustedIssuer, error) {
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, fmt.Errorf("trusted issuer requires issuer and audience")
+	}
+	if config.Issuer == ownIssuer {
+		return nil, fmt.Errorf("trusted issuer %q conflicts with tokens issued by InfoHub", config.Issuer)
+	}
+	if (config.JWKSURL == "") == (len(config.PublicKeys) == 0) {
+		return nil, fmt.Errorf("trusted issuer %q requires either jwks_url or public_keys", config.Issuer)
+	}
+
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = externalTokenMethods
+	}
+	for _, algorithm := range config.Algorithms {
+		if !slices.Contains(externalTokenMethods, algorithm) {
+			return nil, fmt.Errorf("trusted issuer %q: unsupported algorithm %s", config.Issuer, algorithm)
+		}
+	}
+
+	// Для сервисных токенов естественный идентификатор - "sub"
+	if config.UserClaim == "" {
+		config.UserClaim = "sub"
+	}
+	config.ClaimMapping = config.ClaimMapping.withDefaults()
+	if err := config.ClaimMapping.validate(); err != nil {
+		return nil, fmt.Errorf("trusted issuer %q: %w", config.Issuer, err)
+	}
+
+	issuer := &trustedIssuer{config: config}
+	if config.JWKSURL != "" {
+		issuer.jwks = NewJWKSClient(config.JWKSURL, nil, config.JWKSRefresh)
+		return issuer, nil
+	}
+
+	issuer.keys = make(map[string]crypto.PublicKey, len(config.PublicKeys))
+	for kid, path := range config.PublicKeys {
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("trusted issuer %q: %w", config.Issuer, err)
+		}
+		issuer.keys[kid] = key
+	}
+	return issuer, nil
+}
+
+// validate проверяет подпись, издателя, аудиторию и срок действия токена
+func (i *trustedIssuer) validate(ctx context.Context, tokenString string) (*User, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return i.key(ctx, token)
+	},
+		jwt.WithValidMethods(i.config.Algorithms),
+		jwt.WithIssuer(i.config.Issuer),
+		jwt.WithAudience(i.config.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.config.ClaimMapping.user(claims)
+}
+
+// key выбирает открытый ключ по kid из заголовка токена
+func (i *trustedIssuer) key(ctx context.Context, token *jwt.Token) (crypto.PublicKey, error) {
+	kid, _ := token.Header["kid"].(string)
+	if i.jwks != nil {
+		return i.jwks.Key(ctx, kid, token.Method.Alg())
+	}
+
+	if key, ok := i.keys[kid]; ok {
+		return key, nil
+	}
+	// Токен без kid допустим, если у издателя единственный ключ
+	if kid == "" && len(i.keys) == 1 {
+		for _, key := range i.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// issuerOf возвращает стороннего издателя токена или nil для собственных токенов.
+// Claim "iss" читается до проверки подписи только для выбора ключей
+func (m *Manager) issuerOf(tokenString string) *trustedIssuer {
+	if len(m.issuers) == 0 {
+		return nil
+	}
+
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims); err != nil {
+		return nil
+	}
+	return m.issuers[claims.Issuer]
+}
+
+// loadPublicKey читает открытый ключ или сертификат в PEM
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key file %s", path)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate in %s: %w", path, err)
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// JWK описывает открытый ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty" example:"EC"`
+	KeyID     string `json:"kid,omitempty" example:"20261018-3f9a1c0b"`
+	Use       string `json:"use,omitempty" example:"sig"`
+	Algorithm string `json:"alg,omitempty" example:"ES256"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC и OKP
+	Curve string `json:"crv,omitempty" example:"P-256"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JWKS - набор открытых ключей, публикуемый в /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwkCurve связывает имя кривой в JWK с ее реализацией
+type jwkCurve struct {
+	name  string
+	curve elliptic.Curve
+	ecdh  ecdh.Curve
+	size  int // длина координаты в байтах
+}
+
+// jwkCurves - кривые ключей ES256, ES384 и ES512
+var jwkCurves = []jwkCurve{
+	{name: "P-256", curve: elliptic.P256(), ecdh: ecdh.P256(), size: 32},
+	{name: "P-384", curve: elliptic.P384(), ecdh: ecdh.P384(), size: 48},
+	{name: "P-521", curve: elliptic.P521(), ecdh: ecdh.P521(), size: 66},
+}
+
+// findCurve ищет кривую по имени или реализации
+func findCurve(match func(jwkCurve) bool) (jwkCurve, bool) {
+	for _, c := range jwkCurves {
+		if match(c) {
+			return c, true
+		}
+	}
+	return jwkCurve{}, false
+}
+
+// newJWK кодирует открытый ключ подписи
+func newJWK(kid, algorithm string, key crypto.PublicKey) (JWK, error) {
+	jwk := JWK{KeyID: kid, Use: "sig", Algorithm: algorithm}
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeSegment(pub.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		curve, ok := findCurve(func(c jwkCurve) bool { return c.curve == pub.Curve })
+		if !ok {
+			return JWK{}, fmt.Errorf("unsupported EC curve %s", pub.Curve.Params().Name)
+		}
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return JWK{}, err
+		}
+		// Несжатая точка: 0x04 || X || Y
+		point := ecdhKey.Bytes()
+		size := (len(point) - 1) / 2
+		jwk.KeyType = "EC"
+		jwk.Curve = curve.name
+		jwk.X = encodeSegment(point[1 : 1+size])
+		jwk.Y = encodeSegment(point[1+size:])
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encodeSegment(pub)
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", key)
+	}
+
+	return jwk, nil
+}
+
+// PublicKey восстанавливает открытый ключ из JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA key %q", k.KeyID)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curve, ok := findCurve(func(c jwkCurve) bool { return c.name == k.Curve })
+		if !ok {
+			return nil, fmt.Errorf("unsupported EC curve %q", k.Curve)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != curve.size || len(y) != curve.size {
+			return nil, fmt.Errorf("invalid EC key %q", k.KeyID)
+		}
+		// Проверяем, что точка лежит на кривой
+		point := append(append([]byte{4}, x...), y...)
+		if _, err = curve.ecdh.NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("invalid EC key %q: %w", k.KeyID, err)
+		}
+		return &ecdsa.PublicKey{Curve: curve.curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", k.Curve)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key %q", k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Параметры обновления JWKS по умолчанию
+const (
+	defaultJWKSRefresh     = time.Hour
+	jwksMinRefreshInterval = 30 * time.Second
+	maxJWKSResponseSize    = 1 << 20
+)
+
+// externalTokenMethods - алгоритмы подписи сторонних токенов, ключи для которых
+// можно получить из JWKS. Симметричные алгоритмы не допускаются
+var externalTokenMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// jwksKey - ключ из загруженного набора
+type jwksKey struct {
+	algorithm string
+	public    crypto.PublicKey
+}
+
+// JWKSClient загружает и кэширует открытые ключи стороннего издателя токенов.
+// Набор обновляется по истечении интервала и при появлении незнакомого kid,
+// но не чаще jwksMinRefreshInterval, чтобы токены со случайным kid не
+// превращались в поток запросов к издателю. Запрос к издателю выполняется
+// без блокировки, а одновременные обновления объединяются в одно
+type JWKSClient struct {
+	url         string
+	client      *http.Client
+	refresh     time.Duration
+	keys        map[string]jwksKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	mutex       sync.Mutex
+	refreshes   singleflight.Group
+}
+
+// NewJWKSClient создает клиент для набора ключей по адресу url.
+// refresh - интервал планового обновления; 0 - один час
+func NewJWKSClient(url string, client *http.Client, refresh time.Duration) *JWKSClient {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+
+	return &JWKSClient{
+		url:     url,
+		client:  client,
+		refresh: refresh,
+		keys:    make(map[string]jwksKey),
+	}
+}
+
+// Key возвращает открытый ключ по kid и проверяет, что он предназначен для algorithm
+func (c *JWKSClient) Key(ctx context.Context, kid, algorithm string) (crypto.PublicKey, error) {
+	c.mutex.Lock()
+	_, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.refresh
+	c.mutex.Unlock()
+
+	if !ok || stale {
+		// Параллельные запросы ждут одного обновления. Запрос к издателю не
+		// должен прерываться отменой контекста первого из них
+		_, err, _ := c.refreshes.Do(c.url, func() (interface{}, error) {
+			return nil, c.update(context.WithoutCancel(ctx))
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.mutex.Lock()
+	key, ok := c.keys[kid]
+	c.mutex.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.algorithm != "" && key.algorithm != algorithm {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, algorithm)
+	}
+	return key.public, nil
+}
+
+// update обновляет набор, если с прошлой попытки прошло не меньше
+// jwksMinRefreshInterval. Ошибка возвращается, только если ключей нет вовсе
+func (c *JWKSClient) update(ctx context.Context) error {
+	c.mutex.Lock()
+	if time.Since(c.attemptedAt) <= jwksMinRefreshInterval {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.attemptedAt = time.Now()
+	c.mutex.Unlock()
+
+	keys, err := c.fetch(ctx)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err != nil {
+		// При недоступности издателя продолжаем работать с последним набором
+		if len(c.keys) == 0 {
+			return err
+		}
+		fmt.Printf("Failed to refresh JWKS from %s: %v\n", c.url, err)
+		return nil
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+// fetch загружает набор ключей
+func (c *JWKSClient) fetch(ctx context.Context) (map[string]jwksKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponseSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		public, err := jwk.PublicKey()
+		if err != nil {
+			// Ключи неподдерживаемых типов пропускаются, остальные остаются доступны
+			continue
+		}
+		keys[jwk.KeyID] = jwksKey{algorithm: jwk.Algorithm, public: public}
+	}
+
+	return keys, nil
+}
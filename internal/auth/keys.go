@@ -0,0 +1,529 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// apiKeyPrefix начинает все API ключи InfoHub
+const apiKeyPrefix = "infohub_"
+
+// Параметры argon2id для хэширования секретов ключей
+const (
+	argonTime    = 2
+	argonMemory  = 19 * 1024 // KiB
+	argonThreads = 1
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// verifiedKeyTTL - сколько проверенный ключ не хэшируется повторно. Без этого
+// каждый запрос с API ключом тратил бы время и память на argon2id
+const verifiedKeyTTL = 5 * time.Minute
+
+// Ограничения проверки неизвестных секретов. Каждый хэш argon2id занимает
+// argonMemory, поэтому число одновременных хэшей ограничено, а отклоненные
+// ключи какое-то время отклоняются без повторного хэширования
+const (
+	maxConcurrentHashes = 4
+	rejectedKeyTTL      = time.Minute
+	maxRejectedKeys     = 10000
+)
+
+// lastUsedFlushInterval ограничивает частоту записи времени использования ключей на диск
+const lastUsedFlushInterval = time.Minute
+
+// Блокировка файла хранилища между репликами
+const (
+	storeLockTimeout = 5 * time.Second
+	// storeLockStale - после этого срока блокировка считается брошенной
+	// упавшим процессом и снимается
+	storeLockStale = 30 * time.Second
+)
+
+// Ошибки хранилища ключей
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrKeyRevoked  = errors.New("api key has been revoked")
+	ErrKeyExpired  = errors.New("api key has expired")
+)
+
+// APIKeyInfo описывает выпущенный API ключ. Секрет ключа не хранится и не возвращается
+type APIKeyInfo struct {
+	ID          string     `json:"id" example:"3f9a1c0b7d2e"`
+	Prefix      string     `json:"prefix" example:"infohub_3f9a1c0b7d2e"`
+	Description string     `json:"description" example:"Mobile app"`
+	Owner       string     `json:"owner" example:"team-mobile"`
+	Scopes      []string   `json:"scopes,omitempty" example:"news:read,admin:cache"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyRequest содержит параметры нового ключа
+type APIKeyRequest struct {
+	Description string
+	Owner       string
+	Scopes      []string // пустой список - области по умолчанию из Config.DefaultScopes
+	ExpiresAt   *time.Time
+}
+
+// storedKey - запись хранилища: метаданные и хэш секрета
+type storedKey struct {
+	APIKeyInfo
+	Hash string `json:"hash"`
+}
+
+// verifiedKey запоминает успешную проверку ключа
+type verifiedKey struct {
+	id        string
+	expiresAt time.Time
+}
+
+// KeyStore хранит хэши API ключей в JSON файле. Ключ имеет вид
+// infohub_<id>_<secret>: открытая часть id позволяет найти запись
+// без перебора, а секрет хранится только в виде хэша argon2id.
+//
+// Файл может быть общим для нескольких реплик: перед обращением к ключам
+// хранилище перечитывает файл, если его заменила другая реплика, а запись
+// выполняется под файловой блокировкой поверх свежего содержимого
+type KeyStore struct {
+	path      string
+	keys      map[string]*storedKey
+	verified  map[[sha256.Size]byte]verifiedKey
+	rejected  map[[sha256.Size]byte]time.Time // до какого момента ключ отклоняется без хэширования
+	hashSlots chan struct{}
+	loaded    os.FileInfo // версия файла, слитая в keys
+	lastFlush time.Time
+	dirty     bool
+	mutex     sync.Mutex
+}
+
+// NewKeyStore открывает хранилище ключей, создавая его при отсутствии файла
+func NewKeyStore(path string) (*KeyStore, error) {
+	store := &KeyStore{
+		path:      path,
+		keys:      make(map[string]*storedKey),
+		verified:  make(map[[sha256.Size]byte]verifiedKey),
+		rejected:  make(map[[sha256.Size]byte]time.Time),
+		hashSlots: make(chan struct{}, maxConcurrentHashes),
+	}
+
+	if err := store.refresh(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Create выпускает новый ключ. Полный ключ возвращается только один раз
+func (s *KeyStore) Create(request APIKeyRequest) (string, APIKeyInfo, error) {
+	if err := ValidateScopes(request.Scopes); err != nil {
+		return "", APIKeyInfo{}, err
+	}
+
+	key := GenerateAPIKey()
+	id, secret, ok := parseAPIKey(key)
+	if !ok {
+		return "", APIKeyInfo{}, fmt.Errorf("failed to generate api key")
+	}
+
+	hash, err := hashSecret(secret)
+	if err != nil {
+		return "", APIKeyInfo{}, err
+	}
+
+	record := &storedKey{
+		APIKeyInfo: APIKeyInfo{
+			ID:          id,
+			Prefix:      apiKeyPrefix + id,
+			Description: request.Description,
+			Owner:       request.Owner,
+			Scopes:      request.Scopes,
+			CreatedAt:   time.Now().UTC(),
+			ExpiresAt:   request.ExpiresAt,
+		},
+		Hash: hash,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.keys[id] = record
+	if err = s.save(); err != nil {
+		delete(s.keys, id)
+		return "", APIKeyInfo{}, err
+	}
+
+	return key, record.APIKeyInfo, nil
+}
+
+// List возвращает все ключи, включая отозванные, от новых к старым
+func (s *KeyStore) List() []APIKeyInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.refreshOrLog()
+
+	result := make([]APIKeyInfo, 0, len(s.keys))
+	for _, key := range s.keys {
+		result = append(result, key.APIKeyInfo)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return result
+}
+
+// Revoke немедленно отзывает ключ. Запись сохраняется для аудита
+func (s *KeyStore) Revoke(id string) (APIKeyInfo, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.refreshOrLog()
+
+	key, exists := s.keys[id]
+	if !exists {
+		return APIKeyInfo{}, ErrKeyNotFound
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now().UTC()
+		key.RevokedAt = &now
+		if err := s.save(); err != nil {
+			key.RevokedAt = nil
+			return APIKeyInfo{}, err
+		}
+	}
+
+	// Сбрасываем запомненные проверки, чтобы отзыв действовал сразу
+	for digest, verified := range s.verified {
+		if verified.id == id {
+			delete(s.verified, digest)
+		}
+	}
+
+	return key.APIKeyInfo, nil
+}
+
+// Status проверяет, что ключ id существует и действует. Секрет не
+// проверяется: метод нужен для токенов, уже выданных по этому ключу
+func (s *KeyStore) Status(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.refreshOrLog()
+
+	record, exists := s.keys[id]
+	switch {
+	case !exists:
+		return ErrKeyNotFound
+	case record.RevokedAt != nil:
+		return ErrKeyRevoked
+	case record.ExpiresAt != nil && time.Now().After(*record.ExpiresAt):
+		return ErrKeyExpired
+	}
+	return nil
+}
+
+// Verify проверяет ключ и отмечает время его использования.
+// Возвращает ErrKeyNotFound для ключей, не выпущенных этим хранилищем
+func (s *KeyStore) Verify(key string) (APIKeyInfo, error) {
+	id, secret, ok := parseAPIKey(key)
+	if !ok {
+		return APIKeyInfo{}, ErrKeyNotFound
+	}
+
+	s.mutex.Lock()
+	s.refreshOrLog()
+	record, exists := s.keys[id]
+	if !exists {
+		s.mutex.Unlock()
+		return APIKeyInfo{}, ErrKeyNotFound
+	}
+	hash := record.Hash
+
+	digest := sha256.Sum256([]byte(key))
+	verified, cached := s.verified[digest]
+	cached = cached && verified.id == id && time.Now().Before(verified.expiresAt)
+	if until, rejected := s.rejected[digest]; rejected && time.Now().Before(until) {
+		s.mutex.Unlock()
+		return APIKeyInfo{}, ErrKeyNotFound
+	}
+	s.mutex.Unlock()
+
+	// Хэш считается без блокировки хранилища: argon2id занимает заметное время
+	if !cached && !s.verifyLimited(secret, hash) {
+		s.mutex.Lock()
+		s.reject(digest)
+		s.mutex.Unlock()
+		return APIKeyInfo{}, ErrKeyNotFound
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().UTC()
+	switch {
+	case record.RevokedAt != nil:
+		return APIKeyInfo{}, ErrKeyRevoked
+	case record.ExpiresAt != nil && now.After(*record.ExpiresAt):
+		return APIKeyInfo{}, ErrKeyExpired
+	}
+
+	if !cached {
+		s.verified[digest] = verifiedKey{id: id, expiresAt: now.Add(verifiedKeyTTL)}
+	}
+
+	record.LastUsedAt = &now
+	s.dirty = true
+	if now.Sub(s.lastFlush) > lastUsedFlushInterval {
+		if err := s.save(); err != nil {
+			// Время использования не критично, ключ остается действительным
+			fmt.Printf("Failed to persist api key usage: %v\n", err)
+		}
+	}
+
+	return record.APIKeyInfo, nil
+}
+
+// verifyLimited проверяет секрет, не допуская больше maxConcurrentHashes
+// одновременных вычислений argon2id
+func (s *KeyStore) verifyLimited(secret, hash string) bool {
+	s.hashSlots <- struct{}{}
+	defer func() { <-s.hashSlots }()
+	return verifySecret(secret, hash)
+}
+
+// reject запоминает отклоненный ключ на rejectedKeyTTL. При переполнении
+// сначала удаляются истекшие записи, а если их нет - все. Вызывается под блокировкой
+func (s *KeyStore) reject(digest [sha256.Size]byte) {
+	now := time.Now()
+	if len(s.rejected) >= maxRejectedKeys {
+		for key, until := range s.rejected {
+			if now.After(until) {
+				delete(s.rejected, key)
+			}
+		}
+		if len(s.rejected) >= maxRejectedKeys {
+			clear(s.rejected)
+		}
+	}
+	s.rejected[digest] = now.Add(rejectedKeyTTL)
+}
+
+// Flush сохраняет накопленные времена использования ключей
+func (s *KeyStore) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+// refresh сливает в память содержимое файла, если его заменила другая
+// реплика. Вызывается под блокировкой
+func (s *KeyStore) refresh() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read key store: %w", err)
+	}
+	// Запись всегда заменяет файл переименованием, поэтому новая версия
+	// файла - это другой файл
+	if s.loaded != nil && os.SameFile(s.loaded, info) && s.loaded.ModTime().Equal(info.ModTime()) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read key store: %w", err)
+	}
+	var keys []*storedKey
+	if err = json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("failed to decode key store: %w", err)
+	}
+
+	s.merge(keys)
+	s.loaded = info
+	return nil
+}
+
+// refreshOrLog перечитывает файл; при ошибке работа продолжается с ключами в памяти
+func (s *KeyStore) refreshOrLog() {
+	if err := s.refresh(); err != nil {
+		fmt.Printf("Failed to reload api key store: %v\n", err)
+	}
+}
+
+// merge объединяет записи из файла с записями в памяти. Кроме отзыва и
+// времени использования записи не меняются, поэтому слияние однозначно:
+// отзыв в любой из копий сохраняется, время использования берется позднее
+func (s *KeyStore) merge(keys []*storedKey) {
+	for _, key := range keys {
+		local, exists := s.keys[key.ID]
+		if !exists {
+			s.keys[key.ID] = key
+			continue
+		}
+
+		// Запись обновляется на месте: на нее могут ссылаться проверки в процессе
+		if local.RevokedAt == nil || (key.RevokedAt != nil && key.RevokedAt.Before(*local.RevokedAt)) {
+			local.RevokedAt = key.RevokedAt
+		}
+		if local.LastUsedAt == nil || (key.LastUsedAt != nil && key.LastUsedAt.After(*local.LastUsedAt)) {
+			local.LastUsedAt = key.LastUsedAt
+		}
+	}
+}
+
+// save атомарно записывает хранилище поверх изменений других реплик.
+// Вызывается под блокировкой
+func (s *KeyStore) save() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create key store directory: %w", err)
+	}
+
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Изменения, записанные другими репликами, не должны потеряться
+	if err = s.refresh(); err != nil {
+		return err
+	}
+
+	keys := make([]*storedKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+
+	if info, statErr := os.Stat(s.path); statErr == nil {
+		s.loaded = info
+	}
+	s.dirty = false
+	s.lastFlush = time.Now()
+	return nil
+}
+
+// lockFile захватывает межпроцессную блокировку, создавая файл path.
+// Возвращает функцию снятия блокировки
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(storeLockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock key store: %w", err)
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > storeLockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to lock key store: %s is held by another process", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// parseAPIKey разбирает ключ вида infohub_<id>_<secret>
+func parseAPIKey(key string) (id, secret string, ok bool) {
+	rest, found := strings.CutPrefix(key, apiKeyPrefix)
+	if !found {
+		return "", "", false
+	}
+
+	id, secret, found = strings.Cut(rest, "_")
+	if !found || id == "" || secret == "" {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+// hashSecret хэширует секрет ключа argon2id. Параметры сохраняются вместе
+// с хэшем, поэтому их можно усилить, не ломая выпущенные ключи
+func hashSecret(secret string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	hash := argon2.IDKey([]byte(secret), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+// verifySecret сравнивает секрет с сохраненным хэшем
+func verifySecret(secret, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	actual := argon2.IDKey([]byte(secret), salt, iterations, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(actual, expected) == 1
+}
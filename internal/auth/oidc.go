@@ -0,0 +1,490 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pah-an/infohub/internal/audit"
+	"github.com/pah-an/infohub/internal/cache"
+)
+
+// Cookie и ключи кэша входа через OIDC
+const (
+	SessionCookieName = "infohub_session"
+	oidcStateCookie   = "infohub_oidc_state"
+
+	oidcStatePrefix     = "auth:oidc:state:"
+	oidcStateUsedPrefix = "auth:oidc:state-used:"
+	sessionPrefix       = "auth:session:"
+)
+
+// oidcLoginTTL - сколько ждать возврата пользователя от провайдера
+const oidcLoginTTL = 10 * time.Minute
+
+// OIDCConfig задает вход в админ-панель через OpenID Connect провайдер
+type OIDCConfig struct {
+	Enabled      bool   `yaml:"enabled" json:"enabled"`
+	Issuer       string `yaml:"issuer" json:"issuer"`
+	ClientID     string `yaml:"client_id" json:"client_id"`
+	ClientSecret string `yaml:"client_secret" json:"client_secret"`
+	// RedirectURL - адрес /admin/callback, зарегистрированный у провайдера
+	RedirectURL  string        `yaml:"redirect_url" json:"redirect_url"`
+	Scopes       []string      `yaml:"scopes" json:"scopes"`
+	SessionTTL   time.Duration `yaml:"session_ttl" json:"session_ttl"`
+	CookieSecure bool          `yaml:"cookie_secure" json:"cookie_secure"`
+	ClaimMapping `yaml:",inline"`
+}
+
+// oidcMetadata - нужная часть документа discovery провайдера
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// pendingLogin хранит параметры начатого входа до возврата от провайдера
+type pendingLogin struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	ReturnTo string `json:"return_to"`
+}
+
+// OIDCProvider реализует вход по authorization code с PKCE. Сессии хранятся
+// в хранилище менеджера под хэшем идентификатора, браузер получает только
+// сам случайный идентификатор сессии
+type OIDCProvider struct {
+	config    OIDCConfig
+	manager   *Manager
+	client    *http.Client
+	metadata  *oidcMetadata
+	jwks      *JWKSClient
+	mutex     sync.Mutex
+	discovery singleflight.Group
+}
+
+// newOIDCProvider проверяет настройки и создает провайдера. Discovery выполняется
+// при первом входе, поэтому недоступность провайдера не мешает запуску сервиса
+func newOIDCProvider(config OIDCConfig, manager *Manager) (*OIDCProvider, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc requires issuer, client_id and redirect_url")
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+	if config.SessionTTL == 0 {
+		config.SessionTTL = 8 * time.Hour
+	}
+	config.ClaimMapping = config.ClaimMapping.withDefaults()
+	if err := config.ClaimMapping.validate(); err != nil {
+		return nil, err
+	}
+
+	return &OIDCProvider{
+		config:  config,
+		manager: manager,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// OIDC возвращает провайдера входа через OpenID Connect или nil, если он не настроен
+func (m *Manager) OIDC() *OIDCProvider {
+	return m.oidc
+}
+
+// Login начинает вход: сохраняет verifier PKCE и nonce и перенаправляет к провайдеру
+func (p *OIDCProvider) Login(w http.ResponseWriter, r *http.Request) {
+	metadata, err := p.discover(r.Context())
+	if err != nil {
+		fmt.Printf("OIDC discovery failed: %v\n", err)
+		http.Error(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	state, err1 := randomToken(16)
+	nonce, err2 := randomToken(16)
+	verifier, err3 := randomToken(32)
+	if err = errors.Join(err1, err2, err3); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	pending := pendingLogin{Verifier: verifier, Nonce: nonce, ReturnTo: safeReturnTo(r.URL.Query().Get("return_to"))}
+	if err = p.manager.tokens.Set(r.Context(), oidcStatePrefix+state, pending, oidcLoginTTL); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// state дополнительно привязывается к браузеру, начавшему вход
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/admin/",
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   p.config.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	http.Redirect(w, r, addQuery(metadata.AuthorizationEndpoint, query), http.StatusFound)
+}
+
+// Callback завершает вход: обменивает код на токены, проверяет ID токен и открывает сессию
+func (p *OIDCProvider) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if errorCode := query.Get("error"); errorCode != "" {
+		http.Error(w, "Login failed: "+errorCode, http.StatusUnauthorized)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if state == "" || err != nil || cookie.Value != state {
+		http.Error(w, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/admin/", MaxAge: -1})
+
+	pending, err := p.consumeState(r.Context(), state)
+	if err != nil {
+		http.Error(w, "Login has expired, please try again", http.StatusBadRequest)
+		return
+	}
+
+	user, err := p.exchange(r.Context(), query.Get("code"), pending)
+	if err != nil {
+		fmt.Printf("OIDC login failed: %v\n", err)
+		audit.SetOutcome(r.Context(), audit.OutcomeFailure, err.Error())
+		http.Error(w, "Login failed", http.StatusUnauthorized)
+		return
+	}
+	audit.Mark(r.Context(), "", user.ID, "")
+
+	sessionID, err := randomToken(32)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if err = p.manager.tokens.Set(r.Context(), sessionKey(sessionID), user, p.config.SessionTTL); err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   int(p.config.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   p.config.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, pending.ReturnTo, http.StatusFound)
+}
+
+// Logout закрывает сессию и, если провайдер это поддерживает, завершает сессию у него.
+// Принимается только POST со своего origin, чтобы чужая страница не могла
+// завершить сессию администратора
+func (p *OIDCProvider) Logout(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-origin logout is not allowed", http.StatusForbidden)
+		return
+	}
+
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		var user User
+		if p.manager.tokens.Get(r.Context(), sessionKey(cookie.Value), &user) == nil {
+			audit.Mark(r.Context(), "", user.ID, "")
+		}
+		p.manager.tokens.Delete(r.Context(), sessionKey(cookie.Value))
+	}
+	http.SetCookie(w, &http.Cookie{Name: SessionCookieName, Path: "/", MaxAge: -1})
+
+	target := "/"
+	p.mutex.Lock()
+	if p.metadata != nil && p.metadata.EndSessionEndpoint != "" {
+		target = addQuery(p.metadata.EndSessionEndpoint, url.Values{"client_id": {p.config.ClientID}})
+	}
+	p.mutex.Unlock()
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// RequireSession защищает страницы для браузера: без сессии пользователь
+// перенаправляется на вход, без области доступа получает 403
+func (p *OIDCProvider) RequireSession(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := p.manager.sessionUser(r)
+			if err != nil {
+				http.Redirect(w, r, "/admin/login?return_to="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+				return
+			}
+			if !user.HasScope(scope) {
+				http.Error(w, fmt.Sprintf("Missing required scope: %s", scope), http.StatusForbidden)
+				return
+			}
+
+			r.Header.Set("X-User-ID", user.ID)
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// sessionUser возвращает пользователя сессии из cookie. Изменяющие запросы
+// с cookie принимаются только с собственного origin, чтобы сессию нельзя
+// было использовать с чужих страниц
+func (m *Manager) sessionUser(r *http.Request) (*User, error) {
+	if m.oidc == nil {
+		return nil, errNoSession
+	}
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, errNoSession
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if !sameOrigin(r) {
+			return nil, fmt.Errorf("cross-origin request with session cookie")
+		}
+	}
+
+	var user User
+	if err = m.tokens.Get(r.Context(), sessionKey(cookie.Value), &user); err != nil {
+		if errors.Is(err, cache.ErrCacheMiss) {
+			return nil, errNoSession
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// errNoSession означает, что запрос не несет действующей сессии
+var errNoSession = errors.New("no session")
+
+// sessionKey возвращает ключ сессии в хранилище. Хранится хэш идентификатора,
+// а не он сам: по ключам хранилища нельзя восстановить значение cookie
+func sessionKey(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return sessionPrefix + hex.EncodeToString(sum[:])
+}
+
+// consumeState возвращает параметры начатого входа и отмечает state
+// использованным. Отметка атомарна, поэтому из одновременных возвратов
+// с одним state проходит только один
+func (p *OIDCProvider) consumeState(ctx context.Context, state string) (pendingLogin, error) {
+	var pending pendingLogin
+	if err := p.manager.tokens.Get(ctx, oidcStatePrefix+state, &pending); err != nil {
+		return pendingLogin{}, err
+	}
+
+	first, err := p.manager.tokens.SetNX(ctx, oidcStateUsedPrefix+state, true, oidcLoginTTL)
+	if err != nil {
+		return pendingLogin{}, err
+	}
+	if !first {
+		return pendingLogin{}, fmt.Errorf("login state has already been used")
+	}
+	p.manager.tokens.Delete(ctx, oidcStatePrefix+state)
+
+	return pending, nil
+}
+
+// discover возвращает документ discovery провайдера, загружая его при первом
+// обращении. Запрос к провайдеру выполняется без блокировки, одновременные
+// входы ждут одного запроса, а отмена первого из них не прерывает запрос
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mutex.Lock()
+	metadata := p.metadata
+	p.mutex.Unlock()
+	if metadata != nil {
+		return metadata, nil
+	}
+
+	result, err, _ := p.discovery.Do(p.config.Issuer, func() (interface{}, error) {
+		metadata, err := p.fetchMetadata(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if p.metadata == nil {
+			p.metadata = metadata
+			p.jwks = NewJWKSClient(metadata.JWKSURI, p.client, 0)
+		}
+		return p.metadata, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*oidcMetadata), nil
+}
+
+// fetchMetadata загружает и проверяет документ discovery провайдера
+func (p *OIDCProvider) fetchMetadata(ctx context.Context) (*oidcMetadata, error) {
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery returned status %d", resp.StatusCode)
+	}
+
+	var metadata oidcMetadata
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponseSize)).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document is incomplete")
+	}
+	return &metadata, nil
+}
+
+// exchange обменивает код авторизации на ID токен и строит по нему пользователя
+func (p *OIDCProvider) exchange(ctx context.Context, code string, pending pendingLogin) (*User, error) {
+	if code == "" {
+		return nil, fmt.Errorf("no authorization code")
+	}
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {pending.Verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponseSize)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.IDToken == "" {
+		return nil, fmt.Errorf("token request rejected: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+
+	p.mutex.Lock()
+	jwks := p.jwks
+	p.mutex.Unlock()
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokens.IDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return jwks.Key(ctx, kid, token.Method.Alg())
+	},
+		jwt.WithValidMethods(externalTokenMethods),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != pending.Nonce {
+		return nil, fmt.Errorf("id token nonce mismatch")
+	}
+
+	return p.config.ClaimMapping.user(claims)
+}
+
+// sameOrigin проверяет, что запрос отправлен со страницы этого же сервиса
+func sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return false
+	}
+
+	parsed, err := url.Parse(source)
+	return err == nil && parsed.Host == r.Host
+}
+
+// safeReturnTo ограничивает возврат после входа страницами админ-панели
+func safeReturnTo(target string) string {
+	if strings.HasPrefix(target, "/admin/") && !strings.HasPrefix(target, "//") {
+		return target
+	}
+	return "/admin/"
+}
+
+// addQuery добавляет параметры к адресу, который может уже содержать query
+func addQuery(endpoint string, query url.Values) string {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	return endpoint + separator + query.Encode()
+}
+
+// randomToken генерирует случайную строку для URL из size байт
+func randomToken(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
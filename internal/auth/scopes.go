@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Области доступа, проверяемые маршрутами API. Ключам можно выдавать
+// и произвольные области: они учитываются, когда появятся маршруты с ними
+const (
+	ScopeAll         = "*"
+	ScopeNewsRead    = "news:read"
+	ScopeNewsExport  = "news:export"
+	ScopeIngest      = "ingest"
+	ScopeSourcesRead = "sources:read"
+	ScopeAdminStats  = "admin:stats"
+	ScopeAdminCache  = "admin:cache"
+	ScopeAdminKeys   = "admin:keys"
+	ScopeAdminPanel  = "admin:panel"
+	ScopeAdminUsage  = "admin:usage"
+	ScopeAdminAudit  = "admin:audit"
+)
+
+// legacyScopes сопоставляет области, выдававшиеся до появления областей
+// по ресурсам, новым. Нужно для JWT, выпущенных предыдущими версиями
+var legacyScopes = map[string]string{
+	"read": ScopeNewsRead,
+}
+
+// scopePattern описывает допустимое имя области: сегменты через двоеточие,
+// последний сегмент может быть "*"
+var scopePattern = regexp.MustCompile(`^(\*|[a-z0-9_-]+(:[a-z0-9_-]+)*(:\*)?)$`)
+
+// ErrInvalidScope возвращается для недопустимого имени области доступа
+var ErrInvalidScope = errors.New("invalid scope")
+
+// ValidateScopes проверяет имена областей доступа
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !scopePattern.MatchString(scope) {
+			return fmt.Errorf("%w %q", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+type userContextKey struct{}
+
+// WithUser сохраняет аутентифицированного пользователя в контексте запроса
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext возвращает пользователя, сохраненного middleware аутентификации
+func UserFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*User)
+	return user, ok && user != nil
+}
@@ -0,0 +1,425 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Алгоритмы подписи JWT
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits - размер генерируемых RSA ключей
+const rsaKeyBits = 2048
+
+// keyReloadInterval ограничивает перечитывание каталога ключей при встрече незнакомого kid
+const keyReloadInterval = 10 * time.Second
+
+// SigningConfig задает подпись JWT
+type SigningConfig struct {
+	Algorithm string `yaml:"algorithm" json:"algorithm"` // "HS256", "RS256", "ES256" или "EdDSA"
+	// KeysDir - каталог закрытых ключей в PEM. Реплики с общим каталогом используют
+	// одни ключи; пустое значение - ключи живут только в памяти процесса
+	KeysDir string `yaml:"keys_dir" json:"keys_dir"`
+	// RotationInterval - как часто выпускается новый ключ подписи; 0 отключает ротацию
+	RotationInterval time.Duration `yaml:"rotation_interval" json:"rotation_interval"`
+	// AllowedAlgorithms - алгоритмы принимаемых токенов, по умолчанию только Algorithm.
+	// Добавьте HS256 на время перехода на асимметричную подпись, чтобы ранее
+	// выданные токены продолжали приниматься
+	AllowedAlgorithms []string `yaml:"allowed_algorithms" json:"allowed_algorithms"`
+}
+
+// validateAllowed проверяет список принимаемых алгоритмов. HS256 требует общего
+// секрета, асимметричные алгоритмы - асимметричной подписи
+func (c *SigningConfig) validateAllowed(hasSecret bool) error {
+	for _, algorithm := range c.AllowedAlgorithms {
+		switch algorithm {
+		case AlgorithmHS256:
+			if !hasSecret {
+				return fmt.Errorf("allowed algorithm HS256 requires jwt_secret")
+			}
+		case AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA:
+			if c.Algorithm == AlgorithmHS256 {
+				return fmt.Errorf("allowed algorithm %s requires asymmetric signing", algorithm)
+			}
+		default:
+			return fmt.Errorf("unsupported allowed algorithm: %s", algorithm)
+		}
+	}
+	return nil
+}
+
+// signingKey - закрытый ключ подписи с идентификатором
+type signingKey struct {
+	id        string
+	algorithm string
+	createdAt time.Time
+	private   crypto.Signer
+}
+
+// KeyRing хранит ключи асимметричной подписи. Новые токены подписываются
+// самым свежим ключом, выведенные из оборота ключи остаются для проверки,
+// пока не истекут подписанные ими токены
+type KeyRing struct {
+	config     SigningConfig
+	retention  time.Duration
+	keys       []*signingKey // от старых к новым
+	lastReload time.Time
+	mutex      sync.RWMutex
+}
+
+// NewKeyRing загружает ключи из каталога и выпускает первый ключ, если подходящих нет.
+// retention - максимальный срок жизни токенов, подписанных выведенным ключом
+func NewKeyRing(config SigningConfig, retention time.Duration) (*KeyRing, error) {
+	if _, err := signingMethod(config.Algorithm); err != nil {
+		return nil, err
+	}
+	if config.Algorithm == AlgorithmHS256 {
+		return nil, fmt.Errorf("key ring requires an asymmetric algorithm")
+	}
+
+	ring := &KeyRing{config: config, retention: retention}
+	if config.KeysDir != "" {
+		if err := os.MkdirAll(config.KeysDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create signing keys directory: %w", err)
+		}
+	}
+
+	if _, err := ring.Rotate(false); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// Rotate перечитывает каталог ключей, удаляет ключи с истекшим сроком
+// и выпускает новый ключ, если текущий старше интервала ротации или force.
+// Возвращает true, если был выпущен новый ключ
+func (r *KeyRing) Rotate(force bool) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.reload(); err != nil {
+		return false, err
+	}
+	r.prune(time.Now())
+
+	active := r.active()
+	due := active == nil || force ||
+		(r.config.RotationInterval > 0 && time.Since(active.createdAt) >= r.config.RotationInterval)
+	if !due {
+		return false, nil
+	}
+
+	key, err := generateSigningKey(r.config.Algorithm)
+	if err != nil {
+		return false, err
+	}
+	if err = r.store(key); err != nil {
+		return false, err
+	}
+	r.keys = append(r.keys, key)
+	return true, nil
+}
+
+// Run периодически проверяет, не пора ли сменить ключ подписи
+func (r *KeyRing) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rotated, err := r.Rotate(false)
+			if err != nil {
+				fmt.Printf("Failed to rotate JWT signing key: %v\n", err)
+			} else if rotated {
+				fmt.Printf("JWT signing key rotated: kid=%s\n", r.activeID())
+			}
+		}
+	}
+}
+
+// Sign подписывает токен активным ключом и проставляет kid в заголовок
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	r.mutex.RLock()
+	key := r.active()
+	r.mutex.RUnlock()
+
+	if key == nil {
+		return "", fmt.Errorf("no active signing key")
+	}
+
+	method, _ := signingMethod(key.algorithm)
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// VerificationKey возвращает открытый ключ по kid. Незнакомый kid мог
+// быть выпущен другой репликой, поэтому каталог перечитывается
+func (r *KeyRing) VerificationKey(kid, algorithm string) (crypto.PublicKey, error) {
+	key := r.find(kid)
+	if key == nil {
+		r.mutex.Lock()
+		if r.config.KeysDir != "" && time.Since(r.lastReload) > keyReloadInterval {
+			if err := r.reload(); err != nil {
+				fmt.Printf("Failed to reload JWT signing keys: %v\n", err)
+			}
+		}
+		r.mutex.Unlock()
+		key = r.find(kid)
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.algorithm != algorithm {
+		return nil, fmt.Errorf("signing key %q does not use %s", kid, algorithm)
+	}
+	return key.private.Public(), nil
+}
+
+// JWKS возвращает открытые ключи всех ключей кольца
+func (r *KeyRing) JWKS() JWKS {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(r.keys))}
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		key := r.keys[i]
+		jwk, err := newJWK(key.id, key.algorithm, key.private.Public())
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (r *KeyRing) find(kid string) *signingKey {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, key := range r.keys {
+		if key.id == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+func (r *KeyRing) activeID() string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if key := r.active(); key != nil {
+		return key.id
+	}
+	return ""
+}
+
+// active возвращает самый свежий ключ настроенного алгоритма. Вызывается под блокировкой
+func (r *KeyRing) active() *signingKey {
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if r.keys[i].algorithm == r.config.Algorithm {
+			return r.keys[i]
+		}
+	}
+	return nil
+}
+
+// prune удаляет ключи, которыми уже не может быть подписан ни один действующий токен.
+// Ключ выводится из оборота при выпуске следующего. Вызывается под блокировкой
+func (r *KeyRing) prune(now time.Time) {
+	if r.config.RotationInterval <= 0 {
+		return
+	}
+
+	kept := r.keys[:0]
+	for i, key := range r.keys {
+		retired := i < len(r.keys)-1 && now.Sub(r.keys[i+1].createdAt) > r.retention
+		if retired {
+			if r.config.KeysDir != "" {
+				os.Remove(r.keyPath(key.id))
+			}
+			continue
+		}
+		kept = append(kept, key)
+	}
+	r.keys = kept
+}
+
+// reload перечитывает каталог ключей. Вызывается под блокировкой
+func (r *KeyRing) reload() error {
+	r.lastReload = time.Now()
+	if r.config.KeysDir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(r.config.KeysDir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+
+	r.keys = keys
+	return nil
+}
+
+// store сохраняет ключ в каталог. Вызывается под блокировкой
+func (r *KeyRing) store(key *signingKey) error {
+	if r.config.KeysDir == "" {
+		return nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return err
+	}
+	block := &pem.Block{
+		Type: "PRIVATE KEY",
+		Headers: map[string]string{
+			"Key-Id":     key.id,
+			"Algorithm":  key.algorithm,
+			"Created-At": key.createdAt.Format(time.RFC3339),
+		},
+		Bytes: der,
+	}
+
+	// Ключ пишется во временный файл, чтобы другие реплики не прочитали его частично
+	tmp := r.keyPath(key.id) + ".tmp"
+	if err = os.WriteFile(tmp, pem.EncodeToMemory(block), 0600); err != nil {
+		return fmt.Errorf("failed to write signing key: %w", err)
+	}
+	if err = os.Rename(tmp, r.keyPath(key.id)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write signing key: %w", err)
+	}
+	return nil
+}
+
+func (r *KeyRing) keyPath(kid string) string {
+	return filepath.Join(r.config.KeysDir, kid+".pem")
+}
+
+// loadSigningKey читает ключ, сохраненный KeyRing.store
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("invalid signing key file %s", path)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key file %s: %w", path, err)
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key in %s", path)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, block.Headers["Created-At"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid creation time in %s: %w", path, err)
+	}
+
+	id := block.Headers["Key-Id"]
+	if id == "" {
+		id = strings.TrimSuffix(filepath.Base(path), ".pem")
+	}
+
+	return &signingKey{
+		id:        id,
+		algorithm: block.Headers["Algorithm"],
+		createdAt: createdAt,
+		private:   signer,
+	}, nil
+}
+
+// generateSigningKey выпускает ключ для алгоритма
+func generateSigningKey(algorithm string) (*signingKey, error) {
+	var private crypto.Signer
+	var err error
+
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &signingKey{
+		id:        now.Format("20060102") + "-" + hex.EncodeToString(suffix),
+		algorithm: algorithm,
+		createdAt: now.Truncate(time.Second),
+		private:   private,
+	}, nil
+}
+
+// signingMethod сопоставляет алгоритм методу подписи jwt
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		return jwt.SigningMethodHS256, nil
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmES256:
+		return jwt.SigningMethodES256, nil
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
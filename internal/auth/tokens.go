@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/pah-an/infohub/internal/cache"
+)
+
+// Типы токенов в claim "typ"
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// Префиксы ключей списка отзыва в кэше
+const (
+	revokedTokenPrefix  = "auth:revoked:"
+	revokedFamilyPrefix = "auth:revoked-family:"
+)
+
+// Ошибки проверки токенов
+var (
+	ErrTokenRevoked     = errors.New("token has been revoked")
+	ErrWrongTokenType   = errors.New("unexpected token type")
+	ErrRefreshTokenUsed = errors.New("refresh token has already been used")
+	ErrSessionExpired   = errors.New("session has reached its maximum age")
+)
+
+// TokenPair содержит короткоживущий access токен и refresh токен для его обновления
+type TokenPair struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresIn  time.Duration
+	RefreshExpiresIn time.Duration
+}
+
+// SetTokenStore задает хранилище списка отзыва токенов, сессий OIDC и nonce
+// подписанных запросов. Оно не должно совпадать с кэшем данных, из которого
+// записи вытесняются и удаляются через admin API. При нескольких репликах
+// хранилище должно быть общим, иначе отзыв действует только на одной из них
+func (m *Manager) SetTokenStore(store cache.Cache) {
+	if m.ownTokenStore && m.tokens != nil {
+		m.tokens.Close()
+	}
+	m.tokens = store
+	m.ownTokenStore = false
+}
+
+// IssueTokens выпускает access и refresh токены нового семейства.
+// Семейство объединяет все refresh токены, полученные ротацией от одного входа
+func (m *Manager) IssueTokens(user *User) (TokenPair, error) {
+	family, err := newTokenID()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return m.issueTokens(user, family, time.Now())
+}
+
+// Refresh обменивает refresh токен на новую пару токенов. Использованный
+// refresh токен отзывается; повторное его предъявление означает утечку,
+// поэтому отзывается все семейство. Сессия не продлевается дольше
+// MaxSessionAge от входа, а ключ, по которому был выполнен вход, проверяется
+// заново, и новые токены получают его текущие права
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (TokenPair, *User, error) {
+	claims, err := m.parseToken(refreshToken)
+	if err != nil {
+		return TokenPair{}, nil, err
+	}
+	if claims.TokenType != TokenTypeRefresh {
+		return TokenPair{}, nil, ErrWrongTokenType
+	}
+
+	if err = m.checkRevoked(ctx, claims); err != nil {
+		if errors.Is(err, ErrTokenRevoked) && claims.Family != "" {
+			if revokeErr := m.revokeFamily(ctx, claims.Family); revokeErr != nil {
+				return TokenPair{}, nil, revokeErr
+			}
+			return TokenPair{}, nil, ErrRefreshTokenUsed
+		}
+		return TokenPair{}, nil, err
+	}
+
+	authTime := claims.authTime()
+	if time.Since(authTime) >= m.config.MaxSessionAge {
+		return TokenPair{}, nil, ErrSessionExpired
+	}
+	user, err := m.currentUser(claims)
+	if err != nil {
+		return TokenPair{}, nil, err
+	}
+
+	// Отзыв атомарен: из одновременных обменов одного токена проходит один,
+	// остальные считаются повторным использованием
+	first, err := m.revokeClaimsOnce(ctx, claims)
+	if err != nil {
+		return TokenPair{}, nil, err
+	}
+	if !first {
+		if claims.Family != "" {
+			if err = m.revokeFamily(ctx, claims.Family); err != nil {
+				return TokenPair{}, nil, err
+			}
+		}
+		return TokenPair{}, nil, ErrRefreshTokenUsed
+	}
+
+	pair, err := m.issueTokens(user, claims.Family, authTime)
+	if err != nil {
+		return TokenPair{}, nil, err
+	}
+	return pair, user, nil
+}
+
+// RevokeToken отзывает токен по jti до истечения его срока. Для refresh
+// токена отзывается все семейство, чтобы выход завершал сессию целиком
+func (m *Manager) RevokeToken(ctx context.Context, tokenString string) error {
+	claims, err := m.parseToken(tokenString)
+	if err != nil {
+		return err
+	}
+
+	if err = m.revokeClaims(ctx, claims); err != nil {
+		return err
+	}
+	if claims.TokenType == TokenTypeRefresh && claims.Family != "" {
+		return m.revokeFamily(ctx, claims.Family)
+	}
+	return nil
+}
+
+// issueTokens выпускает пару токенов заданного семейства. Сроки токенов
+// не выходят за MaxSessionAge от момента входа authTime
+func (m *Manager) issueTokens(user *User, family string, authTime time.Time) (TokenPair, error) {
+	remaining := time.Until(authTime.Add(m.config.MaxSessionAge))
+	accessTTL := min(m.config.JWTTTL, remaining)
+	refreshTTL := min(m.config.RefreshTTL, remaining)
+
+	access, err := m.signToken(user, TokenTypeAccess, family, accessTTL, authTime)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := m.signToken(user, TokenTypeRefresh, family, refreshTTL, authTime)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		AccessExpiresIn:  accessTTL,
+		RefreshExpiresIn: refreshTTL,
+	}, nil
+}
+
+// signToken подписывает токен с уникальным jti
+func (m *Manager) signToken(user *User, tokenType, family string, ttl time.Duration, authTime time.Time) (string, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:    user.ID,
+		APIKey:    user.APIKey,
+		IsAdmin:   user.IsAdmin,
+		Scopes:    user.Scopes,
+		TokenType: tokenType,
+		Family:    family,
+		KeyID:     user.KeyID,
+		AuthTime:  authTime.Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    ownIssuer,
+			Subject:   user.ID,
+		},
+	}
+
+	if m.signing != nil {
+		return m.signing.Sign(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(m.jwtSecret)
+}
+
+// checkRevoked проверяет список отзыва для токена и его семейства
+func (m *Manager) checkRevoked(ctx context.Context, claims *Claims) error {
+	keys := make([]string, 0, 2)
+	if claims.ID != "" {
+		keys = append(keys, revokedTokenPrefix+claims.ID)
+	}
+	if claims.Family != "" {
+		keys = append(keys, revokedFamilyPrefix+claims.Family)
+	}
+
+	for _, key := range keys {
+		var revoked bool
+		err := m.tokens.Get(ctx, key, &revoked)
+		switch {
+		case err == nil:
+			return ErrTokenRevoked
+		case !errors.Is(err, cache.ErrCacheMiss):
+			// Без списка отзыва нельзя доверять токену
+			return fmt.Errorf("failed to check token revocation: %w", err)
+		}
+	}
+	return nil
+}
+
+// revokeClaims заносит jti токена в список отзыва до истечения его срока
+func (m *Manager) revokeClaims(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return fmt.Errorf("token cannot be revoked: no jti or expiration")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return m.tokens.Set(ctx, revokedTokenPrefix+claims.ID, true, ttl)
+}
+
+// revokeClaimsOnce атомарно заносит jti в список отзыва. Возвращает false,
+// если токен уже был отозван
+func (m *Manager) revokeClaimsOnce(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return false, fmt.Errorf("token cannot be revoked: no jti or expiration")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return false, nil
+	}
+	return m.tokens.SetNX(ctx, revokedTokenPrefix+claims.ID, true, ttl)
+}
+
+// revokeFamily отзывает все refresh токены семейства и выпущенные по ним access токены
+func (m *Manager) revokeFamily(ctx context.Context, family string) error {
+	return m.tokens.Set(ctx, revokedFamilyPrefix+family, true, m.config.RefreshTTL)
+}
+
+// currentUser возвращает пользователя токена, заново проверяя ключ, по
+// которому выполнен вход. Ключ хранилища не должен быть отозван или истечь,
+// а ключ из конфигурации должен в ней оставаться; для него возвращаются
+// текущие права. Токены, выданные без ключа, не проверяются
+func (m *Manager) currentUser(claims *Claims) (*User, error) {
+	if claims.KeyID != "" {
+		if m.keys == nil {
+			return nil, ErrKeyNotFound
+		}
+		if err := m.keys.Status(claims.KeyID); err != nil {
+			return nil, err
+		}
+		return claims.user(), nil
+	}
+
+	if claims.APIKey == "" || claims.APIKey == "none" {
+		return claims.user(), nil
+	}
+	user, ok := m.configKeyUser(claims.APIKey)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return user, nil
+}
+
+// authTime возвращает момент входа, с которого началась сессия. Для токенов
+// без auth_time, выпущенных до его появления, берется время выпуска
+func (c *Claims) authTime() time.Time {
+	if c.AuthTime > 0 {
+		return time.Unix(c.AuthTime, 0)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Now()
+}
+
+// user восстанавливает пользователя из claims
+func (c *Claims) user() *User {
+	return &User{
+		ID:      c.UserID,
+		APIKey:  c.APIKey,
+		IsAdmin: c.IsAdmin,
+		Scopes:  c.Scopes,
+		KeyID:   c.KeyID,
+	}
+}
+
+// newTokenID генерирует случайный идентификатор токена
+func newTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,360 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pah-an/infohub/internal/domain"
+)
+
+// Format представляет формат выгрузки/загрузки новостей
+type Format string
+
+const (
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+const (
+	// allNews используется как лимит для чтения всего содержимого репозитория
+	allNews = math.MaxInt32
+	// batchSize - количество новостей, читаемых или записываемых за один раз
+	batchSize = 500
+)
+
+// csvHeader задает порядок колонок в CSV
+var csvHeader = []string{"id", "title", "description", "url", "source", "category", "published_at"}
+
+// ParseFormat разбирает формат из строки
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case FormatNDJSON, "":
+		return FormatNDJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", s)
+	}
+}
+
+// ContentType возвращает MIME тип для формата
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// TimeRange ограничивает выборку по дате публикации. Нулевые границы не применяются
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Export потоково выгружает новости из репозитория в w и возвращает количество
+// записей. Хранилища с поддержкой запросов читаются страницами по batchSize,
+// поэтому выгрузка не держит всю базу в памяти. Выгрузка не является снимком:
+// новости, добавленные во время чтения, могут попасть в нее дважды
+func Export(w io.Writer, repo domain.NewsRepository, format Format, tr TimeRange) (int, error) {
+	var encoder recordEncoder
+	switch format {
+	case FormatNDJSON:
+		encoder = newNDJSONEncoder(w)
+	case FormatCSV:
+		csvEncoder, err := newCSVEncoder(w)
+		if err != nil {
+			return 0, err
+		}
+		encoder = csvEncoder
+	default:
+		return 0, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	count := 0
+	err := eachBatch(repo, domain.NewsFilter{From: tr.From, To: tr.To}, func(news domain.NewsList) error {
+		for _, item := range news {
+			if err := encoder.encode(item); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, encoder.flush()
+}
+
+// eachBatch передает fn новости, подходящие под фильтр, страницами по batchSize.
+// Хранилища без поддержки запросов читаются целиком и фильтруются в памяти
+func eachBatch(repo domain.NewsRepository, filter domain.NewsFilter, fn func(domain.NewsList) error) error {
+	if querier, ok := repo.(domain.NewsQuerier); ok {
+		filter.Limit = batchSize
+		for filter.Offset = 0; ; filter.Offset += batchSize {
+			news, total, err := querier.QueryNews(filter)
+			if errors.Is(err, errors.ErrUnsupported) && filter.Offset == 0 {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read news: %w", err)
+			}
+			if len(news) > 0 {
+				if err = fn(news); err != nil {
+					return err
+				}
+			}
+			if len(news) < batchSize || filter.Offset+len(news) >= total {
+				return nil
+			}
+		}
+	}
+
+	news, err := repo.GetLatestNews(allNews)
+	if err != nil {
+		return fmt.Errorf("failed to read news: %w", err)
+	}
+
+	news, _ = news.SortByDate().Query(filter)
+	return fn(news)
+}
+
+// recordEncoder записывает новости в выбранном формате
+type recordEncoder interface {
+	encode(item domain.News) error
+	flush() error
+}
+
+type ndjsonEncoder struct {
+	bw      *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) *ndjsonEncoder {
+	bw := bufio.NewWriter(w)
+	return &ndjsonEncoder{bw: bw, encoder: json.NewEncoder(bw)}
+}
+
+func (e *ndjsonEncoder) encode(item domain.News) error {
+	return e.encoder.Encode(item)
+}
+
+func (e *ndjsonEncoder) flush() error {
+	return e.bw.Flush()
+}
+
+type csvEncoder struct {
+	cw *csv.Writer
+}
+
+// newCSVEncoder создает кодировщик и сразу записывает заголовок
+func newCSVEncoder(w io.Writer) (*csvEncoder, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &csvEncoder{cw: cw}, nil
+}
+
+func (e *csvEncoder) encode(item domain.News) error {
+	return e.cw.Write([]string{
+		item.ID,
+		item.Title,
+		item.Description,
+		item.URL,
+		item.Source,
+		item.Category,
+		item.PublishedAt.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (e *csvEncoder) flush() error {
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+// Decode читает новости из r в указанном формате
+func Decode(r io.Reader, format Format) (domain.NewsList, error) {
+	news := domain.NewsList{}
+	err := decode(r, format, func(item domain.News) error {
+		news = append(news, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return news, nil
+}
+
+// decode потоково разбирает r и передает fn каждую проверенную запись
+func decode(r io.Reader, format Format, fn func(domain.News) error) error {
+	switch format {
+	case FormatNDJSON:
+		return decodeNDJSON(r, fn)
+	case FormatCSV:
+		return decodeCSV(r, fn)
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func decodeNDJSON(r io.Reader, fn func(domain.News) error) error {
+	decoder := json.NewDecoder(r)
+
+	for line := 1; ; line++ {
+		var item domain.News
+		if err := decoder.Decode(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("record %d: %w", line, err)
+		}
+		if err := validate(item); err != nil {
+			return fmt.Errorf("record %d: %w", line, err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeCSV(r io.Reader, fn func(domain.News) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i, column := range csvHeader {
+		if strings.TrimSpace(header[i]) != column {
+			return fmt.Errorf("unexpected CSV header: want %s", strings.Join(csvHeader, ","))
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		publishedAt, err := time.Parse(time.RFC3339Nano, record[6])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid published_at: %w", line, err)
+		}
+
+		item := domain.News{
+			ID:          record[0],
+			Title:       record[1],
+			Description: record[2],
+			URL:         record[3],
+			Source:      record[4],
+			Category:    record[5],
+			PublishedAt: publishedAt,
+		}
+		if err = validate(item); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err = fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// validate проверяет обязательные поля импортируемой новости
+func validate(item domain.News) error {
+	if item.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if item.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	if item.PublishedAt.IsZero() {
+		return fmt.Errorf("published_at is required")
+	}
+	return nil
+}
+
+// ImportResult описывает результат импорта
+type ImportResult struct {
+	Imported int `json:"imported" example:"250"`
+	Total    int `json:"total" example:"1000"`
+}
+
+// Import потоково загружает новости из r и добавляет их в репозиторий
+// пачками по batchSize через domain.NewsUpserter. Записи с совпадающим ID
+// заменяются импортируемыми, остальные новости хранилища не затрагиваются.
+// Импорт не атомарен: при ошибке пачки, сохраненные до некорректной записи,
+// остаются в хранилище, а result.Imported показывает их количество.
+// Повторный импорт того же файла безопасен
+func Import(r io.Reader, repo domain.NewsRepository, format Format) (ImportResult, error) {
+	var result ImportResult
+
+	upserter, ok := repo.(domain.NewsUpserter)
+	if !ok {
+		return result, fmt.Errorf("repository does not support upserts")
+	}
+
+	batch := make(domain.NewsList, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := upserter.UpsertNews(batch); err != nil {
+			return fmt.Errorf("failed to save news: %w", err)
+		}
+		result.Imported += len(batch)
+		batch = make(domain.NewsList, 0, batchSize)
+		return nil
+	}
+
+	err := decode(r, format, func(item domain.News) error {
+		batch = append(batch, item)
+		if len(batch) < batchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if result.Total, err = countNews(repo); err != nil {
+		return result, fmt.Errorf("failed to count news: %w", err)
+	}
+
+	return result, nil
+}
+
+// countNews возвращает количество новостей в репозитории
+func countNews(repo domain.NewsRepository) (int, error) {
+	if querier, ok := repo.(domain.NewsQuerier); ok {
+		_, total, err := querier.QueryNews(domain.NewsFilter{Limit: 1})
+		if !errors.Is(err, errors.ErrUnsupported) {
+			return total, err
+		}
+	}
+
+	news, err := repo.GetLatestNews(allNews)
+	if err != nil {
+		return 0, err
+	}
+	return len(news), nil
+}
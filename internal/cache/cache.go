@@ -1,46 +1,91 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/pah-an/infohub/internal/domain"
 )
 
 // RedisCache реализует кэширование через Redis
 type RedisCache struct {
-	client *redis.Client
+	client redis.UniversalClient
+	codec  *valueCodec
 	prefix string
 	ttl    time.Duration
 }
 
+// Режимы подключения к Redis
+const (
+	RedisModeSingle   = "single"
+	RedisModeSentinel = "sentinel"
+	RedisModeCluster  = "cluster"
+)
+
 // Config содержит конфигурацию Redis
 type Config struct {
+	// Mode задает режим подключения: "single", "sentinel" или "cluster"
+	Mode     string        `yaml:"mode" json:"mode"`
 	Address  string        `yaml:"address" json:"address"`
+	Username string        `yaml:"username" json:"username"` // ACL пользователь (Redis 6+)
 	Password string        `yaml:"password" json:"password"`
 	DB       int           `yaml:"db" json:"db"`
 	TTL      time.Duration `yaml:"ttl" json:"ttl"`
+	StaleTTL time.Duration `yaml:"stale_ttl" json:"stale_ttl"` // stale-while-revalidate для кэша новостей
 	Prefix   string        `yaml:"prefix" json:"prefix"`
 	Enabled  bool          `yaml:"enabled" json:"enabled"`
+	Tiered   TieredConfig  `yaml:"tiered" json:"tiered"` // локальный L1 кэш перед Redis
+
+	Serialization SerializationConfig `yaml:"serialization" json:"serialization"`
+
+	// Addresses - адреса узлов кластера или sentinel. Если не заданы, используется Address
+	Addresses        []string  `yaml:"addresses" json:"addresses"`
+	MasterName       string    `yaml:"master_name" json:"master_name"`
+	SentinelUsername string    `yaml:"sentinel_username" json:"sentinel_username"`
+	SentinelPassword string    `yaml:"sentinel_password" json:"sentinel_password"`
+	TLS              TLSConfig `yaml:"tls" json:"tls"`
+}
+
+// TLSConfig содержит настройки TLS соединения с Redis
+type TLSConfig struct {
+	Enabled            bool   `yaml:"enabled" json:"enabled"`
+	CAFile             string `yaml:"ca_file" json:"ca_file"`
+	CertFile           string `yaml:"cert_file" json:"cert_file"` // клиентский сертификат для mTLS
+	KeyFile            string `yaml:"key_file" json:"key_file"`
+	ServerName         string `yaml:"server_name" json:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
 }
 
 // NewRedisCache создает новый Redis кэш
 func NewRedisCache(config Config) (*RedisCache, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     config.Address,
-		Password: config.Password,
-		DB:       config.DB,
-	})
+	codec, err := newValueCodec(config.Serialization)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newRedisClient(config)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := client.Ping(ctx).Err(); err != nil {
+	if err = client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
@@ -54,14 +99,86 @@ func NewRedisCache(config Config) (*RedisCache, error) {
 
 	return &RedisCache{
 		client: client,
+		codec:  codec,
 		prefix: config.Prefix,
 		ttl:    config.TTL,
 	}, nil
 }
 
+// newRedisClient создает клиент для выбранного режима подключения
+func newRedisClient(config Config) (redis.UniversalClient, error) {
+	addresses := config.Addresses
+	if len(addresses) == 0 && config.Address != "" {
+		addresses = []string{config.Address}
+	}
+
+	options := &redis.UniversalOptions{
+		Addrs:            addresses,
+		DB:               config.DB,
+		Username:         config.Username,
+		Password:         config.Password,
+		MasterName:       config.MasterName,
+		SentinelUsername: config.SentinelUsername,
+		SentinelPassword: config.SentinelPassword,
+	}
+
+	if config.TLS.Enabled {
+		tlsConfig, err := config.TLS.build()
+		if err != nil {
+			return nil, err
+		}
+		options.TLSConfig = tlsConfig
+	}
+
+	switch config.Mode {
+	case RedisModeSingle, "":
+		return redis.NewClient(options.Simple()), nil
+	case RedisModeSentinel:
+		if config.MasterName == "" {
+			return nil, fmt.Errorf("redis sentinel mode requires master_name")
+		}
+		return redis.NewFailoverClient(options.Failover()), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(options.Cluster()), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", config.Mode)
+	}
+}
+
+// build создает tls.Config из настроек
+func (c TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 // Set сохраняет данные в кэш
 func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	data, err := json.Marshal(value)
+	data, err := r.codec.encode(value)
 	if err != nil {
 		return fmt.Errorf("failed to marshal value: %w", err)
 	}
@@ -74,10 +191,25 @@ func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl
 	return r.client.Set(ctx, fullKey, data, ttl).Err()
 }
 
+// SetNX атомарно сохраняет значение, только если ключа еще нет.
+// Возвращает false, если ключ уже существует
+func (r *RedisCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := r.codec.encode(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if ttl == 0 {
+		ttl = r.ttl
+	}
+
+	return r.client.SetNX(ctx, r.prefix+key, data, ttl).Result()
+}
+
 // Get получает данные из кэша
 func (r *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
 	fullKey := r.prefix + key
-	data, err := r.client.Get(ctx, fullKey).Result()
+	data, err := r.client.Get(ctx, fullKey).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return ErrCacheMiss
@@ -85,7 +217,7 @@ func (r *RedisCache) Get(ctx context.Context, key string, dest interface{}) erro
 		return fmt.Errorf("failed to get from cache: %w", err)
 	}
 
-	if err = json.Unmarshal([]byte(data), dest); err != nil {
+	if err = r.codec.decode(data, dest); err != nil {
 		return fmt.Errorf("failed to unmarshal cached value: %w", err)
 	}
 
@@ -117,23 +249,156 @@ func (r *RedisCache) GetTTL(ctx context.Context, key string) (time.Duration, err
 	return r.client.TTL(ctx, fullKey).Result()
 }
 
-// Keys получает все ключи по паттерну
+// scanBatchSize задает размер порции ключей за одну итерацию SCAN
+const scanBatchSize = 500
+
+// Keys получает все ключи по паттерну. Ключи перебираются курсором SCAN,
+// который, в отличие от KEYS, не блокирует Redis на время обхода
 func (r *RedisCache) Keys(ctx context.Context, pattern string) ([]string, error) {
-	fullPattern := r.prefix + pattern
-	keys, err := r.client.Keys(ctx, fullPattern).Result()
+	var result []string
+	seen := make(map[string]struct{})
+	err := r.scan(ctx, pattern, func(keys []string) error {
+		for _, key := range keys {
+			// SCAN может вернуть один ключ несколько раз
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			// Убираем префикс из ключей
+			result = append(result, key[len(r.prefix):])
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Убираем префикс из ключей
-	result := make([]string, len(keys))
+	return result, nil
+}
+
+// MGet получает значения нескольких ключей за один проход в виде JSON.
+// Отсутствующие ключи не попадают в результат
+func (r *RedisCache) MGet(ctx context.Context, keys []string) (map[string]json.RawMessage, error) {
+	if len(keys) == 0 {
+		return map[string]json.RawMessage{}, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
 	for i, key := range keys {
-		result[i] = key[len(r.prefix):]
+		cmds[i] = pipe.Get(ctx, r.prefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get from cache: %w", err)
+	}
+
+	result := make(map[string]json.RawMessage, len(keys))
+	for i, cmd := range cmds {
+		data, err := cmd.Bytes()
+		if err != nil {
+			continue
+		}
+		if result[keys[i]], err = r.codec.toJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", keys[i], err)
+		}
 	}
 
 	return result, nil
 }
 
+// MSet сохраняет несколько значений за один проход
+func (r *RedisCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = r.ttl
+	}
+
+	pipe := r.client.Pipeline()
+	for key, value := range values {
+		data, err := r.codec.encode(value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal value for %s: %w", key, err)
+		}
+		pipe.Set(ctx, r.prefix+key, data, ttl)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeleteByPattern удаляет все ключи по паттерну и возвращает их количество.
+// Ключи сначала собираются полностью, чтобы удаление не влияло на обход курсором
+func (r *RedisCache) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	keys, err := r.Keys(ctx, pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for start := 0; start < len(keys); start += scanBatchSize {
+		end := start + scanBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		// Ключи удаляются по одному в конвейере: многоключевой DEL
+		// недопустим в Redis Cluster для ключей из разных слотов
+		pipe := r.client.Pipeline()
+		cmds := make([]*redis.IntCmd, 0, end-start)
+		for _, key := range keys[start:end] {
+			cmds = append(cmds, pipe.Unlink(ctx, r.prefix+key))
+		}
+		if _, err = pipe.Exec(ctx); err != nil {
+			return deleted, err
+		}
+		for _, cmd := range cmds {
+			deleted += int(cmd.Val())
+		}
+	}
+
+	return deleted, nil
+}
+
+// scan обходит ключи по паттерну порциями. В режиме кластера обходится
+// каждый master узел, так как SCAN видит только ключи своего узла
+func (r *RedisCache) scan(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, r.client, r.prefix+pattern, fn)
+	}
+
+	// ForEachMaster вызывает функцию параллельно для всех узлов
+	var mutex sync.Mutex
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return scanNode(ctx, node, r.prefix+pattern, func(keys []string) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return fn(keys)
+		})
+	})
+}
+
+// scanNode обходит ключи одного узла курсором SCAN
+func scanNode(ctx context.Context, client redis.Cmdable, match string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, match, scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err = fn(keys); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
 // Close закрывает соединение с Redis
 func (r *RedisCache) Close() error {
 	return r.client.Close()
@@ -144,74 +409,226 @@ func (r *RedisCache) Ping(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
 }
 
-// MemoryCache реализует in-memory кэширование
+// MemoryConfig содержит настройки in-memory кэша
+type MemoryConfig struct {
+	TTL             time.Duration `yaml:"ttl" json:"ttl"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" json:"cleanup_interval"`
+	MaxEntries      int           `yaml:"max_entries" json:"max_entries"` // 0 - без ограничения
+	MaxBytes        int64         `yaml:"max_bytes" json:"max_bytes"`     // 0 - без ограничения
+	Eviction        string        `yaml:"eviction" json:"eviction"`       // "lru" или "lfu"
+}
+
+// StatsRecorder принимает статистику обращений к кэшу
+type StatsRecorder interface {
+	RecordCacheHit(cache string)
+	RecordCacheMiss(cache string)
+	RecordCacheEviction(cache, reason string)
+}
+
+// MemoryCache реализует in-memory кэширование. Значения хранятся
+// в сериализованном виде, поэтому изменения объектов вызывающим кодом
+// не влияют на закэшированные данные. С MaxEntries или MaxBytes записи
+// вытесняются, поэтому состояние, которое нельзя терять, хранится в
+// отдельном кэше без ограничений (см. NewAuthStore)
 type MemoryCache struct {
-	data   map[string]*cacheItem
-	mutex  *sync.RWMutex
-	ttl    time.Duration
-	ticker *time.Ticker
-	done   chan bool
+	data       map[string]*cacheItem
+	mutex      *sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	policy     evictionPolicy
+	recorder   StatsRecorder
+	ticker     *time.Ticker
+	done       chan bool
 }
 
 type cacheItem struct {
-	value     interface{}
+	key       string
+	data      []byte
 	expiresAt time.Time
+
+	// Служебные поля политики вытеснения
+	element *list.Element
+	freq    int
 }
 
+// ErrValueTooLarge возвращается когда значение не помещается в кэш целиком
+var ErrValueTooLarge = fmt.Errorf("value exceeds cache size limit")
+
 // NewMemoryCache создает новый in-memory кэш
-func NewMemoryCache(ttl time.Duration, cleanupInterval time.Duration) *MemoryCache {
-	if ttl == 0 {
-		ttl = 5 * time.Minute
+func NewMemoryCache(config MemoryConfig) (*MemoryCache, error) {
+	if config.TTL == 0 {
+		config.TTL = 5 * time.Minute
 	}
-	if cleanupInterval == 0 {
-		cleanupInterval = 10 * time.Minute
+	if config.CleanupInterval == 0 {
+		config.CleanupInterval = 10 * time.Minute
+	}
+
+	policy, err := newEvictionPolicy(config.Eviction)
+	if err != nil {
+		return nil, err
 	}
 
 	cache := &MemoryCache{
-		data:   make(map[string]*cacheItem),
-		mutex:  &sync.RWMutex{},
-		ttl:    ttl,
-		ticker: time.NewTicker(cleanupInterval),
-		done:   make(chan bool),
+		data:       make(map[string]*cacheItem),
+		mutex:      &sync.Mutex{},
+		ttl:        config.TTL,
+		maxEntries: config.MaxEntries,
+		maxBytes:   config.MaxBytes,
+		policy:     policy,
+		ticker:     time.NewTicker(config.CleanupInterval),
+		done:       make(chan bool),
 	}
 
 	go cache.cleanup()
 
-	return cache
+	return cache, nil
+}
+
+// AuthStorePrefix отделяет в Redis ключи состояния аутентификации от ключей
+// кэша данных, чтобы шаблоны очистки и просмотра кэша их не затрагивали
+const AuthStorePrefix = "auth:"
+
+// NewAuthStore создает хранилище состояния аутентификации: списка отзыва
+// токенов, сессий и nonce. С Redis оно общее для реплик и использует префикс
+// AuthStorePrefix перед префиксом кэша, без Redis - отдельный MemoryCache без
+// ограничения размера, из которого записи не вытесняются до истечения TTL
+func NewAuthStore(redisConfig Config, useRedis bool) (Cache, error) {
+	if !useRedis {
+		memoryCache, err := NewMemoryCache(MemoryConfig{})
+		if err != nil {
+			return nil, err
+		}
+		return memoryCache, nil
+	}
+
+	if redisConfig.Prefix == "" {
+		redisConfig.Prefix = "infohub:"
+	}
+	redisConfig.Prefix = AuthStorePrefix + redisConfig.Prefix
+	redisConfig.Tiered = TieredConfig{}
+
+	redisCache, err := NewRedisCache(redisConfig)
+	if err != nil {
+		return nil, err
+	}
+	return redisCache, nil
+}
+
+// SetStatsRecorder подключает сбор статистики попаданий, промахов и вытеснений
+func (m *MemoryCache) SetStatsRecorder(recorder StatsRecorder) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.recorder = recorder
 }
 
 // Set сохраняет данные в memory кэш
 func (m *MemoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
 	if ttl == 0 {
 		ttl = m.ttl
 	}
 
+	item := &cacheItem{
+		key:       key,
+		data:      data,
+		expiresAt: time.Now().Add(ttl),
+	}
+	if m.maxBytes > 0 && item.size() > m.maxBytes {
+		return ErrValueTooLarge
+	}
+
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	m.data[key] = &cacheItem{
-		value:     value,
-		expiresAt: time.Now().Add(ttl),
+	if existing, exists := m.data[key]; exists {
+		m.removeItem(existing)
 	}
 
+	m.insertItem(item)
 	return nil
 }
 
+// insertItem добавляет элемент, вытесняя другие при превышении ограничений.
+// Вызывается под блокировкой
+func (m *MemoryCache) insertItem(item *cacheItem) {
+	// Освобождаем место до вставки, чтобы новый элемент не вытеснил сам себя
+	for m.overflow(item.size()) {
+		victim := m.policy.victim()
+		if victim == nil {
+			break
+		}
+		m.removeItem(victim)
+		m.recordEviction("size")
+	}
+
+	m.data[item.key] = item
+	m.size += item.size()
+	m.policy.add(item)
+}
+
+// SetNX атомарно сохраняет значение, только если ключа еще нет или он истек.
+// Возвращает false, если ключ уже существует
+func (m *MemoryCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if ttl == 0 {
+		ttl = m.ttl
+	}
+
+	item := &cacheItem{
+		key:       key,
+		data:      data,
+		expiresAt: time.Now().Add(ttl),
+	}
+	if m.maxBytes > 0 && item.size() > m.maxBytes {
+		return false, ErrValueTooLarge
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if existing, exists := m.data[key]; exists {
+		if time.Now().Before(existing.expiresAt) {
+			return false, nil
+		}
+		m.removeItem(existing)
+	}
+
+	m.insertItem(item)
+	return true, nil
+}
+
 // Get получает данные из memory кэша
 func (m *MemoryCache) Get(ctx context.Context, key string, dest interface{}) error {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
+	m.mutex.Lock()
 	item, exists := m.data[key]
-	if !exists || time.Now().After(item.expiresAt) {
+	if exists && time.Now().After(item.expiresAt) {
+		m.removeItem(item)
+		m.recordEviction("expired")
+		exists = false
+	}
+	if !exists {
+		m.recordMiss()
+		m.mutex.Unlock()
 		return ErrCacheMiss
 	}
 
-	data, err := json.Marshal(item.value)
-	if err != nil {
-		return err
-	}
+	m.policy.touch(item)
+	m.recordHit()
+	data := item.data
+	m.mutex.Unlock()
 
+	// Срез данных не изменяется после записи, поэтому декодируем без блокировки
 	return json.Unmarshal(data, dest)
 }
 
@@ -220,10 +637,123 @@ func (m *MemoryCache) Delete(ctx context.Context, key string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	delete(m.data, key)
+	if item, exists := m.data[key]; exists {
+		m.removeItem(item)
+	}
 	return nil
 }
 
+// Keys возвращает ключи, подходящие под glob паттерн
+func (m *MemoryCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	var result []string
+	for key, item := range m.data {
+		if now.After(item.expiresAt) {
+			continue
+		}
+		matched, err := path.Match(pattern, key)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			result = append(result, key)
+		}
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// MGet получает значения нескольких ключей. Отсутствующие ключи не попадают в результат
+func (m *MemoryCache) MGet(ctx context.Context, keys []string) (map[string]json.RawMessage, error) {
+	result := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		var raw json.RawMessage
+		if err := m.Get(ctx, key, &raw); err == nil {
+			result[key] = raw
+		}
+	}
+
+	return result, nil
+}
+
+// MSet сохраняет несколько значений
+func (m *MemoryCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	for key, value := range values {
+		if err := m.Set(ctx, key, value, ttl); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteByPattern удаляет все ключи по glob паттерну и возвращает их количество
+func (m *MemoryCache) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	deleted := 0
+	for key, item := range m.data {
+		if matched, _ := path.Match(pattern, key); matched {
+			m.removeItem(item)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// Len возвращает количество элементов и их суммарный размер в байтах
+func (m *MemoryCache) Len() (int, int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.data), m.size
+}
+
+// overflow проверяет, превысит ли кэш ограничения после добавления элемента размером size
+func (m *MemoryCache) overflow(size int64) bool {
+	return (m.maxEntries > 0 && len(m.data)+1 > m.maxEntries) ||
+		(m.maxBytes > 0 && m.size+size > m.maxBytes)
+}
+
+func (m *MemoryCache) removeItem(item *cacheItem) {
+	m.policy.remove(item)
+	delete(m.data, item.key)
+	m.size -= item.size()
+}
+
+func (m *MemoryCache) recordHit() {
+	if m.recorder != nil {
+		m.recorder.RecordCacheHit("memory")
+	}
+}
+
+func (m *MemoryCache) recordMiss() {
+	if m.recorder != nil {
+		m.recorder.RecordCacheMiss("memory")
+	}
+}
+
+func (m *MemoryCache) recordEviction(reason string) {
+	if m.recorder != nil {
+		m.recorder.RecordCacheEviction("memory", reason)
+	}
+}
+
+// size оценивает занимаемую элементом память
+func (i *cacheItem) size() int64 {
+	return int64(len(i.key) + len(i.data))
+}
+
 // cleanup очищает устаревшие элементы
 func (m *MemoryCache) cleanup() {
 	for {
@@ -231,9 +761,10 @@ func (m *MemoryCache) cleanup() {
 		case <-m.ticker.C:
 			m.mutex.Lock()
 			now := time.Now()
-			for key, item := range m.data {
+			for _, item := range m.data {
 				if now.After(item.expiresAt) {
-					delete(m.data, key)
+					m.removeItem(item)
+					m.recordEviction("expired")
 				}
 			}
 			m.mutex.Unlock()
@@ -255,57 +786,139 @@ type Cache interface {
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
 	Get(ctx context.Context, key string, dest interface{}) error
 	Delete(ctx context.Context, key string) error
+	// SetNX атомарно сохраняет значение, только если ключа еще нет
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
 	Close() error
 }
 
+// BulkCache расширяет Cache пакетными операциями и обходом ключей
+type BulkCache interface {
+	Cache
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	MGet(ctx context.Context, keys []string) (map[string]json.RawMessage, error)
+	MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error
+	DeleteByPattern(ctx context.Context, pattern string) (int, error)
+}
+
 // ErrCacheMiss возвращается когда ключ не найден в кэше
 var ErrCacheMiss = fmt.Errorf("cache miss")
 
-// NewsCacheKey генерирует ключ для кэширования новостей
-func NewsCacheKey(limit int) string {
-	return fmt.Sprintf("news:latest:%d", limit)
+// newsGenerationKey хранит текущее поколение кэша новостей. Каждая запись
+// в хранилище меняет поколение, и все ранее закэшированные выборки перестают
+// находиться, истекая затем по TTL
+const newsGenerationKey = "news:generation"
+
+// newsGenerationTTL должен быть заметно больше TTL выборок: при потере
+// ключа поколения кэш просто начинается заново
+const newsGenerationTTL = 24 * time.Hour
+
+// NewsCacheKey генерирует ключ для кэширования новостей заданного поколения
+func NewsCacheKey(generation int64, limit int) string {
+	return fmt.Sprintf("news:%d:latest:%d", generation, limit)
 }
 
-// CachedNewsRepository добавляет кэширование к репозиторию новостей
+// RepositoryConfig содержит настройки кэширования репозитория новостей
+type RepositoryConfig struct {
+	TTL time.Duration // время, в течение которого значение считается свежим
+	// StaleTTL - сколько еще после истечения TTL значение может отдаваться,
+	// пока оно обновляется в фоне (stale-while-revalidate). 0 - режим выключен
+	StaleTTL time.Duration
+}
+
+// cachedNews хранит выборку вместе с моментом, после которого она устаревает
+type cachedNews struct {
+	News       domain.NewsList `json:"news"`
+	FreshUntil time.Time       `json:"fresh_until"`
+}
+
+// CachedNewsRepository добавляет кэширование к репозиторию новостей.
+// Одновременные промахи по одному ключу объединяются в один запрос к хранилищу
 type CachedNewsRepository struct {
-	cache Cache
-	repo  domain.NewsRepository
-	ttl   time.Duration
+	cache    Cache
+	repo     domain.NewsRepository
+	ttl      time.Duration
+	staleTTL time.Duration
+	loads    singleflight.Group
 }
 
 // NewCachedNewsRepository создает репозиторий с кэшированием
-func NewCachedNewsRepository(cache Cache, repo domain.NewsRepository, ttl time.Duration) *CachedNewsRepository {
+func NewCachedNewsRepository(cache Cache, repo domain.NewsRepository, config RepositoryConfig) *CachedNewsRepository {
+	if config.TTL == 0 {
+		config.TTL = 5 * time.Minute
+	}
+	if config.StaleTTL < 0 {
+		config.StaleTTL = 0
+	}
+
 	return &CachedNewsRepository{
-		cache: cache,
-		repo:  repo,
-		ttl:   ttl,
+		cache:    cache,
+		repo:     repo,
+		ttl:      config.TTL,
+		staleTTL: config.StaleTTL,
 	}
 }
 
 // GetLatestNews получает новости с кэшированием
 func (c *CachedNewsRepository) GetLatestNews(limit int) (domain.NewsList, error) {
-	ctx := context.Background()
-	key := NewsCacheKey(limit)
+	key := NewsCacheKey(c.generation(), limit)
 
 	// Пытаемся получить из кэша
-	var cachedNews domain.NewsList
-	if err := c.cache.Get(ctx, key, &cachedNews); err == nil {
-		return cachedNews, nil
+	var cached cachedNews
+	if err := c.cache.Get(context.Background(), key, &cached); err == nil {
+		if time.Now().Before(cached.FreshUntil) {
+			return cached.News, nil
+		}
+
+		// Значение устарело: отдаем его сразу, а обновляем в фоне
+		if c.staleTTL > 0 {
+			go func() {
+				if _, err := c.load(key, limit); err != nil {
+					fmt.Printf("Failed to refresh cached news: %v\n", err)
+				}
+			}()
+			return cached.News, nil
+		}
 	}
 
 	// Если в кэше нет, получаем из репозитория
-	news, err := c.repo.GetLatestNews(limit)
+	return c.load(key, limit)
+}
+
+// load читает новости из репозитория и кэширует их. Параллельные вызовы
+// для одного ключа ждут результата первого
+func (c *CachedNewsRepository) load(key string, limit int) (domain.NewsList, error) {
+	result, err, _ := c.loads.Do(key, func() (interface{}, error) {
+		news, err := c.repo.GetLatestNews(limit)
+		if err != nil {
+			return nil, err
+		}
+
+		// Устаревшее значение хранится в кэше еще staleTTL после истечения свежести
+		entry := cachedNews{News: news, FreshUntil: time.Now().Add(c.ttl)}
+		if err = c.cache.Set(context.Background(), key, entry, c.ttl+c.staleTTL); err != nil {
+			// Логируем ошибку, но не возвращаем её
+			fmt.Printf("Failed to cache news: %v\n", err)
+		}
+
+		return news, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Сохраняем в кэш
-	if err = c.cache.Set(ctx, key, news, c.ttl); err != nil {
-		// Логируем ошибку, но не возвращаем её
-		fmt.Printf("Failed to cache news: %v\n", err)
+	return result.(domain.NewsList), nil
+}
+
+// QueryNews выполняет выборку напрямую в хранилище, минуя кэш. Для хранилищ
+// без поддержки запросов возвращает errors.ErrUnsupported: вызывающий код
+// читает их целиком один раз, а не на каждой странице
+func (c *CachedNewsRepository) QueryNews(filter domain.NewsFilter) (domain.NewsList, int, error) {
+	querier, ok := c.repo.(domain.NewsQuerier)
+	if !ok {
+		return nil, 0, fmt.Errorf("underlying repository does not support queries: %w", errors.ErrUnsupported)
 	}
 
-	return news, nil
+	return querier.QueryNews(filter)
 }
 
 // SaveNews сохраняет новости и инвалидирует кэш
@@ -317,20 +930,79 @@ func (c *CachedNewsRepository) SaveNews(news domain.NewsList) error {
 		return err
 	}
 
-	// Инвалидируем кэш
-	keys := []string{
-		NewsCacheKey(10),
-		NewsCacheKey(50),
-		NewsCacheKey(100),
-		NewsCacheKey(500),
-		NewsCacheKey(1000),
+	c.invalidate(ctx)
+
+	return nil
+}
+
+// UpsertNews добавляет новости в хранилище без удаления остальных и инвалидирует кэш
+func (c *CachedNewsRepository) UpsertNews(news domain.NewsList) error {
+	upserter, ok := c.repo.(domain.NewsUpserter)
+	if !ok {
+		return fmt.Errorf("underlying repository does not support upserts: %w", errors.ErrUnsupported)
 	}
 
-	for _, key := range keys {
-		if err := c.cache.Delete(ctx, key); err != nil {
-			fmt.Printf("Failed to invalidate cache key %s: %v\n", key, err)
-		}
+	if err := upserter.UpsertNews(news); err != nil {
+		return err
+	}
+
+	c.invalidate(context.Background())
+
+	return nil
+}
+
+// DeleteNews удаляет новости из хранилища и инвалидирует кэш
+func (c *CachedNewsRepository) DeleteNews(ids []string) error {
+	deleter, ok := c.repo.(domain.NewsDeleter)
+	if !ok {
+		return fmt.Errorf("underlying repository does not support deletes: %w", errors.ErrUnsupported)
 	}
 
+	if err := deleter.DeleteNews(ids); err != nil {
+		return err
+	}
+
+	c.invalidate(context.Background())
+
 	return nil
 }
+
+// DeleteNewsBefore удаляет из хранилища новости старше t и инвалидирует кэш
+func (c *CachedNewsRepository) DeleteNewsBefore(t time.Time) (int, error) {
+	pruner, ok := c.repo.(domain.NewsPruner)
+	if !ok {
+		return 0, fmt.Errorf("underlying repository does not support pruning: %w", errors.ErrUnsupported)
+	}
+
+	deleted, err := pruner.DeleteNewsBefore(t)
+	if err != nil {
+		return deleted, err
+	}
+
+	if deleted > 0 {
+		c.invalidate(context.Background())
+	}
+
+	return deleted, nil
+}
+
+// generation возвращает текущее поколение кэша, создавая его при отсутствии
+func (c *CachedNewsRepository) generation() int64 {
+	var generation int64
+	if err := c.cache.Get(context.Background(), newsGenerationKey, &generation); err == nil {
+		return generation
+	}
+
+	return c.invalidate(context.Background())
+}
+
+// invalidate начинает новое поколение кэша, делая недоступными все закэшированные
+// выборки, включая результаты загрузок, завершившихся после записи
+func (c *CachedNewsRepository) invalidate(ctx context.Context) int64 {
+	generation := time.Now().UnixNano()
+	if err := c.cache.Set(ctx, newsGenerationKey, generation, newsGenerationTTL); err != nil {
+		fmt.Printf("Failed to update cache generation: %v\n", err)
+	}
+
+	return generation
+}
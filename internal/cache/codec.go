@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Форматы сериализации значений кэша
+const (
+	CodecJSON    = "json"
+	CodecMsgPack = "msgpack"
+	CodecGob     = "gob"
+)
+
+// Алгоритмы сжатия значений кэша
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// valueTag открывает заголовок закодированного значения. Нулевой байт не
+// может начинать JSON документ, поэтому значения без заголовка читаются
+// как JSON, записанный до появления кодеков
+const valueTag = 0x00
+
+// Идентификаторы в заголовке значения. Менять нельзя: по ним читаются
+// значения, записанные другими репликами и предыдущими версиями
+const (
+	codecIDJSON    byte = 1
+	codecIDMsgPack byte = 2
+	codecIDGob     byte = 3
+
+	compressionIDNone byte = 0
+	compressionIDGzip byte = 1
+	compressionIDZstd byte = 2
+)
+
+// Codec сериализует значения кэша
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// msgpackCodec использует json теги, чтобы имена полей совпадали с JSON
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+var codecs = map[byte]Codec{
+	codecIDJSON:    jsonCodec{},
+	codecIDMsgPack: msgpackCodec{},
+	codecIDGob:     gobCodec{},
+}
+
+// SerializationConfig задает формат хранения значений в Redis
+type SerializationConfig struct {
+	Codec       string `yaml:"codec" json:"codec"`             // "json", "msgpack" или "gob"
+	Compression string `yaml:"compression" json:"compression"` // "none", "gzip" или "zstd"
+	// CompressionThreshold - минимальный размер значения в байтах, начиная с которого оно сжимается
+	CompressionThreshold int `yaml:"compression_threshold" json:"compression_threshold"`
+}
+
+// valueCodec кодирует значения с заголовком [tag][codec][compression].
+// Декодирование определяет формат по заголовку, поэтому реплики с разными
+// настройками читают значения друг друга
+type valueCodec struct {
+	codecID       byte
+	compressionID byte
+	threshold     int
+	zstdEncoder   *zstd.Encoder
+	zstdDecoder   *zstd.Decoder
+}
+
+// newValueCodec создает кодек по настройкам
+func newValueCodec(config SerializationConfig) (*valueCodec, error) {
+	c := &valueCodec{threshold: config.CompressionThreshold}
+	if c.threshold <= 0 {
+		c.threshold = 1024
+	}
+
+	switch config.Codec {
+	case CodecJSON, "":
+		c.codecID = codecIDJSON
+	case CodecMsgPack:
+		c.codecID = codecIDMsgPack
+	case CodecGob:
+		c.codecID = codecIDGob
+	default:
+		return nil, fmt.Errorf("unsupported cache codec: %s", config.Codec)
+	}
+
+	switch config.Compression {
+	case CompressionNone, "":
+		c.compressionID = compressionIDNone
+	case CompressionGzip:
+		c.compressionID = compressionIDGzip
+	case CompressionZstd:
+		c.compressionID = compressionIDZstd
+	default:
+		return nil, fmt.Errorf("unsupported cache compression: %s", config.Compression)
+	}
+
+	var err error
+	// Декодер zstd нужен всегда: значение могла сжать другая реплика
+	if c.zstdDecoder, err = zstd.NewReader(nil); err != nil {
+		return nil, err
+	}
+	if c.compressionID == compressionIDZstd {
+		if c.zstdEncoder, err = zstd.NewWriter(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// encode сериализует значение и сжимает его, если оно больше порога
+func (c *valueCodec) encode(v interface{}) ([]byte, error) {
+	payload, err := codecs[c.codecID].Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	compressionID := compressionIDNone
+	if c.compressionID != compressionIDNone && len(payload) >= c.threshold {
+		compressionID = c.compressionID
+		if payload, err = c.compress(payload); err != nil {
+			return nil, err
+		}
+	}
+
+	data := make([]byte, 0, len(payload)+3)
+	data = append(data, valueTag, c.codecID, compressionID)
+	return append(data, payload...), nil
+}
+
+// decode разбирает значение любого поддерживаемого формата
+func (c *valueCodec) decode(data []byte, v interface{}) error {
+	if len(data) == 0 || data[0] != valueTag {
+		return json.Unmarshal(data, v)
+	}
+	if len(data) < 3 {
+		return fmt.Errorf("cache value header is truncated")
+	}
+
+	codec, ok := codecs[data[1]]
+	if !ok {
+		return fmt.Errorf("unknown cache codec id %d", data[1])
+	}
+
+	payload, err := c.decompress(data[2], data[3:])
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(payload, v)
+}
+
+// toJSON перекодирует значение в JSON для просмотра. Значения gob
+// не описывают свой тип и не могут быть декодированы без него
+func (c *valueCodec) toJSON(data []byte) (json.RawMessage, error) {
+	if len(data) == 0 || data[0] != valueTag {
+		return data, nil
+	}
+	if len(data) >= 2 && data[1] == codecIDGob {
+		return json.Marshal(fmt.Sprintf("<gob value, %d bytes>", len(data)))
+	}
+
+	var value interface{}
+	if err := c.decode(data, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func (c *valueCodec) compress(payload []byte) ([]byte, error) {
+	switch c.compressionID {
+	case compressionIDGzip:
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case compressionIDZstd:
+		return c.zstdEncoder.EncodeAll(payload, nil), nil
+	default:
+		return payload, nil
+	}
+}
+
+func (c *valueCodec) decompress(compressionID byte, payload []byte) ([]byte, error) {
+	switch compressionID {
+	case compressionIDNone:
+		return payload, nil
+	case compressionIDGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return io.ReadAll(gz)
+	case compressionIDZstd:
+		return c.zstdDecoder.DecodeAll(payload, nil)
+	default:
+		return nil, fmt.Errorf("unknown cache compression id %d", compressionID)
+	}
+}
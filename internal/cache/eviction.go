@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
+
+// Политики вытеснения MemoryCache
+const (
+	EvictionLRU = "lru" // вытесняется элемент, к которому дольше всего не обращались
+	EvictionLFU = "lfu" // вытесняется элемент с наименьшим числом обращений
+)
+
+// evictionPolicy определяет порядок вытеснения элементов при переполнении.
+// Методы вызываются под блокировкой MemoryCache
+type evictionPolicy interface {
+	add(item *cacheItem)
+	touch(item *cacheItem)
+	remove(item *cacheItem)
+	victim() *cacheItem
+}
+
+// newEvictionPolicy создает политику по имени
+func newEvictionPolicy(name string) (evictionPolicy, error) {
+	switch strings.ToLower(name) {
+	case EvictionLRU, "":
+		return &lruPolicy{order: list.New()}, nil
+	case EvictionLFU:
+		return &lfuPolicy{buckets: make(map[int]*list.List)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported eviction policy: %s", name)
+	}
+}
+
+// lruPolicy хранит элементы в порядке последнего обращения
+type lruPolicy struct {
+	order *list.List
+}
+
+func (p *lruPolicy) add(item *cacheItem) {
+	item.element = p.order.PushFront(item)
+}
+
+func (p *lruPolicy) touch(item *cacheItem) {
+	p.order.MoveToFront(item.element)
+}
+
+func (p *lruPolicy) remove(item *cacheItem) {
+	p.order.Remove(item.element)
+}
+
+func (p *lruPolicy) victim() *cacheItem {
+	if back := p.order.Back(); back != nil {
+		return back.Value.(*cacheItem)
+	}
+	return nil
+}
+
+// lfuPolicy группирует элементы по числу обращений. Внутри группы
+// вытесняется элемент, к которому дольше всего не обращались
+type lfuPolicy struct {
+	buckets map[int]*list.List
+	minFreq int
+}
+
+func (p *lfuPolicy) add(item *cacheItem) {
+	item.freq = 1
+	p.push(item)
+	p.minFreq = 1
+}
+
+func (p *lfuPolicy) touch(item *cacheItem) {
+	p.remove(item)
+	item.freq++
+	p.push(item)
+	if _, ok := p.buckets[p.minFreq]; !ok && p.minFreq == item.freq-1 {
+		p.minFreq = item.freq
+	}
+}
+
+func (p *lfuPolicy) remove(item *cacheItem) {
+	bucket := p.buckets[item.freq]
+	bucket.Remove(item.element)
+	if bucket.Len() == 0 {
+		delete(p.buckets, item.freq)
+	}
+}
+
+func (p *lfuPolicy) victim() *cacheItem {
+	bucket, ok := p.buckets[p.minFreq]
+	if !ok {
+		// Минимальная группа опустела после удаления - ищем следующую
+		p.minFreq = 0
+		for freq, b := range p.buckets {
+			if p.minFreq == 0 || freq < p.minFreq {
+				p.minFreq, bucket = freq, b
+			}
+		}
+		if bucket == nil {
+			return nil
+		}
+	}
+	return bucket.Back().Value.(*cacheItem)
+}
+
+func (p *lfuPolicy) push(item *cacheItem) {
+	bucket, ok := p.buckets[item.freq]
+	if !ok {
+		bucket = list.New()
+		p.buckets[item.freq] = bucket
+	}
+	item.element = bucket.PushFront(item)
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TieredConfig содержит настройки двухуровневого кэша
+type TieredConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// L1TTL ограничивает время жизни копии в памяти реплики. Оно же задает
+	// максимальную задержку инвалидации, если сообщение pub/sub было потеряно
+	L1TTL   time.Duration `yaml:"l1_ttl" json:"l1_ttl"`
+	Channel string        `yaml:"channel" json:"channel"`
+}
+
+// invalidationMessage рассылается репликам при изменении ключа
+type invalidationMessage struct {
+	Origin  string `json:"origin"`
+	Key     string `json:"key,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// TieredCache реализует двухуровневый кэш: локальный MemoryCache (L1)
+// перед общим RedisCache (L2). Записи и удаления рассылаются через Redis
+// pub/sub, и каждая реплика удаляет у себя устаревшую копию из L1
+type TieredCache struct {
+	l1       *MemoryCache
+	l2       *RedisCache
+	l1TTL    time.Duration
+	channel  string
+	instance string
+	pubsub   *redis.PubSub
+	wg       sync.WaitGroup
+
+	// generation увеличивается при каждом изменении L1 помимо Get. Get
+	// сохраняет прочитанное из L2 значение, только если за время чтения
+	// не было инвалидаций, иначе оно может оказаться уже устаревшим
+	generation atomic.Uint64
+	localMu    sync.Mutex
+}
+
+// NewTieredCache создает двухуровневый кэш и подписывается на канал инвалидации
+func NewTieredCache(l1 *MemoryCache, l2 *RedisCache, config TieredConfig) (*TieredCache, error) {
+	if config.L1TTL == 0 {
+		config.L1TTL = 30 * time.Second
+	}
+	if config.Channel == "" {
+		config.Channel = l2.prefix + "invalidate"
+	}
+
+	instance := make([]byte, 8)
+	if _, err := rand.Read(instance); err != nil {
+		return nil, fmt.Errorf("failed to generate instance id: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pubsub := l2.client.Subscribe(ctx, config.Channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to invalidation channel: %w", err)
+	}
+
+	t := &TieredCache{
+		l1:       l1,
+		l2:       l2,
+		l1TTL:    config.L1TTL,
+		channel:  config.Channel,
+		instance: hex.EncodeToString(instance),
+		pubsub:   pubsub,
+	}
+
+	t.wg.Add(1)
+	go t.listen()
+
+	return t, nil
+}
+
+// Set сохраняет данные в оба уровня и оповещает остальные реплики
+func (t *TieredCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := t.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	t.invalidateLocal(func() { t.setLocal(ctx, key, value, ttl) })
+	t.publish(ctx, invalidationMessage{Key: key})
+
+	return nil
+}
+
+// SetNX атомарно сохраняет значение в L2, только если ключа еще нет.
+// Решение принимает L2, общий для всех реплик
+func (t *TieredCache) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	stored, err := t.l2.SetNX(ctx, key, value, ttl)
+	if err != nil || !stored {
+		return stored, err
+	}
+
+	t.invalidateLocal(func() { t.setLocal(ctx, key, value, ttl) })
+	t.publish(ctx, invalidationMessage{Key: key})
+
+	return true, nil
+}
+
+// Get получает данные из L1, а при промахе - из L2 с сохранением копии в L1
+func (t *TieredCache) Get(ctx context.Context, key string, dest interface{}) error {
+	if err := t.l1.Get(ctx, key, dest); err == nil {
+		return nil
+	}
+
+	generation := t.generation.Load()
+	if err := t.l2.Get(ctx, key, dest); err != nil {
+		return err
+	}
+
+	// Копия в L1 не должна пережить значение в L2
+	ttl, err := t.l2.GetTTL(ctx, key)
+	if err != nil || ttl <= 0 {
+		ttl = t.l1TTL
+	}
+
+	// Инвалидация, пришедшая во время чтения из L2, могла удалить из L1 уже
+	// новое значение. Прочитанное остается ответом, но в L1 не попадает
+	t.localMu.Lock()
+	if t.generation.Load() == generation {
+		t.setLocal(ctx, key, dest, ttl)
+	}
+	t.localMu.Unlock()
+
+	return nil
+}
+
+// Delete удаляет данные с обоих уровней и оповещает остальные реплики
+func (t *TieredCache) Delete(ctx context.Context, key string) error {
+	if err := t.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	t.invalidateLocal(func() { t.l1.Delete(ctx, key) })
+	t.publish(ctx, invalidationMessage{Key: key})
+
+	return nil
+}
+
+// Keys получает ключи по паттерну из L2
+func (t *TieredCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return t.l2.Keys(ctx, pattern)
+}
+
+// MGet получает значения нескольких ключей из L2
+func (t *TieredCache) MGet(ctx context.Context, keys []string) (map[string]json.RawMessage, error) {
+	return t.l2.MGet(ctx, keys)
+}
+
+// MSet сохраняет несколько значений в L2 и сбрасывает их копии на всех репликах
+func (t *TieredCache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if err := t.l2.MSet(ctx, values, ttl); err != nil {
+		return err
+	}
+
+	for key := range values {
+		t.invalidateLocal(func() { t.l1.Delete(ctx, key) })
+		t.publish(ctx, invalidationMessage{Key: key})
+	}
+
+	return nil
+}
+
+// DeleteByPattern удаляет ключи по паттерну с обоих уровней на всех репликах
+func (t *TieredCache) DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	deleted, err := t.l2.DeleteByPattern(ctx, pattern)
+	if err != nil {
+		return deleted, err
+	}
+
+	t.invalidateLocal(func() { t.l1.DeleteByPattern(ctx, pattern) })
+	t.publish(ctx, invalidationMessage{Pattern: pattern})
+
+	return deleted, nil
+}
+
+// Ping проверяет соединение с Redis
+func (t *TieredCache) Ping(ctx context.Context) error {
+	return t.l2.Ping(ctx)
+}
+
+// Close отписывается от канала инвалидации и закрывает оба уровня
+func (t *TieredCache) Close() error {
+	err := t.pubsub.Close()
+	t.wg.Wait()
+
+	t.l1.Close()
+	if closeErr := t.l2.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// setLocal сохраняет копию в L1 на время не больше l1TTL
+func (t *TieredCache) setLocal(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	if ttl == 0 || ttl > t.l1TTL {
+		ttl = t.l1TTL
+	}
+
+	if err := t.l1.Set(ctx, key, value, ttl); err != nil {
+		// Значение остается доступным через L2
+		t.l1.Delete(ctx, key)
+	}
+}
+
+// invalidateLocal изменяет L1 и увеличивает generation, чтобы параллельный
+// Get не записал поверх изменения значение, прочитанное из L2 до него
+func (t *TieredCache) invalidateLocal(change func()) {
+	t.localMu.Lock()
+	defer t.localMu.Unlock()
+
+	t.generation.Add(1)
+	change()
+}
+
+// publish рассылает инвалидацию ключа или паттерна остальным репликам
+func (t *TieredCache) publish(ctx context.Context, message invalidationMessage) {
+	message.Origin = t.instance
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	if err = t.l2.client.Publish(ctx, t.channel, data).Err(); err != nil {
+		// Копии на других репликах истекут не позже чем через l1TTL
+		fmt.Printf("Failed to publish cache invalidation: %v\n", err)
+	}
+}
+
+// listen удаляет из L1 ключи, измененные другими репликами
+func (t *TieredCache) listen() {
+	defer t.wg.Done()
+
+	for msg := range t.pubsub.Channel() {
+		var message invalidationMessage
+		if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+			continue
+		}
+		if message.Origin == t.instance {
+			continue
+		}
+
+		t.invalidateLocal(func() {
+			if message.Pattern != "" {
+				t.l1.DeleteByPattern(context.Background(), message.Pattern)
+			} else {
+				t.l1.Delete(context.Background(), message.Key)
+			}
+		})
+	}
+}
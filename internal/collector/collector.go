@@ -128,6 +128,7 @@ func (c *Collector) CollectFromSource(source domain.Source) (domain.NewsList, er
 			Description: article.Description,
 			URL:         article.URL,
 			Source:      source.Name,
+			Category:    source.Category,
 			PublishedAt: publishedAt,
 		})
 	}
@@ -6,27 +6,36 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/pah-an/infohub/internal/aggregator"
+	"github.com/pah-an/infohub/internal/audit"
 	"github.com/pah-an/infohub/internal/auth"
 	"github.com/pah-an/infohub/internal/cache"
 	"github.com/pah-an/infohub/internal/domain"
 	"github.com/pah-an/infohub/internal/logger"
+	"github.com/pah-an/infohub/internal/storage"
+	"github.com/pah-an/infohub/internal/usage"
 )
 
 // Config представляет конфигурацию приложения
 type Config struct {
-	Server       ServerConfig     `yaml:"server"`
-	Sources      []domain.Source  `yaml:"sources"`
-	Interval     time.Duration    `yaml:"interval"`
-	Cache        CacheConfig      `yaml:"cache"`
-	Redis        cache.Config     `yaml:"redis"`
-	Auth         auth.Config      `yaml:"auth"`
-	RateLimiting RateLimitConfig  `yaml:"rate_limiting"`
-	Logging      logger.Config    `yaml:"logging"`
-	Monitoring   MonitoringConfig `yaml:"monitoring"`
-	Health       HealthConfig     `yaml:"health"`
-	CORS         CORSConfig       `yaml:"cors"`
-	Security     SecurityConfig   `yaml:"security"`
-	Profiling    ProfilingConfig  `yaml:"profiling"`
+	Server       ServerConfig               `yaml:"server"`
+	Sources      []domain.Source            `yaml:"sources"`
+	Interval     time.Duration              `yaml:"interval"`
+	Cache        CacheConfig                `yaml:"cache"`
+	Retention    aggregator.RetentionConfig `yaml:"retention"`
+	Redis        cache.Config               `yaml:"redis"`
+	MemoryCache  cache.MemoryConfig         `yaml:"memory_cache"`
+	Auth         auth.Config                `yaml:"auth"`
+	RateLimiting RateLimitConfig            `yaml:"rate_limiting"`
+	Logging      logger.Config              `yaml:"logging"`
+	Monitoring   MonitoringConfig           `yaml:"monitoring"`
+	Health       HealthConfig               `yaml:"health"`
+	CORS         CORSConfig                 `yaml:"cors"`
+	Security     SecurityConfig             `yaml:"security"`
+	HTTPCache    ResponseCacheConfig        `yaml:"http_cache"`
+	Profiling    ProfilingConfig            `yaml:"profiling"`
+	Usage        usage.Config               `yaml:"usage"`
+	Audit        audit.Config               `yaml:"audit"`
 }
 
 // ServerConfig содержит настройки HTTP сервера
@@ -36,11 +45,39 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	TLS          TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig содержит настройки HTTPS и проверки клиентских сертификатов
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile - CA bundle для проверки клиентских сертификатов (mTLS)
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth - "optional" (сертификат проверяется, если предъявлен) или "require"
+	ClientAuth string `yaml:"client_auth"`
+	// ReloadInterval - как часто проверять изменение файлов сертификатов
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+	MinVersion     string        `yaml:"min_version"` // "1.2" или "1.3"
 }
 
 // CacheConfig содержит настройки кэширования
 type CacheConfig struct {
-	FilePath string `yaml:"file_path"`
+	Backend     string            `yaml:"backend"` // "file", "wal" или "sqlite"
+	FilePath    string            `yaml:"file_path"`
+	Backups     int               `yaml:"backups"`     // резервные копии файла; отрицательное значение отключает
+	Compression string            `yaml:"compression"` // сжатие файла: "none", "gzip" или "zstd"
+	WAL         storage.WALConfig `yaml:"wal"`
+	SQL         storage.SQLConfig `yaml:"sql"`
+}
+
+// ResponseCacheConfig содержит настройки HTTP кэширования ответов
+type ResponseCacheConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	MaxAge      time.Duration `yaml:"max_age"`      // Cache-Control max-age
+	CacheBodies bool          `yaml:"cache_bodies"` // хранить закодированные ответы в кэше
+	BodyTTL     time.Duration `yaml:"body_ttl"`
 }
 
 // RateLimitConfig содержит настройки rate limiting
@@ -82,6 +119,9 @@ type CORSConfig struct {
 type SecurityConfig struct {
 	EnableSecurityHeaders bool   `yaml:"enable_security_headers"`
 	ContentSecurityPolicy string `yaml:"content_security_policy"`
+	// TrustedProxies - адреса и подсети обратных прокси, от которых лентам
+	// принимается заголовок X-Forwarded-Proto
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 // ProfilingConfig содержит настройки профилирования
@@ -132,6 +172,15 @@ func setDefaults(config *Config) {
 	if config.Server.IdleTimeout == 0 {
 		config.Server.IdleTimeout = 60 * time.Second
 	}
+	if config.Server.TLS.ClientAuth == "" {
+		config.Server.TLS.ClientAuth = "optional"
+	}
+	if config.Server.TLS.ReloadInterval == 0 {
+		config.Server.TLS.ReloadInterval = 30 * time.Second
+	}
+	if config.Server.TLS.MinVersion == "" {
+		config.Server.TLS.MinVersion = "1.2"
+	}
 
 	// General defaults
 	if config.Interval == 0 {
@@ -139,11 +188,34 @@ func setDefaults(config *Config) {
 	}
 
 	// Cache defaults
+	if config.Cache.Backend == "" {
+		config.Cache.Backend = "file"
+	}
 	if config.Cache.FilePath == "" {
 		config.Cache.FilePath = "news_cache.json"
 	}
+	if config.Cache.Backups == 0 {
+		config.Cache.Backups = 3
+	}
+	if config.Cache.WAL.Dir == "" {
+		config.Cache.WAL.Dir = "data/wal"
+	}
+	if config.Cache.WAL.SyncPolicy == "" {
+		config.Cache.WAL.SyncPolicy = storage.SyncAlways
+	}
+	if config.Cache.SQL.Path == "" {
+		config.Cache.SQL.Path = "data/infohub.db"
+	}
+
+	// Retention defaults
+	if config.Retention.MaxItems == 0 {
+		config.Retention.MaxItems = 1000
+	}
 
 	// Redis defaults
+	if config.Redis.Mode == "" {
+		config.Redis.Mode = cache.RedisModeSingle
+	}
 	if config.Redis.Address == "" {
 		config.Redis.Address = "localhost:6379"
 	}
@@ -154,9 +226,29 @@ func setDefaults(config *Config) {
 		config.Redis.Prefix = "infohub:"
 	}
 
+	// Memory cache defaults
+	if config.MemoryCache.TTL == 0 {
+		config.MemoryCache.TTL = 5 * time.Minute
+	}
+	if config.MemoryCache.CleanupInterval == 0 {
+		config.MemoryCache.CleanupInterval = 10 * time.Minute
+	}
+	if config.MemoryCache.Eviction == "" {
+		config.MemoryCache.Eviction = cache.EvictionLRU
+	}
+
 	// Auth defaults
 	if config.Auth.JWTTTL == 0 {
-		config.Auth.JWTTTL = 24 * time.Hour
+		config.Auth.JWTTTL = 15 * time.Minute
+	}
+	if config.Auth.RefreshTTL == 0 {
+		config.Auth.RefreshTTL = 7 * 24 * time.Hour
+	}
+	if config.Auth.Signing.Algorithm == "" {
+		config.Auth.Signing.Algorithm = auth.AlgorithmHS256
+	}
+	if len(config.Auth.DefaultScopes) == 0 {
+		config.Auth.DefaultScopes = []string{auth.ScopeNewsRead}
 	}
 	if config.Auth.PublicPaths == nil {
 		config.Auth.PublicPaths = []string{
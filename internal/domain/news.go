@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"sort"
+	"strings"
+	"time"
+)
 
 // News представляет новость из любого источника
 type News struct {
@@ -9,6 +13,7 @@ type News struct {
 	Description string    `json:"description" example:"This breakthrough announcement changes the landscape..."`
 	URL         string    `json:"url" example:"https://example.com/news/go-release"`
 	Source      string    `json:"source" example:"Tech News"`
+	Category    string    `json:"category,omitempty" example:"technology"`
 	PublishedAt time.Time `json:"published_at" example:"2024-01-01T12:00:00Z"`
 }
 
@@ -17,16 +22,85 @@ type NewsList []News
 
 // SortByDate сортирует новости по дате (по убыванию)
 func (nl NewsList) SortByDate() NewsList {
-	// Простая сортировка пузырьком для демонстрации
-	// В продакшене лучше использовать sort.Slice
-	for i := 0; i < len(nl)-1; i++ {
-		for j := 0; j < len(nl)-i-1; j++ {
-			if nl[j].PublishedAt.Before(nl[j+1].PublishedAt) {
-				nl[j], nl[j+1] = nl[j+1], nl[j]
+	sort.SliceStable(nl, func(i, j int) bool {
+		return nl[i].PublishedAt.After(nl[j].PublishedAt)
+	})
+	return nl
+}
+
+// FilterBySource оставляет только новости указанного источника
+func (nl NewsList) FilterBySource(source string) NewsList {
+	filtered := make(NewsList, 0, len(nl))
+	for _, news := range nl {
+		if strings.EqualFold(news.Source, source) {
+			filtered = append(filtered, news)
+		}
+	}
+	return filtered
+}
+
+// FilterByCategory оставляет только новости указанной категории
+func (nl NewsList) FilterByCategory(category string) NewsList {
+	filtered := make(NewsList, 0, len(nl))
+	for _, news := range nl {
+		if strings.EqualFold(news.Category, category) {
+			filtered = append(filtered, news)
+		}
+	}
+	return filtered
+}
+
+// Query применяет фильтр к списку, отсортированному по дате, и возвращает
+// страницу результата и общее количество совпадений
+func (nl NewsList) Query(filter NewsFilter) (NewsList, int) {
+	matched := make(NewsList, 0, len(nl))
+	for _, news := range nl {
+		if filter.Source != "" && !strings.EqualFold(news.Source, filter.Source) {
+			continue
+		}
+		if filter.Category != "" && !strings.EqualFold(news.Category, filter.Category) {
+			continue
+		}
+		if filter.URL != "" && news.URL != filter.URL {
+			continue
+		}
+		if !filter.From.IsZero() && news.PublishedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && news.PublishedAt.After(filter.To) {
+			continue
+		}
+		matched = append(matched, news)
+	}
+
+	total := len(matched)
+	if filter.Offset >= total {
+		return NewsList{}, total
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 {
+		matched = matched.LimitTo(filter.Limit)
+	}
+	return matched, total
+}
+
+// Merge объединяет списки по ID (приоритет у incoming) и сортирует результат по дате
+func (nl NewsList) Merge(incoming NewsList) NewsList {
+	index := make(map[string]int, len(nl)+len(incoming))
+	merged := make(NewsList, 0, len(nl)+len(incoming))
+
+	for _, list := range []NewsList{nl, incoming} {
+		for _, news := range list {
+			if i, ok := index[news.ID]; ok {
+				merged[i] = news
+				continue
 			}
+			index[news.ID] = len(merged)
+			merged = append(merged, news)
 		}
 	}
-	return nl
+
+	return merged.SortByDate()
 }
 
 // LimitTo ограничивает количество новостей
@@ -6,6 +6,7 @@ import "time"
 type Source struct {
 	Name     string        `yaml:"name" json:"name"`
 	URL      string        `yaml:"url" json:"url"`
+	Category string        `yaml:"category" json:"category"`
 	Interval time.Duration `yaml:"interval" json:"interval"`
 }
 
@@ -20,6 +21,43 @@ type NewsRepository interface {
 	GetLatestNews(limit int) (NewsList, error)
 }
 
+// NewsUpserter реализуется хранилищами, которые умеют добавлять и обновлять
+// новости, не удаляя отсутствующие в списке. Используется архивным режимом хранения
+type NewsUpserter interface {
+	UpsertNews(news NewsList) error
+}
+
+// NewsDeleter реализуется хранилищами, которые умеют удалять отдельные новости.
+// Используется агрегатором без архива, чтобы удалять вытесненные из окна
+// новости, не затрагивая записи за его пределами
+type NewsDeleter interface {
+	DeleteNews(ids []string) error
+}
+
+// NewsPruner реализуется хранилищами, которые умеют удалять новости старше
+// заданного времени. Используется ограничением возраста без архива
+type NewsPruner interface {
+	DeleteNewsBefore(t time.Time) (int, error)
+}
+
+// NewsFilter описывает условия выборки новостей из хранилища.
+// Пустые поля не ограничивают выборку, Limit <= 0 означает отсутствие лимита
+type NewsFilter struct {
+	Source   string
+	Category string
+	URL      string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Offset   int
+}
+
+// NewsQuerier реализуется хранилищами, поддерживающими фильтрацию и пагинацию.
+// Возвращает страницу новостей и общее количество совпадений
+type NewsQuerier interface {
+	QueryNews(filter NewsFilter) (NewsList, int, error)
+}
+
 // NewsCollector определяет интерфейс для сбора новостей
 type NewsCollector interface {
 	CollectFromSource(source Source) (NewsList, error)
@@ -0,0 +1,312 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pah-an/infohub/internal/domain"
+)
+
+// Format представляет формат публикуемой ленты
+type Format string
+
+const (
+	FormatRSS      Format = "rss"
+	FormatAtom     Format = "atom"
+	FormatJSONFeed Format = "jsonfeed"
+)
+
+// Meta содержит метаданные ленты
+type Meta struct {
+	Title       string
+	Description string
+	Link        string // адрес сайта (HTML представление)
+	FeedURL     string // адрес самой ленты
+	Updated     time.Time
+}
+
+// ParseFormat разбирает формат ленты из строки
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatRSS, FormatAtom, FormatJSONFeed:
+		return Format(s), nil
+	default:
+		return "", fmt.Errorf("unsupported feed format: %s", s)
+	}
+}
+
+// ContentType возвращает MIME тип для формата
+func (f Format) ContentType() string {
+	switch f {
+	case FormatRSS:
+		return "application/rss+xml; charset=utf-8"
+	case FormatAtom:
+		return "application/atom+xml; charset=utf-8"
+	case FormatJSONFeed:
+		return "application/feed+json; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Render записывает новости в w в указанном формате
+func Render(w io.Writer, format Format, meta Meta, news domain.NewsList) error {
+	if meta.Updated.IsZero() {
+		meta.Updated = latestPublished(news)
+	}
+
+	switch format {
+	case FormatRSS:
+		return renderXML(w, buildRSS(meta, news))
+	case FormatAtom:
+		return renderXML(w, buildAtom(meta, news))
+	case FormatJSONFeed:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(buildJSONFeed(meta, news))
+	default:
+		return fmt.Errorf("unsupported feed format: %s", format)
+	}
+}
+
+// renderXML кодирует документ в XML с заголовком
+func renderXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// latestPublished возвращает дату самой свежей новости
+func latestPublished(news domain.NewsList) time.Time {
+	var latest time.Time
+	for _, item := range news {
+		if item.PublishedAt.After(latest) {
+			latest = item.PublishedAt
+		}
+	}
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	return latest.UTC()
+}
+
+// itemID возвращает стабильный идентификатор элемента ленты
+func itemID(item domain.News) string {
+	return "urn:infohub:news:" + item.ID
+}
+
+// RSS 2.0
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+func buildRSS(meta Meta, news domain.NewsList) rssDocument {
+	items := make([]rssItem, 0, len(news))
+	for _, item := range news {
+		items = append(items, rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			Description: item.Description,
+			GUID:        rssGUID{Value: itemID(item)},
+			PubDate:     item.PublishedAt.UTC().Format(time.RFC1123Z),
+			Category:    categoryOrSource(item),
+		})
+	}
+
+	return rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       meta.Title,
+			Link:        meta.Link,
+			Description: meta.Description,
+			AtomLink: rssLink{
+				Href: meta.FeedURL,
+				Rel:  "self",
+				Type: "application/rss+xml",
+			},
+			LastBuildDate: meta.Updated.UTC().Format(time.RFC1123Z),
+			Generator:     "InfoHub",
+			Items:         items,
+		},
+	}
+}
+
+// Atom 1.0
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	Updated   string      `xml:"updated"`
+	Generator string      `xml:"generator"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string        `xml:"id"`
+	Title     string        `xml:"title"`
+	Updated   string        `xml:"updated"`
+	Published string        `xml:"published"`
+	Links     []atomLink    `xml:"link,omitempty"`
+	Summary   string        `xml:"summary,omitempty"`
+	Author    atomPerson    `xml:"author"`
+	Category  *atomCategory `xml:"category,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func buildAtom(meta Meta, news domain.NewsList) atomFeed {
+	entries := make([]atomEntry, 0, len(news))
+	for _, item := range news {
+		published := item.PublishedAt.UTC().Format(time.RFC3339)
+		entry := atomEntry{
+			ID:        itemID(item),
+			Title:     item.Title,
+			Updated:   published,
+			Published: published,
+			Summary:   item.Description,
+			Author:    atomPerson{Name: item.Source},
+		}
+		if item.URL != "" {
+			entry.Links = []atomLink{{Href: item.URL, Rel: "alternate"}}
+		}
+		if category := categoryOrSource(item); category != "" {
+			entry.Category = &atomCategory{Term: category}
+		}
+		entries = append(entries, entry)
+	}
+
+	return atomFeed{
+		ID:        meta.FeedURL,
+		Title:     meta.Title,
+		Subtitle:  meta.Description,
+		Updated:   meta.Updated.UTC().Format(time.RFC3339),
+		Generator: "InfoHub",
+		Links: []atomLink{
+			{Href: meta.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: meta.Link, Rel: "alternate"},
+		},
+		Entries: entries,
+	}
+}
+
+// JSON Feed 1.1
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title"`
+	ContentText   string           `json:"content_text"`
+	DatePublished string           `json:"date_published"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func buildJSONFeed(meta Meta, news domain.NewsList) jsonFeed {
+	items := make([]jsonFeedItem, 0, len(news))
+	for _, item := range news {
+		feedItem := jsonFeedItem{
+			ID:            itemID(item),
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentText:   item.Description,
+			DatePublished: item.PublishedAt.UTC().Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: item.Source}},
+		}
+		if item.Category != "" {
+			feedItem.Tags = []string{item.Category}
+		}
+		items = append(items, feedItem)
+	}
+
+	return jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       meta.Title,
+		HomePageURL: meta.Link,
+		FeedURL:     meta.FeedURL,
+		Description: meta.Description,
+		Items:       items,
+	}
+}
+
+// categoryOrSource возвращает категорию новости или, если она не задана, источник
+func categoryOrSource(item domain.News) string {
+	if item.Category != "" {
+		return item.Category
+	}
+	return item.Source
+}
@@ -23,6 +23,11 @@ type Metrics struct {
 	SourcesActive          *prometheus.GaugeVec
 	NewsCacheSize          prometheus.Gauge
 
+	// Cache метрики
+	CacheHits      *prometheus.CounterVec
+	CacheMisses    *prometheus.CounterVec
+	CacheEvictions *prometheus.CounterVec
+
 	// System метрики
 	ApplicationInfo *prometheus.GaugeVec
 	StartTime       prometheus.Gauge
@@ -108,6 +113,35 @@ func New(namespace, subsystem string) *Metrics {
 			},
 		),
 
+		// Cache метрики
+		CacheHits: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "cache_hits_total",
+				Help:      "Total number of cache hits",
+			},
+			[]string{"cache"},
+		),
+		CacheMisses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "cache_misses_total",
+				Help:      "Total number of cache misses",
+			},
+			[]string{"cache"},
+		),
+		CacheEvictions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: subsystem,
+				Name:      "cache_evictions_total",
+				Help:      "Total number of cache evictions",
+			},
+			[]string{"cache", "reason"}, // "size", "expired"
+		),
+
 		// System метрики
 		ApplicationInfo: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -145,6 +179,9 @@ func (m *Metrics) Register() error {
 		m.NewsCollectionDuration,
 		m.SourcesActive,
 		m.NewsCacheSize,
+		m.CacheHits,
+		m.CacheMisses,
+		m.CacheEvictions,
 		m.ApplicationInfo,
 		m.StartTime,
 	}
@@ -189,6 +226,21 @@ func (m *Metrics) SetNewsCacheSize(size int) {
 	m.NewsCacheSize.Set(float64(size))
 }
 
+// RecordCacheHit записывает попадание в кэш
+func (m *Metrics) RecordCacheHit(cache string) {
+	m.CacheHits.WithLabelValues(cache).Inc()
+}
+
+// RecordCacheMiss записывает промах кэша
+func (m *Metrics) RecordCacheMiss(cache string) {
+	m.CacheMisses.WithLabelValues(cache).Inc()
+}
+
+// RecordCacheEviction записывает вытеснение элемента из кэша
+func (m *Metrics) RecordCacheEviction(cache, reason string) {
+	m.CacheEvictions.WithLabelValues(cache, reason).Inc()
+}
+
 // SetApplicationInfo устанавливает информацию о приложении
 func (m *Metrics) SetApplicationInfo(version, goVersion, gitCommit string) {
 	m.ApplicationInfo.WithLabelValues(version, goVersion, gitCommit).Set(1)
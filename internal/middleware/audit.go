@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/pah-an/infohub/internal/audit"
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/logger"
+)
+
+// Audit записывает в журнал аудита запросы, отмеченные обработчиками через
+// audit.Mark. IP, request ID и, если не задан явно, результат по коду ответа
+// заполняются здесь
+func Audit(auditLog *audit.Log, logger *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, event := audit.WithEvent(r.Context())
+			lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(lrw, r.WithContext(ctx))
+
+			if event.Action == "" {
+				return
+			}
+			if event.Actor == "" {
+				event.Actor = "anonymous"
+			}
+			if event.Target == "" {
+				event.Target = auditTarget(r)
+			}
+			if event.Outcome == "" {
+				event.Outcome = auditOutcome(lrw.statusCode)
+			}
+			event.IP = getClientIP(r)
+			event.RequestID, _ = r.Context().Value("request_id").(string)
+
+			if err := auditLog.Record(*event); err != nil {
+				logger.WithError(err).WithField("action", event.Action).Error("Failed to write audit event")
+			}
+		})
+	}
+}
+
+// Audited отмечает запросы маршрута действием action. Исполнитель берется
+// из контекста, поэтому обработчик должен стоять после middleware Auth
+func Audited(action string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := ""
+		if user, ok := auth.UserFromContext(r.Context()); ok {
+			actor = user.ID
+		}
+		audit.Mark(r.Context(), action, actor, "")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auditTarget описывает объект действия адресом запроса без секретов
+func auditTarget(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del("api_key")
+	if len(query) == 0 {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// auditOutcome определяет результат действия по коду ответа
+func auditOutcome(status int) string {
+	switch {
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return audit.OutcomeDenied
+	case status >= http.StatusBadRequest:
+		return audit.OutcomeFailure
+	default:
+		return audit.OutcomeSuccess
+	}
+}
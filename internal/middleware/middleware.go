@@ -12,6 +12,7 @@ import (
 
 	"golang.org/x/time/rate"
 
+	"github.com/pah-an/infohub/internal/audit"
 	"github.com/pah-an/infohub/internal/auth"
 	"github.com/pah-an/infohub/internal/logger"
 	"github.com/pah-an/infohub/internal/metrics"
@@ -242,6 +243,8 @@ func Auth(authManager *auth.Manager, logger *logger.Logger) func(http.Handler) h
 					"ip":         getClientIP(r),
 					"request_id": r.Context().Value("request_id"),
 				}).Warn("Authentication failed")
+				audit.Mark(r.Context(), "auth.failure", "", "")
+				audit.SetOutcome(r.Context(), audit.OutcomeDenied, err.Error())
 
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusUnauthorized)
@@ -257,6 +260,7 @@ func Auth(authManager *auth.Manager, logger *logger.Logger) func(http.Handler) h
 
 			r.Header.Set("X-User-ID", user.ID)
 			r.Header.Set("X-User-Admin", strconv.FormatBool(user.IsAdmin))
+			r = r.WithContext(auth.WithUser(r.Context(), user))
 
 			logger.WithFields(map[string]interface{}{
 				"user_id":    user.ID,
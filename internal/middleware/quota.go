@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/usage"
+)
+
+// Quota учитывает запросы и объем ответов пользователя из контекста и
+// отклоняет запросы сверх квоты. Запросы администраторов учитываются, но
+// не ограничиваются
+func Quota(tracker *usage.Tracker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := auth.UserFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			consumer := user.UsageID()
+			current, err := tracker.Acquire(consumer, !user.IsAdmin)
+			setQuotaHeaders(w.Header(), current)
+
+			if errors.Is(err, usage.ErrQuotaExceeded) {
+				reset := current.ResetAt()
+				w.Header().Set("Retry-After", strconv.FormatInt(int64(time.Until(reset).Seconds())+1, 10))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+
+				response := map[string]interface{}{
+					"error":    "Quota exceeded",
+					"code":     429,
+					"message":  fmt.Sprintf("API quota is exhausted until %s", reset.Format(time.RFC3339)),
+					"reset_at": reset,
+				}
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+
+			cw := &countingResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(cw, r)
+			tracker.AddBytes(consumer, cw.bytes)
+		})
+	}
+}
+
+// setQuotaHeaders сообщает клиенту заданные квоты и их остаток
+func setQuotaHeaders(header http.Header, current usage.Usage) {
+	set := func(name string, limit, used int64, reset time.Time) {
+		if limit <= 0 {
+			return
+		}
+		header.Set("X-Quota-"+name+"-Limit", strconv.FormatInt(limit, 10))
+		header.Set("X-Quota-"+name+"-Remaining", strconv.FormatInt(max(limit-used, 0), 10))
+		header.Set("X-Quota-"+name+"-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}
+
+	set("Daily", current.Quota.DailyRequests, current.Daily.Requests, current.DailyReset())
+	set("Monthly", current.Quota.MonthlyRequests, current.Monthly.Requests, current.MonthlyReset())
+	set("Daily-Bytes", current.Quota.DailyBytes, current.Daily.Bytes, current.DailyReset())
+	set("Monthly-Bytes", current.Quota.MonthlyBytes, current.Monthly.Bytes, current.MonthlyReset())
+}
+
+// countingResponseWriter подсчитывает объем тела ответа
+type countingResponseWriter struct {
+	http.ResponseWriter
+	bytes int64
+}
+
+func (cw *countingResponseWriter) Write(data []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(data)
+	cw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter
+func (cw *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/netip"
 	"time"
 
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 
+	"github.com/pah-an/infohub/internal/audit"
 	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/cache"
 	"github.com/pah-an/infohub/internal/config"
 	"github.com/pah-an/infohub/internal/domain"
 	"github.com/pah-an/infohub/internal/health"
@@ -17,23 +20,31 @@ import (
 	"github.com/pah-an/infohub/internal/metrics"
 	"github.com/pah-an/infohub/internal/middleware"
 	v1 "github.com/pah-an/infohub/internal/server/v1"
+	"github.com/pah-an/infohub/internal/usage"
 )
 
 // Config содержит конфигурацию сервера
 type Config struct {
-	Host          string
-	Port          string
-	ReadTimeout   time.Duration
-	WriteTimeout  time.Duration
-	IdleTimeout   time.Duration
-	NewsProvider  NewsProvider
-	Logger        *logger.Logger
-	Metrics       *metrics.Metrics
-	AuthManager   *auth.Manager
-	HealthManager *health.Manager
-	RateLimiting  config.RateLimitConfig
-	CORS          config.CORSConfig
-	Security      config.SecurityConfig
+	Host           string
+	Port           string
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+	IdleTimeout    time.Duration
+	TLS            config.TLSConfig
+	NewsProvider   NewsProvider
+	NewsRepository domain.NewsRepository
+	Cache          cache.Cache
+	Logger         *logger.Logger
+	Metrics        *metrics.Metrics
+	AuthManager    *auth.Manager
+	Usage          *usage.Tracker // nil - учет использования отключен
+	Audit          *audit.Log     // nil - журнал аудита отключен
+	HealthManager  *health.Manager
+	RateLimiting   config.RateLimitConfig
+	CORS           config.CORSConfig
+	Security       config.SecurityConfig
+	ResponseCache  config.ResponseCacheConfig
+	TrustedProxies []netip.Prefix // пусто - X-Forwarded-Proto в лентах не принимается
 }
 
 // InfoHubServer представляет HTTP сервер
@@ -42,6 +53,7 @@ type InfoHubServer struct {
 	logger        *logger.Logger
 	metrics       *metrics.Metrics
 	healthManager *health.Manager
+	tls           config.TLSConfig
 }
 
 // NewsProvider определяет интерфейс для получения новостей
@@ -55,12 +67,16 @@ func NewInfoHubServer(cfg Config) *InfoHubServer {
 		logger:        cfg.Logger,
 		metrics:       cfg.Metrics,
 		healthManager: cfg.HealthManager,
+		tls:           cfg.TLS,
 	}
 
 	router := mux.NewRouter()
 
 	// Применяем базовые middleware
 	router.Use(middleware.RequestID)
+	if cfg.Audit != nil {
+		router.Use(middleware.Audit(cfg.Audit, cfg.Logger))
+	}
 	router.Use(middleware.Security)
 
 	if cfg.Logger != nil {
@@ -88,7 +104,15 @@ func NewInfoHubServer(cfg Config) *InfoHubServer {
 	router.Use(middleware.Recovery(cfg.Logger))
 	router.Use(middleware.Timeout(30 * time.Second))
 
-	v1Handlers := v1.NewHandlers(cfg.NewsProvider)
+	v1Handlers := v1.NewHandlers(cfg.NewsProvider, cfg.NewsRepository, cfg.Cache)
+	if cfg.ResponseCache.Enabled {
+		v1Handlers.SetResponseCache(v1.ResponseCacheOptions{
+			MaxAge:      cfg.ResponseCache.MaxAge,
+			CacheBodies: cfg.ResponseCache.CacheBodies,
+			BodyTTL:     cfg.ResponseCache.BodyTTL,
+		})
+	}
+	v1Handlers.SetTrustedProxies(cfg.TrustedProxies)
 
 	// API v1 routes с аутентификацией
 	apiV1 := router.PathPrefix("/api/v1").Subrouter()
@@ -97,20 +121,44 @@ func NewInfoHubServer(cfg Config) *InfoHubServer {
 		// Публичные endpoints (без аутентификации)
 		apiV1.HandleFunc("/healthz", v1Handlers.GetHealth).Methods("GET")
 
-		// Приватные endpoints (с аутентификацией)
+		// Приватные endpoints (с аутентификацией). Права проверяются
+		// по областям доступа отдельно для каждого маршрута
 		protectedV1 := apiV1.PathPrefix("").Subrouter()
 		protectedV1.Use(middleware.Auth(cfg.AuthManager, cfg.Logger))
-		protectedV1.HandleFunc("/news", v1Handlers.GetNews).Methods("GET")
+		// Квота расходуется только запросами, прошедшими проверку прав
+		limited := func(handler http.Handler) http.Handler { return handler }
+		if cfg.Usage != nil {
+			limited = middleware.Quota(cfg.Usage)
+		}
+		scoped := func(scope string, handler http.HandlerFunc) http.Handler {
+			return cfg.AuthManager.RequireScope(scope)(limited(handler))
+		}
+
+		protectedV1.Handle("/news", scoped(auth.ScopeNewsRead, v1Handlers.GetNews)).Methods("GET")
+		registerFeedRoutes(protectedV1, v1Handlers, func(handler http.HandlerFunc) http.Handler {
+			return scoped(auth.ScopeNewsRead, handler)
+		})
 
 		// Admin endpoints
-		adminV1 := apiV1.PathPrefix("/admin").Subrouter()
-		adminV1.Use(cfg.AuthManager.RequireAdmin())
-		adminV1.HandleFunc("/stats", v1Handlers.GetAdminStats).Methods("GET")
-		adminV1.HandleFunc("/sources", v1Handlers.GetAdminSources).Methods("GET")
-		adminV1.HandleFunc("/cache/clear", v1Handlers.ClearAdminCache).Methods("POST")
+		adminV1 := protectedV1.PathPrefix("/admin").Subrouter()
+		adminV1.Handle("/stats", middleware.Audited("stats.read", scoped(auth.ScopeAdminStats, v1Handlers.GetAdminStats))).Methods("GET")
+		adminV1.Handle("/sources", middleware.Audited("sources.read", scoped(auth.ScopeSourcesRead, v1Handlers.GetAdminSources))).Methods("GET")
+		adminV1.Handle("/cache/clear", middleware.Audited("cache.clear", scoped(auth.ScopeAdminCache, v1Handlers.ClearAdminCache))).Methods("POST")
+		adminV1.Handle("/cache/keys", middleware.Audited("cache.keys", scoped(auth.ScopeAdminCache, v1Handlers.GetAdminCacheKeys))).Methods("GET")
+		adminV1.Handle("/export", middleware.Audited("news.export", scoped(auth.ScopeNewsExport, v1Handlers.GetAdminExport))).Methods("GET")
+		adminV1.Handle("/import", middleware.Audited("news.import", scoped(auth.ScopeIngest, v1Handlers.PostAdminImport))).Methods("POST")
+		adminV1.Handle("/keys", middleware.Audited("keys.create", scoped(auth.ScopeAdminKeys, v1Handlers.PostAdminKey(cfg.AuthManager)))).Methods("POST")
+		adminV1.Handle("/keys", middleware.Audited("keys.list", scoped(auth.ScopeAdminKeys, v1Handlers.GetAdminKeys(cfg.AuthManager)))).Methods("GET")
+		adminV1.Handle("/keys/{id}", middleware.Audited("keys.revoke", scoped(auth.ScopeAdminKeys, v1Handlers.DeleteAdminKey(cfg.AuthManager)))).Methods("DELETE")
+		adminV1.Handle("/usage", middleware.Audited("usage.read", scoped(auth.ScopeAdminUsage, v1Handlers.GetAdminUsage(cfg.Usage)))).Methods("GET")
+		adminV1.Handle("/audit", middleware.Audited("audit.read", scoped(auth.ScopeAdminAudit, v1Handlers.GetAdminAudit(cfg.Audit)))).Methods("GET")
+
+		// Любой аутентифицированный потребитель может узнать свой остаток квоты
+		protectedV1.HandleFunc("/me/usage", v1Handlers.GetMyUsage(cfg.Usage)).Methods("GET")
 	} else {
 		// Без аутентификации (development mode)
 		apiV1.HandleFunc("/news", v1Handlers.GetNews).Methods("GET")
+		registerFeedRoutes(apiV1, v1Handlers, func(handler http.HandlerFunc) http.Handler { return handler })
 		apiV1.HandleFunc("/healthz", v1Handlers.GetHealth).Methods("GET")
 	}
 
@@ -140,15 +188,25 @@ func NewInfoHubServer(cfg Config) *InfoHubServer {
 	if cfg.AuthManager != nil {
 		authRouter := router.PathPrefix("/auth").Subrouter()
 		authRouter.HandleFunc("/login", v1Handlers.PostLogin(cfg.AuthManager)).Methods("POST")
+		authRouter.HandleFunc("/refresh", v1Handlers.PostRefresh(cfg.AuthManager)).Methods("POST")
+		authRouter.HandleFunc("/logout", v1Handlers.PostLogout(cfg.AuthManager)).Methods("POST")
 		authRouter.HandleFunc("/validate", v1Handlers.GetValidateToken(cfg.AuthManager)).Methods("GET")
+
+		// Открытые ключи для проверки токенов сторонними сервисами
+		router.HandleFunc("/.well-known/jwks.json", handleJWKS(cfg.AuthManager)).Methods("GET")
 	}
 
 	// Admin panel (static files)
-	router.PathPrefix("/admin/").Handler(
-		http.StripPrefix("/admin/",
-			http.HandlerFunc(server.handleAdminPanel),
-		),
-	).Methods("GET")
+	var adminPanel http.Handler = http.StripPrefix("/admin/", http.HandlerFunc(server.handleAdminPanel))
+	if cfg.AuthManager != nil && cfg.AuthManager.OIDC() != nil {
+		// Вход сотрудников через провайдера единого входа
+		oidc := cfg.AuthManager.OIDC()
+		router.HandleFunc("/admin/login", oidc.Login).Methods("GET")
+		router.Handle("/admin/callback", middleware.Audited("auth.oidc_login", http.HandlerFunc(oidc.Callback))).Methods("GET")
+		router.Handle("/admin/logout", middleware.Audited("auth.oidc_logout", http.HandlerFunc(oidc.Logout))).Methods("POST")
+		adminPanel = oidc.RequireSession(auth.ScopeAdminPanel)(adminPanel)
+	}
+	router.PathPrefix("/admin/").Handler(adminPanel).Methods("GET")
 
 	// Обратная совместимость (redirect старых endpoints)
 	router.HandleFunc("/news", func(w http.ResponseWriter, r *http.Request) {
@@ -172,11 +230,32 @@ func NewInfoHubServer(cfg Config) *InfoHubServer {
 	return server
 }
 
-// Start запускает HTTP сервер
+// registerFeedRoutes регистрирует маршруты лент новостей. wrap оборачивает
+// обработчики, например проверкой области доступа
+func registerFeedRoutes(router *mux.Router, handlers *v1.Handlers, wrap func(http.HandlerFunc) http.Handler) {
+	router.Handle("/feeds/{format}", wrap(handlers.GetFeed)).Methods("GET")
+	router.Handle("/feeds/{format}/sources/{source}", wrap(handlers.GetSourceFeed)).Methods("GET")
+	router.Handle("/feeds/{format}/categories/{category}", wrap(handlers.GetCategoryFeed)).Methods("GET")
+}
+
+// Start запускает HTTP сервер, а при включенном TLS - HTTPS сервер
 func (s *InfoHubServer) Start() error {
-	s.logger.WithField("address", s.httpServer.Addr).Info("Starting HTTP server")
+	if s.tls.Enabled {
+		tlsConfig, err := NewTLSConfig(s.tls, s.logger)
+		if err != nil {
+			return err
+		}
+		s.httpServer.TLSConfig = tlsConfig
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"address": s.httpServer.Addr,
+		"tls":     s.tls.Enabled,
+		"mtls":    s.tls.Enabled && s.tls.ClientCAFile != "",
+	}).Info("Starting HTTP server")
 	s.logger.Info("Available endpoints:")
 	s.logger.Info("  GET /api/v1/news         - Get latest news")
+	s.logger.Info("  GET /api/v1/feeds/{fmt}  - RSS/Atom/JSON Feed")
 	s.logger.Info("  GET /api/v1/healthz      - Simple health check")
 	s.logger.Info("  GET /health              - Detailed health check")
 	s.logger.Info("  GET /health/live         - Liveness probe")
@@ -185,10 +264,22 @@ func (s *InfoHubServer) Start() error {
 	s.logger.Info("  GET /swagger/            - API documentation")
 	s.logger.Info("  GET /admin/              - Admin panel")
 	s.logger.Info("  POST /auth/login         - Authentication")
+	s.logger.Info("  POST /auth/refresh       - Refresh tokens")
+	s.logger.Info("  POST /auth/logout        - Revoke tokens")
+	s.logger.Info("  GET /.well-known/jwks.json - JWT verification keys")
 
+	if s.tls.Enabled {
+		// Сертификаты отдает TLSConfig, поэтому пути к файлам не передаются
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
 	return s.httpServer.ListenAndServe()
 }
 
+// Handler возвращает корневой HTTP handler сервера
+func (s *InfoHubServer) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
 // Shutdown корректно завершает работу сервера
 func (s *InfoHubServer) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server...")
@@ -207,10 +298,20 @@ func (s *InfoHubServer) handleAPIInfo(w http.ResponseWriter, r *http.Request) {
 				"status": "active",
 				"endpoints": []string{
 					"/api/v1/news",
+					"/api/v1/feeds/{format}",
+					"/api/v1/feeds/{format}/sources/{source}",
+					"/api/v1/feeds/{format}/categories/{category}",
 					"/api/v1/healthz",
 					"/api/v1/admin/stats",
 					"/api/v1/admin/sources",
 					"/api/v1/admin/cache/clear",
+					"/api/v1/admin/cache/keys",
+					"/api/v1/admin/export",
+					"/api/v1/admin/import",
+					"/api/v1/admin/keys",
+					"/api/v1/admin/usage",
+					"/api/v1/admin/audit",
+					"/api/v1/me/usage",
 				},
 			},
 		},
@@ -227,6 +328,19 @@ func (s *InfoHubServer) handleAPIInfo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleJWKS публикует открытые ключи подписи JWT. Ответ кэшируется ненадолго,
+// чтобы новый ключ после ротации быстро становился известен проверяющим
+func handleJWKS(authManager *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(http.StatusOK)
+
+		json.NewEncoder(w).Encode(authManager.JWKS())
+	}
+}
+
 // handleLiveness обрабатывает liveness probe для Kubernetes
 func (s *InfoHubServer) handleLiveness(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -275,9 +389,17 @@ func (s *InfoHubServer) handleAdminPanel(w http.ResponseWriter, r *http.Request)
     <div class="card">
         <h2>API Endpoints</h2>
         <div class="endpoint">GET /api/v1/news - Get latest news</div>
+        <div class="endpoint">GET /api/v1/feeds/{rss|atom|jsonfeed} - News feeds</div>
         <div class="endpoint">GET /api/v1/admin/stats - System statistics</div>
         <div class="endpoint">GET /api/v1/admin/sources - Source information</div>
-        <div class="endpoint">POST /api/v1/admin/cache/clear - Clear cache</div>
+        <div class="endpoint">POST /api/v1/admin/cache/clear?pattern=* - Clear cache</div>
+        <div class="endpoint">GET /api/v1/admin/cache/keys?pattern=*&values=true - Inspect cache keys</div>
+        <div class="endpoint">GET /api/v1/admin/export?format=ndjson|csv - Export news</div>
+        <div class="endpoint">POST /api/v1/admin/import?format=ndjson|csv - Import news</div>
+        <div class="endpoint">GET/POST /api/v1/admin/keys - List and issue API keys</div>
+        <div class="endpoint">DELETE /api/v1/admin/keys/{id} - Revoke API key</div>
+        <div class="endpoint">GET /api/v1/admin/usage?consumer= - API usage and quotas</div>
+        <div class="endpoint">GET /api/v1/admin/audit?actor=&action=&since= - Audit log</div>
     </div>
     
     <div class="card">
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pah-an/infohub/internal/config"
+	"github.com/pah-an/infohub/internal/logger"
+)
+
+// certReloader отдает TLS конфигурацию и перечитывает сертификат, ключ и
+// CA bundle при изменении файлов, так что обновление сертификата не требует
+// перезапуска сервиса
+type certReloader struct {
+	config    config.TLSConfig
+	logger    *logger.Logger
+	current   *tls.Config
+	loadedSig string
+	checkedAt time.Time
+	mutex     sync.Mutex
+}
+
+// NewTLSConfig создает TLS конфигурацию сервера с горячей перезагрузкой сертификатов
+func NewTLSConfig(cfg config.TLSConfig, log *logger.Logger) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("tls requires cert_file and key_file")
+	}
+	if cfg.ClientAuth == "" {
+		cfg.ClientAuth = "optional"
+	}
+	if cfg.ClientAuth != "optional" && cfg.ClientAuth != "require" {
+		return nil, fmt.Errorf("unsupported tls client_auth: %s", cfg.ClientAuth)
+	}
+	if cfg.ClientAuth == "require" && cfg.ClientCAFile == "" {
+		return nil, fmt.Errorf("tls client_auth require needs client_ca_file")
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = 30 * time.Second
+	}
+
+	minVersion, err := tlsVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader := &certReloader{config: cfg, logger: log}
+	if err = reloader.load(minVersion); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:         minVersion,
+		NextProtos:         []string{"h2", "http/1.1"},
+		GetConfigForClient: reloader.configForClient,
+	}, nil
+}
+
+// configForClient возвращает актуальную конфигурацию для нового соединения.
+// Файлы проверяются не чаще ReloadInterval; при ошибке чтения остается
+// предыдущий сертификат
+func (r *certReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if time.Since(r.checkedAt) < r.config.ReloadInterval {
+		return r.current, nil
+	}
+	r.checkedAt = time.Now()
+
+	if r.signature() == r.loadedSig {
+		return r.current, nil
+	}
+	if err := r.load(r.current.MinVersion); err != nil {
+		if r.logger != nil {
+			r.logger.WithError(err).Warn("Failed to reload TLS certificates, keeping the previous ones")
+		}
+		return r.current, nil
+	}
+	if r.logger != nil {
+		r.logger.WithField("cert_file", r.config.CertFile).Info("TLS certificates reloaded")
+	}
+	return r.current, nil
+}
+
+// load читает сертификат, ключ и CA bundle. Вызывается под блокировкой
+func (r *certReloader) load(minVersion uint16) error {
+	signature := r.signature()
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	current := &tls.Config{
+		MinVersion:   minVersion,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert,
+	}
+
+	if r.config.ClientCAFile != "" {
+		data, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", r.config.ClientCAFile)
+		}
+		current.ClientCAs = pool
+		current.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.config.ClientAuth == "require" {
+			current.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	r.current = current
+	r.loadedSig = signature
+	return nil
+}
+
+// signature описывает состояние файлов по времени изменения и размеру
+func (r *certReloader) signature() string {
+	var parts []string
+	for _, path := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			parts = append(parts, path+":missing")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.ModTime().UnixNano(), info.Size()))
+	}
+	return strings.Join(parts, "|")
+}
+
+// tlsVersion переводит версию протокола из конфигурации
+func tlsVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls min_version: %s", version)
+	}
+}
@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pah-an/infohub/internal/audit"
+)
+
+// AuditResponse представляет выборку из журнала аудита
+type AuditResponse struct {
+	Count  int           `json:"count" example:"1"`
+	Events []audit.Event `json:"events"`
+}
+
+// GetAdminAudit
+// @Summary      Журнал аудита
+// @Description  Возвращает записи журнала аудита о входах, ошибках аутентификации и действиях администраторов, от новых к старым (область admin:audit)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        since   query     string  false  "Начало периода (RFC3339)"
+// @Param        until   query     string  false  "Конец периода (RFC3339)"
+// @Param        actor   query     string  false  "Исполнитель"
+// @Param        action  query     string  false  "Действие, например cache.clear"
+// @Param        limit   query     int     false  "Максимум записей (по умолчанию 100, не более 1000)"
+// @Success      200     {object}  AuditResponse
+// @Failure      400     {object}  ErrorResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse
+// @Failure      501     {object}  ErrorResponse
+// @Router       /admin/audit [get]
+func (h *Handlers) GetAdminAudit(auditLog *audit.Log) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auditLog == nil {
+			h.writeErrorResponse(w, "Audit log is not enabled", http.StatusNotImplemented)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := audit.Filter{
+			Actor:  query.Get("actor"),
+			Action: query.Get("action"),
+		}
+
+		var err error
+		if since := query.Get("since"); since != "" {
+			if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+				h.writeErrorResponse(w, "Invalid since parameter, expected RFC3339", http.StatusBadRequest)
+				return
+			}
+		}
+		if until := query.Get("until"); until != "" {
+			if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+				h.writeErrorResponse(w, "Invalid until parameter, expected RFC3339", http.StatusBadRequest)
+				return
+			}
+		}
+		if limit := query.Get("limit"); limit != "" {
+			if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 {
+				h.writeErrorResponse(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		events, err := auditLog.Query(filter)
+		if err != nil {
+			h.writeErrorResponse(w, "Failed to read audit log", http.StatusInternalServerError)
+			return
+		}
+
+		h.writeJSONResponse(w, AuditResponse{Count: len(events), Events: events}, http.StatusOK)
+	}
+}
@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentVersioner реализуется поставщиками новостей, которые знают версию
+// своего содержимого. Версия меняется при любом изменении набора новостей
+type ContentVersioner interface {
+	ContentVersion() (version string, updatedAt time.Time)
+}
+
+// ResponseCacheOptions задает HTTP кэширование ответов со списком новостей
+type ResponseCacheOptions struct {
+	MaxAge      time.Duration // значение Cache-Control max-age; 0 - клиент всегда перепроверяет ответ
+	CacheBodies bool          // хранить закодированные ответы в кэше
+	BodyTTL     time.Duration
+}
+
+// SetResponseCache включает ETag, 304 Not Modified и Cache-Control для /news.
+// Требует поставщика новостей, реализующего ContentVersioner
+func (h *Handlers) SetResponseCache(options ResponseCacheOptions) {
+	if options.BodyTTL == 0 {
+		options.BodyTTL = 5 * time.Minute
+	}
+	h.responseCache = &options
+}
+
+// newsETag формирует ETag из версии содержимого и параметров выборки
+func newsETag(version string, query newsQuery) string {
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d\x00%s\x00%s", query.Limit, strings.ToLower(query.Source), strings.ToLower(query.Category))
+	return `"` + version + "-" + strconv.FormatUint(hash.Sum64(), 36) + `"`
+}
+
+// notModified проверяет условные заголовки запроса. If-Modified-Since
+// учитывается только при отсутствии If-None-Match
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		return err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// writeCachedNews отвечает на запрос новостей с учетом HTTP кэширования.
+// Возвращает false, если поставщик не поддерживает версии и ответ нужно сформировать обычным способом
+func (h *Handlers) writeCachedNews(w http.ResponseWriter, r *http.Request, query newsQuery) bool {
+	versioner, ok := h.newsProvider.(ContentVersioner)
+	if !ok || h.responseCache == nil {
+		return false
+	}
+
+	version, updatedAt := versioner.ContentVersion()
+	etag := newsETag(version, query)
+
+	header := w.Header()
+	header.Set("ETag", etag)
+	if !updatedAt.IsZero() {
+		header.Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+	}
+	if h.responseCache.MaxAge > 0 {
+		header.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(h.responseCache.MaxAge.Seconds())))
+	} else {
+		header.Set("Cache-Control", "private, no-cache")
+	}
+
+	if notModified(r, etag, updatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	// Ключ включает версию содержимого, поэтому устаревшие тела не отдаются
+	bodyKey := "http:news:" + strings.Trim(etag, `"`)
+	if h.responseCache.CacheBodies && h.cacheStore != nil {
+		var body []byte
+		if err := h.cacheStore.Get(r.Context(), bodyKey, &body); err == nil {
+			h.writeRawJSON(w, body)
+			return true
+		}
+	}
+
+	news := h.queryNews(query)
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(NewsResponse{Count: len(news), News: news, Version: "v1"}); err != nil {
+		h.writeErrorResponse(w, "Failed to encode response", http.StatusInternalServerError)
+		return true
+	}
+
+	if h.responseCache.CacheBodies && h.cacheStore != nil {
+		if err := h.cacheStore.Set(context.Background(), bodyKey, buf.Bytes(), h.responseCache.BodyTTL); err != nil {
+			log.Printf("Failed to cache news response: %v", err)
+		}
+	}
+
+	h.writeRawJSON(w, buf.Bytes())
+	return true
+}
+
+// writeRawJSON отправляет заранее закодированный JSON ответ
+func (h *Handlers) writeRawJSON(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(body); err != nil {
+		log.Printf("Error writing JSON response: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pah-an/infohub/internal/bulk"
+)
+
+// maxImportBodySize ограничивает размер тела запроса на импорт
+const maxImportBodySize = 256 << 20
+
+// newsReloader реализуется провайдерами, которые держат новости в памяти
+// и должны перечитать репозиторий после импорта
+type newsReloader interface {
+	LoadFromRepository() error
+}
+
+// GetAdminExport
+// @Summary      Выгрузить новости
+// @Description  Потоково выгружает все новости из хранилища в формате NDJSON или CSV (область news:export)
+// @Tags         admin
+// @Produce      plain
+// @Security     BearerAuth
+// @Param        format  query     string  false  "Формат выгрузки (по умолчанию ndjson)"  Enums(ndjson, csv)
+// @Param        from    query     string  false  "Начало диапазона published_at (RFC3339)"
+// @Param        to      query     string  false  "Конец диапазона published_at (RFC3339)"
+// @Success      200     {string}  string  "Поток новостей"
+// @Failure      400     {object}  ErrorResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse
+// @Failure      503     {object}  ErrorResponse
+// @Router       /admin/export [get]
+func (h *Handlers) GetAdminExport(w http.ResponseWriter, r *http.Request) {
+	if h.newsRepository == nil {
+		h.writeErrorResponse(w, "News storage is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	format, err := bulk.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		h.writeErrorResponse(w, "Invalid format parameter. Use ndjson or csv", http.StatusBadRequest)
+		return
+	}
+
+	timeRange, err := parseTimeRange(r)
+	if err != nil {
+		h.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("infohub-news-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	// Заголовки уже отправлены, поэтому ошибки потока можно только залогировать
+	count, err := bulk.Export(w, h.newsRepository, format, timeRange)
+	if err != nil {
+		log.Printf("Error exporting news after %d records: %v", count, err)
+	}
+}
+
+// PostAdminImport
+// @Summary      Загрузить новости
+// @Description  Потоково импортирует новости в формате NDJSON или CSV пачками и объединяет их с хранилищем по ID (область ingest). При ошибке сохраненные до нее пачки остаются в хранилище
+// @Tags         admin
+// @Accept       plain
+// @Produce      json
+// @Security     BearerAuth
+// @Param        format  query     string  false  "Формат данных (по умолчанию определяется по Content-Type)"  Enums(ndjson, csv)
+// @Success      200     {object}  bulk.ImportResult
+// @Failure      400     {object}  ErrorResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse
+// @Failure      503     {object}  ErrorResponse
+// @Router       /admin/import [post]
+func (h *Handlers) PostAdminImport(w http.ResponseWriter, r *http.Request) {
+	if h.newsRepository == nil {
+		h.writeErrorResponse(w, "News storage is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	formatParam := r.URL.Query().Get("format")
+	if formatParam == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		formatParam = string(bulk.FormatCSV)
+	}
+
+	format, err := bulk.ParseFormat(formatParam)
+	if err != nil {
+		h.writeErrorResponse(w, "Invalid format parameter. Use ndjson or csv", http.StatusBadRequest)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodySize)
+	result, importErr := bulk.Import(body, h.newsRepository, format)
+
+	// Пачки, сохраненные до ошибки, остаются в хранилище, поэтому окно
+	// перечитывается и после частичного импорта
+	if reloader, ok := h.newsProvider.(newsReloader); ok && result.Imported > 0 {
+		if err = reloader.LoadFromRepository(); err != nil {
+			log.Printf("Error reloading news after import: %v", err)
+		}
+	}
+
+	if importErr != nil {
+		h.writeErrorResponse(w, fmt.Sprintf("Import failed after %d records: %v", result.Imported, importErr), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSONResponse(w, result, http.StatusOK)
+}
+
+// parseTimeRange разбирает параметры from/to из запроса
+func parseTimeRange(r *http.Request) (bulk.TimeRange, error) {
+	var timeRange bulk.TimeRange
+
+	if from := r.URL.Query().Get("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return timeRange, fmt.Errorf("Invalid from parameter. Use RFC3339 format")
+		}
+		timeRange.From = parsed
+	}
+
+	if to := r.URL.Query().Get("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return timeRange, fmt.Errorf("Invalid to parameter. Use RFC3339 format")
+		}
+		timeRange.To = parsed
+	}
+
+	return timeRange, nil
+}
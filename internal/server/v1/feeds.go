@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/pah-an/infohub/internal/feed"
+	"github.com/pah-an/infohub/internal/middleware"
+)
+
+// GetFeed
+// @Summary      Получить ленту новостей
+// @Description  Возвращает агрегированные новости в формате RSS 2.0, Atom 1.0 или JSON Feed 1.1
+// @Tags         feeds
+// @Produce      xml
+// @Produce      json
+// @Param        format    path      string  true   "Формат ленты"  Enums(rss, atom, jsonfeed)
+// @Param        limit     query     int     false  "Количество новостей (по умолчанию 100)"  minimum(1)  maximum(1000)
+// @Param        source    query     string  false  "Фильтр по источнику"
+// @Param        category  query     string  false  "Фильтр по категории"
+// @Success      200       {string}  string  "Лента новостей"
+// @Failure      400       {object}  ErrorResponse
+// @Failure      404       {object}  ErrorResponse
+// @Router       /feeds/{format} [get]
+func (h *Handlers) GetFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	format, err := feed.ParseFormat(vars["format"])
+	if err != nil {
+		h.writeErrorResponse(w, "Unsupported feed format. Use rss, atom or jsonfeed", http.StatusNotFound)
+		return
+	}
+
+	query, err := parseNewsQuery(r)
+	if err != nil {
+		h.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Варианты ленты по источнику и категории задаются сегментом пути
+	title := "InfoHub"
+	if source, ok := vars["source"]; ok {
+		query.Source = source
+		title += ": " + source
+	}
+	if category, ok := vars["category"]; ok {
+		query.Category = category
+		title += ": " + category
+	}
+
+	meta := feed.Meta{
+		Title:       title,
+		Description: "Новости, агрегированные InfoHub",
+		Link:        h.requestBaseURL(r) + "/",
+		FeedURL:     h.requestBaseURL(r) + r.URL.RequestURI(),
+	}
+
+	var buf bytes.Buffer
+	if err = feed.Render(&buf, format, meta, h.queryNews(query)); err != nil {
+		log.Printf("Error rendering %s feed: %v", format, err)
+		h.writeErrorResponse(w, "Failed to render feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// GetSourceFeed
+// @Summary      Получить ленту новостей источника
+// @Description  Возвращает новости одного источника в формате RSS 2.0, Atom 1.0 или JSON Feed 1.1
+// @Tags         feeds
+// @Produce      xml
+// @Produce      json
+// @Param        format  path      string  true   "Формат ленты"  Enums(rss, atom, jsonfeed)
+// @Param        source  path      string  true   "Название источника"
+// @Param        limit   query     int     false  "Количество новостей (по умолчанию 100)"  minimum(1)  maximum(1000)
+// @Success      200     {string}  string  "Лента новостей"
+// @Failure      400     {object}  ErrorResponse
+// @Failure      404     {object}  ErrorResponse
+// @Router       /feeds/{format}/sources/{source} [get]
+func (h *Handlers) GetSourceFeed(w http.ResponseWriter, r *http.Request) {
+	h.GetFeed(w, r)
+}
+
+// GetCategoryFeed
+// @Summary      Получить ленту новостей категории
+// @Description  Возвращает новости одной категории в формате RSS 2.0, Atom 1.0 или JSON Feed 1.1
+// @Tags         feeds
+// @Produce      xml
+// @Produce      json
+// @Param        format    path      string  true   "Формат ленты"  Enums(rss, atom, jsonfeed)
+// @Param        category  path      string  true   "Категория"
+// @Param        limit     query     int     false  "Количество новостей (по умолчанию 100)"  minimum(1)  maximum(1000)
+// @Success      200       {string}  string  "Лента новостей"
+// @Failure      400       {object}  ErrorResponse
+// @Failure      404       {object}  ErrorResponse
+// @Router       /feeds/{format}/categories/{category} [get]
+func (h *Handlers) GetCategoryFeed(w http.ResponseWriter, r *http.Request) {
+	h.GetFeed(w, r)
+}
+
+// SetTrustedProxies задает прокси, от которых лентам принимается
+// X-Forwarded-Proto для ссылок на саму ленту
+func (h *Handlers) SetTrustedProxies(trusted []netip.Prefix) {
+	h.trustedProxies = trusted
+}
+
+// requestBaseURL возвращает схему и хост, по которым пришел запрос.
+// X-Forwarded-Proto учитывается, только если его выставил доверенный прокси
+func (h *Handlers) requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if !middleware.IsTrustedProxy(r.RemoteAddr, h.trustedProxies) {
+		return scheme + "://" + r.Host
+	}
+	switch forwarded := strings.ToLower(r.Header.Get("X-Forwarded-Proto")); forwarded {
+	case "http", "https":
+		scheme = forwarded
+	}
+	return scheme + "://" + r.Host
+}
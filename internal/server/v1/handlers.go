@@ -2,13 +2,20 @@ package v1
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pah-an/infohub/internal/audit"
 	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/bulk"
+	"github.com/pah-an/infohub/internal/cache"
 	"github.com/pah-an/infohub/internal/domain"
 )
 
@@ -17,23 +24,39 @@ type NewsProvider interface {
 	GetLatestNews(limit int) domain.NewsList
 }
 
+const (
+	defaultNewsLimit = 100
+	maxNewsLimit     = 1000
+)
+
+// maxCacheKeys ограничивает количество ключей в ответе инспекции кэша
+const maxCacheKeys = 1000
+
 // Handlers содержит все обработчики для API v1
 type Handlers struct {
-	newsProvider NewsProvider
+	newsProvider   NewsProvider
+	newsRepository domain.NewsRepository
+	cacheStore     cache.Cache
+	responseCache  *ResponseCacheOptions
+	trustedProxies []netip.Prefix
 }
 
 // NewHandlers создает новый экземпляр обработчиков
-func NewHandlers(newsProvider NewsProvider) *Handlers {
+func NewHandlers(newsProvider NewsProvider, newsRepository domain.NewsRepository, cacheStore cache.Cache) *Handlers {
 	return &Handlers{
-		newsProvider: newsProvider,
+		newsProvider:   newsProvider,
+		newsRepository: newsRepository,
+		cacheStore:     cacheStore,
 	}
 }
 
 // NewsResponse представляет ответ с новостями
 type NewsResponse struct {
-	Count   int             `json:"count" example:"10"`
-	News    domain.NewsList `json:"news"`
-	Version string          `json:"version" example:"v1"`
+	Count int             `json:"count" example:"10"`
+	News  domain.NewsList `json:"news"`
+	// Total - общее количество совпадений при постраничной выборке из истории
+	Total   int    `json:"total,omitempty" example:"2500"`
+	Version string `json:"version" example:"v1"`
 }
 
 // HealthResponse представляет ответ healthcheck
@@ -53,14 +76,22 @@ type ErrorResponse struct {
 
 // GetNews
 // @Summary      Получить список новостей
-// @Description  Возвращает последние новости, агрегированные из всех источников
+// @Description  Возвращает последние новости, агрегированные из всех источников. Параметры offset, from или to включают постраничную выборку из хранилища с историей за пределами окна (для sqlite) и возвращают total
 // @Tags         news
 // @Accept       json
 // @Produce      json
-// @Param        limit    query     int  false  "Количество новостей (по умолчанию 100)"  minimum(1)  maximum(1000)
-// @Success      200      {object}  NewsResponse
-// @Failure      400      {object}  ErrorResponse
-// @Failure      500      {object}  ErrorResponse
+// @Param        limit     query     int     false  "Количество новостей (по умолчанию 100)"  minimum(1)  maximum(1000)
+// @Param        source    query     string  false  "Фильтр по источнику"
+// @Param        category  query     string  false  "Фильтр по категории"
+// @Param        offset    query     int     false  "Смещение от начала выборки"  minimum(0)
+// @Param        from      query     string  false  "Начало диапазона published_at (RFC3339)"
+// @Param        to        query     string  false  "Конец диапазона published_at (RFC3339)"
+// @Param        If-None-Match      header    string  false  "ETag ранее полученного ответа"
+// @Param        If-Modified-Since  header    string  false  "Время ранее полученного ответа"
+// @Success      200       {object}  NewsResponse
+// @Success      304       "Новости не изменились"
+// @Failure      400       {object}  ErrorResponse
+// @Failure      500       {object}  ErrorResponse
 // @Router       /news [get]
 func (h *Handlers) GetNews(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -68,18 +99,22 @@ func (h *Handlers) GetNews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Получаем лимит из query параметра, по умолчанию 100
-	limit := 100
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
-			limit = parsedLimit
-		} else {
-			h.writeErrorResponse(w, "Invalid limit parameter. Must be between 1 and 1000", http.StatusBadRequest)
-			return
-		}
+	query, err := parseNewsQuery(r)
+	if err != nil {
+		h.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if query.History {
+		h.writeNewsPage(w, query)
+		return
+	}
+
+	if h.writeCachedNews(w, r, query) {
+		return
 	}
 
-	news := h.newsProvider.GetLatestNews(limit)
+	news := h.queryNews(query)
 
 	response := NewsResponse{
 		Count:   len(news),
@@ -90,6 +125,103 @@ func (h *Handlers) GetNews(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, response, http.StatusOK)
 }
 
+// newsQuery содержит параметры выборки новостей
+type newsQuery struct {
+	Limit    int
+	Source   string
+	Category string
+
+	// History включается параметрами offset, from или to и означает
+	// постраничную выборку из хранилища вместо горячего окна
+	History bool
+	Offset  int
+	Range   bulk.TimeRange
+}
+
+// parseNewsQuery разбирает параметры выборки новостей из запроса
+func parseNewsQuery(r *http.Request) (newsQuery, error) {
+	// Получаем лимит из query параметра, по умолчанию 100
+	query := newsQuery{
+		Limit:    defaultNewsLimit,
+		Source:   r.URL.Query().Get("source"),
+		Category: r.URL.Query().Get("category"),
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 || parsedLimit > maxNewsLimit {
+			return query, fmt.Errorf("Invalid limit parameter. Must be between 1 and %d", maxNewsLimit)
+		}
+		query.Limit = parsedLimit
+	}
+
+	values := r.URL.Query()
+	query.History = values.Has("offset") || values.Has("from") || values.Has("to")
+	if offsetStr := values.Get("offset"); offsetStr != "" {
+		parsedOffset, err := strconv.Atoi(offsetStr)
+		if err != nil || parsedOffset < 0 {
+			return query, fmt.Errorf("Invalid offset parameter. Must be a non-negative integer")
+		}
+		query.Offset = parsedOffset
+	}
+
+	var err error
+	if query.Range, err = parseTimeRange(r); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+// writeNewsPage отправляет страницу выборки из хранилища. Хранилища без
+// поддержки запросов заменяются горячим окном с той же фильтрацией
+func (h *Handlers) writeNewsPage(w http.ResponseWriter, query newsQuery) {
+	filter := domain.NewsFilter{
+		Source:   query.Source,
+		Category: query.Category,
+		From:     query.Range.From,
+		To:       query.Range.To,
+		Limit:    query.Limit,
+		Offset:   query.Offset,
+	}
+
+	var news domain.NewsList
+	var total int
+	err := errors.ErrUnsupported
+	if querier, ok := h.newsRepository.(domain.NewsQuerier); ok {
+		news, total, err = querier.QueryNews(filter)
+	}
+	if errors.Is(err, errors.ErrUnsupported) {
+		news, total = h.newsProvider.GetLatestNews(maxNewsLimit).Query(filter)
+		err = nil
+	}
+	if err != nil {
+		log.Printf("Error querying news: %v", err)
+		h.writeErrorResponse(w, "Failed to query news", http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSONResponse(w, NewsResponse{Count: len(news), News: news, Total: total, Version: "v1"}, http.StatusOK)
+}
+
+// queryNews возвращает новости с учетом фильтров
+func (h *Handlers) queryNews(query newsQuery) domain.NewsList {
+	if query.Source == "" && query.Category == "" {
+		return h.newsProvider.GetLatestNews(query.Limit)
+	}
+
+	// При фильтрации берем полное окно, чтобы лимит применялся к отфильтрованному результату
+	news := h.newsProvider.GetLatestNews(maxNewsLimit)
+	if query.Source != "" {
+		news = news.FilterBySource(query.Source)
+	}
+	if query.Category != "" {
+		news = news.FilterByCategory(query.Category)
+	}
+
+	return news.LimitTo(query.Limit)
+}
+
 // GetHealth
 // @Summary      Проверка состояния сервиса
 // @Description  Возвращает статус работы сервиса
@@ -162,9 +294,25 @@ type AdminSourceResponse struct {
 type AdminClearCacheResponse struct {
 	Success   bool      `json:"success" example:"true"`
 	Message   string    `json:"message" example:"Cache cleared successfully"`
+	Pattern   string    `json:"pattern" example:"news:*"`
+	Deleted   int       `json:"deleted" example:"12"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// AdminCacheEntry представляет ключ кэша и, по запросу, его значение
+type AdminCacheEntry struct {
+	Key   string          `json:"key" example:"news:1700000000000000000:latest:100"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+}
+
+// AdminCacheKeysResponse представляет ответ инспекции кэша
+type AdminCacheKeysResponse struct {
+	Pattern   string            `json:"pattern" example:"news:*"`
+	Count     int               `json:"count" example:"12"`
+	Truncated bool              `json:"truncated" example:"false"`
+	Keys      []AdminCacheEntry `json:"keys"`
+}
+
 // LoginRequest представляет запрос на авторизацию
 type LoginRequest struct {
 	APIKey string `json:"api_key" example:"your-api-key"`
@@ -172,10 +320,24 @@ type LoginRequest struct {
 
 // LoginResponse представляет ответ на авторизацию
 type LoginResponse struct {
-	Token     string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
-	UserID    string `json:"user_id" example:"user123"`
-	IsAdmin   bool   `json:"is_admin" example:"false"`
-	ExpiresIn string `json:"expires_in" example:"24h"`
+	Token            string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken     string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType        string `json:"token_type" example:"Bearer"`
+	UserID           string `json:"user_id" example:"user123"`
+	IsAdmin          bool   `json:"is_admin" example:"false"`
+	ExpiresIn        string `json:"expires_in" example:"15m0s"`
+	RefreshExpiresIn string `json:"refresh_expires_in" example:"168h0m0s"`
+}
+
+// RefreshRequest представляет запрос на обновление токенов
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+// LogoutResponse представляет ответ на выход
+type LogoutResponse struct {
+	Success bool   `json:"success" example:"true"`
+	Message string `json:"message" example:"Tokens revoked"`
 }
 
 // ValidateTokenResponse представляет ответ на проверку токена
@@ -188,7 +350,7 @@ type ValidateTokenResponse struct {
 
 // GetAdminStats
 // @Summary      Получить статистику системы
-// @Description  Возвращает статистику работы системы (только для администраторов)
+// @Description  Возвращает статистику работы системы (область admin:stats)
 // @Tags         admin
 // @Accept       json
 // @Produce      json
@@ -216,7 +378,7 @@ func (h *Handlers) GetAdminStats(w http.ResponseWriter, r *http.Request) {
 
 // GetAdminSources
 // @Summary      Получить информацию об источниках
-// @Description  Возвращает информацию о всех источниках новостей (только для администраторов)
+// @Description  Возвращает информацию о всех источниках новостей (область sources:read)
 // @Tags         admin
 // @Accept       json
 // @Produce      json
@@ -251,31 +413,122 @@ func (h *Handlers) GetAdminSources(w http.ResponseWriter, r *http.Request) {
 
 // ClearAdminCache
 // @Summary      Очистить кэш
-// @Description  Очищает кэш новостей (только для администраторов)
+// @Description  Удаляет ключи кэша по glob паттерну (по умолчанию все ключи). Требует область admin:cache
 // @Tags         admin
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {object}  AdminClearCacheResponse
-// @Failure      401  {object}  ErrorResponse
-// @Failure      403  {object}  ErrorResponse
+// @Param        pattern  query     string  false  "Glob паттерн ключей"  default(*)
+// @Success      200      {object}  AdminClearCacheResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Failure      501      {object}  ErrorResponse
 // @Router       /admin/cache/clear [post]
 func (h *Handlers) ClearAdminCache(w http.ResponseWriter, r *http.Request) {
-	// TODO: реализовать логику очистки кэша
-	log.Println("Cache cleared by admin")
+	bulkCache, ok := h.cacheStore.(cache.BulkCache)
+	if !ok {
+		h.writeErrorResponse(w, "Cache does not support bulk operations", http.StatusNotImplemented)
+		return
+	}
+
+	pattern := r.URL.Query().Get("pattern")
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	deleted, err := bulkCache.DeleteByPattern(r.Context(), pattern)
+	if err != nil {
+		h.writeErrorResponse(w, fmt.Sprintf("Failed to clear cache: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Cache cleared by admin: pattern=%s deleted=%d", pattern, deleted)
 
 	response := AdminClearCacheResponse{
 		Success:   true,
 		Message:   "Cache cleared successfully",
+		Pattern:   pattern,
+		Deleted:   deleted,
 		Timestamp: time.Now().UTC(),
 	}
 
 	h.writeJSONResponse(w, response, http.StatusOK)
 }
 
+// GetAdminCacheKeys
+// @Summary      Просмотреть ключи кэша
+// @Description  Возвращает ключи кэша по glob паттерну и, при values=true, их значения (область admin:cache)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        pattern  query     string  false  "Glob паттерн ключей"  default(*)
+// @Param        values   query     bool    false  "Вернуть значения ключей"
+// @Param        limit    query     int     false  "Максимум ключей в ответе"  default(100)
+// @Success      200      {object}  AdminCacheKeysResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      500      {object}  ErrorResponse
+// @Failure      501      {object}  ErrorResponse
+// @Router       /admin/cache/keys [get]
+func (h *Handlers) GetAdminCacheKeys(w http.ResponseWriter, r *http.Request) {
+	bulkCache, ok := h.cacheStore.(cache.BulkCache)
+	if !ok {
+		h.writeErrorResponse(w, "Cache does not support bulk operations", http.StatusNotImplemented)
+		return
+	}
+
+	query := r.URL.Query()
+	pattern := query.Get("pattern")
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > maxCacheKeys {
+			h.writeErrorResponse(w, fmt.Sprintf("Invalid limit parameter (must be between 1 and %d)", maxCacheKeys), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	keys, err := bulkCache.Keys(r.Context(), pattern)
+	if err != nil {
+		h.writeErrorResponse(w, fmt.Sprintf("Failed to list cache keys: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := AdminCacheKeysResponse{
+		Pattern: pattern,
+		Count:   len(keys),
+		Keys:    make([]AdminCacheEntry, 0, len(keys)),
+	}
+	if len(keys) > limit {
+		keys = keys[:limit]
+		response.Truncated = true
+	}
+
+	var values map[string]json.RawMessage
+	if query.Get("values") == "true" {
+		if values, err = bulkCache.MGet(r.Context(), keys); err != nil {
+			h.writeErrorResponse(w, fmt.Sprintf("Failed to read cache values: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, key := range keys {
+		response.Keys = append(response.Keys, AdminCacheEntry{Key: key, Value: values[key]})
+	}
+
+	h.writeJSONResponse(w, response, http.StatusOK)
+}
+
 // PostLogin
 // @Summary      Авторизация пользователя
-// @Description  Авторизация по API ключу и получение JWT токена
+// @Description  Авторизация по API ключу. Возвращает короткоживущий access токен и refresh токен для его обновления
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -293,26 +546,119 @@ func (h *Handlers) PostLogin(authManager *auth.Manager) http.HandlerFunc {
 			return
 		}
 
+		audit.Mark(r.Context(), "auth.login", "", "")
 		user, err := authManager.ValidateAPIKey(loginRequest.APIKey)
 		if err != nil {
+			audit.SetOutcome(r.Context(), audit.OutcomeDenied, err.Error())
 			h.writeErrorResponse(w, "Invalid API key", http.StatusUnauthorized)
 			return
 		}
+		audit.Mark(r.Context(), "", user.ID, "")
 
-		token, err := authManager.GenerateJWT(user)
+		tokens, err := authManager.IssueTokens(user)
 		if err != nil {
 			h.writeErrorResponse(w, "Failed to generate token", http.StatusInternalServerError)
 			return
 		}
 
-		response := LoginResponse{
-			Token:     token,
-			UserID:    user.ID,
-			IsAdmin:   user.IsAdmin,
-			ExpiresIn: "24h",
+		h.writeJSONResponse(w, newLoginResponse(user, tokens), http.StatusOK)
+	}
+}
+
+// PostRefresh
+// @Summary      Обновить токены
+// @Description  Обменивает refresh токен на новую пару токенов. Refresh токен одноразовый: повторное использование отзывает всю сессию
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      RefreshRequest  true  "Refresh токен"
+// @Success      200      {object}  LoginResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Router       /refresh [post]
+func (h *Handlers) PostRefresh(authManager *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+			h.writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		audit.Mark(r.Context(), "auth.refresh", "", "")
+		tokens, user, err := authManager.Refresh(r.Context(), request.RefreshToken)
+		if err != nil {
+			if errors.Is(err, auth.ErrRefreshTokenUsed) {
+				log.Printf("Refresh token reuse detected, session revoked")
+			}
+			audit.SetOutcome(r.Context(), audit.OutcomeDenied, err.Error())
+			h.writeErrorResponse(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
 		}
+		audit.Mark(r.Context(), "", user.ID, "")
 
-		h.writeJSONResponse(w, response, http.StatusOK)
+		h.writeJSONResponse(w, newLoginResponse(user, tokens), http.StatusOK)
+	}
+}
+
+// PostLogout
+// @Summary      Выйти
+// @Description  Отзывает access токен из заголовка Authorization и, если передан, refresh токен вместе со всей сессией
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      RefreshRequest  false  "Refresh токен"
+// @Success      200      {object}  LogoutResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Router       /logout [post]
+func (h *Handlers) PostLogout(authManager *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request RefreshRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				h.writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		audit.Mark(r.Context(), "auth.logout", "", "")
+		tokens := make([]string, 0, 2)
+		if accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if user, err := authManager.ValidateJWT(accessToken); err == nil {
+				audit.Mark(r.Context(), "", user.ID, "")
+			}
+			tokens = append(tokens, accessToken)
+		}
+		if request.RefreshToken != "" {
+			tokens = append(tokens, request.RefreshToken)
+		}
+		if len(tokens) == 0 {
+			h.writeErrorResponse(w, "No token to revoke", http.StatusBadRequest)
+			return
+		}
+
+		for _, token := range tokens {
+			if err := authManager.RevokeToken(r.Context(), token); err != nil {
+				h.writeErrorResponse(w, "Invalid token", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		h.writeJSONResponse(w, LogoutResponse{Success: true, Message: "Tokens revoked"}, http.StatusOK)
+	}
+}
+
+// newLoginResponse формирует ответ с выпущенными токенами
+func newLoginResponse(user *auth.User, tokens auth.TokenPair) LoginResponse {
+	return LoginResponse{
+		Token:            tokens.AccessToken,
+		RefreshToken:     tokens.RefreshToken,
+		TokenType:        "Bearer",
+		UserID:           user.ID,
+		IsAdmin:          user.IsAdmin,
+		ExpiresIn:        tokens.AccessExpiresIn.String(),
+		RefreshExpiresIn: tokens.RefreshExpiresIn.String(),
 	}
 }
 
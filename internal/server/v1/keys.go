@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/pah-an/infohub/internal/auth"
+)
+
+// CreateAPIKeyRequest представляет запрос на выпуск API ключа
+type CreateAPIKeyRequest struct {
+	Description string `json:"description" example:"Mobile app"`
+	Owner       string `json:"owner" example:"team-mobile"`
+	// Scopes - области доступа ключа; пустой список - области по умолчанию
+	Scopes []string `json:"scopes,omitempty" example:"news:read,admin:cache"`
+	// ExpiresIn - срок действия ключа в формате Go duration; пустое значение - бессрочный ключ
+	ExpiresIn string `json:"expires_in,omitempty" example:"720h"`
+}
+
+// CreateAPIKeyResponse содержит выпущенный ключ. Полный ключ показывается только один раз
+type CreateAPIKeyResponse struct {
+	Key string `json:"key" example:"infohub_3f9a1c0b7d2e_5b0c..."`
+	auth.APIKeyInfo
+}
+
+// APIKeysResponse представляет список выпущенных ключей
+type APIKeysResponse struct {
+	Count int               `json:"count" example:"2"`
+	Keys  []auth.APIKeyInfo `json:"keys"`
+}
+
+// PostAdminKey
+// @Summary      Выпустить API ключ
+// @Description  Создает новый API ключ. Полный ключ возвращается только в этом ответе, в хранилище сохраняется его хэш (область admin:keys)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      CreateAPIKeyRequest  true  "Параметры ключа"
+// @Success      201      {object}  CreateAPIKeyResponse
+// @Failure      400      {object}  ErrorResponse
+// @Failure      401      {object}  ErrorResponse
+// @Failure      403      {object}  ErrorResponse
+// @Failure      501      {object}  ErrorResponse
+// @Router       /admin/keys [post]
+func (h *Handlers) PostAdminKey(authManager *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			h.writeErrorResponse(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		params := auth.APIKeyRequest{Description: request.Description, Owner: request.Owner, Scopes: request.Scopes}
+		if request.ExpiresIn != "" {
+			ttl, err := time.ParseDuration(request.ExpiresIn)
+			if err != nil || ttl <= 0 {
+				h.writeErrorResponse(w, "Invalid expires_in parameter", http.StatusBadRequest)
+				return
+			}
+			expiresAt := time.Now().UTC().Add(ttl)
+			params.ExpiresAt = &expiresAt
+		}
+
+		key, info, err := authManager.CreateAPIKey(params)
+		if err != nil {
+			h.writeKeyStoreError(w, err)
+			return
+		}
+		log.Printf("API key issued: id=%s owner=%s by=%s", info.ID, info.Owner, r.Header.Get("X-User-ID"))
+
+		h.writeJSONResponse(w, CreateAPIKeyResponse{Key: key, APIKeyInfo: info}, http.StatusCreated)
+	}
+}
+
+// GetAdminKeys
+// @Summary      Список API ключей
+// @Description  Возвращает метаданные выпущенных API ключей, включая отозванные (область admin:keys)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  APIKeysResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Router       /admin/keys [get]
+func (h *Handlers) GetAdminKeys(authManager *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := authManager.ListAPIKeys()
+		if err != nil {
+			h.writeKeyStoreError(w, err)
+			return
+		}
+
+		h.writeJSONResponse(w, APIKeysResponse{Count: len(keys), Keys: keys}, http.StatusOK)
+	}
+}
+
+// DeleteAdminKey
+// @Summary      Отозвать API ключ
+// @Description  Немедленно отзывает API ключ. Метаданные ключа сохраняются (область admin:keys)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Идентификатор ключа"
+// @Success      200  {object}  auth.APIKeyInfo
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Router       /admin/keys/{id} [delete]
+func (h *Handlers) DeleteAdminKey(authManager *auth.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := authManager.RevokeAPIKey(mux.Vars(r)["id"])
+		if err != nil {
+			h.writeKeyStoreError(w, err)
+			return
+		}
+		log.Printf("API key revoked: id=%s by=%s", info.ID, r.Header.Get("X-User-ID"))
+
+		h.writeJSONResponse(w, info, http.StatusOK)
+	}
+}
+
+// writeKeyStoreError отображает ошибки хранилища ключей в HTTP статусы
+func (h *Handlers) writeKeyStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrKeyStoreDisabled):
+		h.writeErrorResponse(w, "API key store is not configured", http.StatusNotImplemented)
+	case errors.Is(err, auth.ErrInvalidScope):
+		h.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, auth.ErrKeyNotFound):
+		h.writeErrorResponse(w, "API key not found", http.StatusNotFound)
+	default:
+		log.Printf("API key store error: %v", err)
+		h.writeErrorResponse(w, "API key store error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/usage"
+)
+
+// UsageListResponse представляет использование API всеми потребителями
+type UsageListResponse struct {
+	Count int           `json:"count" example:"2"`
+	Usage []usage.Usage `json:"usage"`
+}
+
+// GetAdminUsage
+// @Summary      Использование API по ключам
+// @Description  Возвращает число запросов и объем ответов за текущие сутки и месяц по каждому ключу и пользователю вместе с их квотами (область admin:usage)
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        consumer  query     string  false  "Идентификатор ключа или пользователя"
+// @Success      200       {object}  UsageListResponse
+// @Failure      401       {object}  ErrorResponse
+// @Failure      403       {object}  ErrorResponse
+// @Failure      501       {object}  ErrorResponse
+// @Router       /admin/usage [get]
+func (h *Handlers) GetAdminUsage(tracker *usage.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tracker == nil {
+			h.writeErrorResponse(w, "Usage accounting is not enabled", http.StatusNotImplemented)
+			return
+		}
+
+		var list []usage.Usage
+		if consumer := r.URL.Query().Get("consumer"); consumer != "" {
+			list = []usage.Usage{tracker.Get(consumer)}
+		} else {
+			list = tracker.List()
+		}
+
+		h.writeJSONResponse(w, UsageListResponse{Count: len(list), Usage: list}, http.StatusOK)
+	}
+}
+
+// GetMyUsage
+// @Summary      Собственное использование API
+// @Description  Возвращает число запросов и объем ответов текущего ключа за сутки и месяц и его квоты. Запрос не расходует квоту
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  usage.Usage
+// @Failure      401  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Router       /me/usage [get]
+func (h *Handlers) GetMyUsage(tracker *usage.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if tracker == nil {
+			h.writeErrorResponse(w, "Usage accounting is not enabled", http.StatusNotImplemented)
+			return
+		}
+
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			h.writeErrorResponse(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		h.writeJSONResponse(w, tracker.Get(user.UsageID()), http.StatusOK)
+	}
+}
@@ -1,48 +1,253 @@
 package storage
 
 import (
-	"encoding/json"
+	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/pah-an/infohub/internal/domain"
 )
 
-// FileCache реализует кэширование новостей в файл
+// FileConfig содержит настройки файлового хранилища
+type FileConfig struct {
+	Path        string
+	Backups     int    // количество хранимых предыдущих снимков
+	Compression string // "none", "gzip" или "zstd"
+}
+
+// FileCache реализует кэширование новостей в файл.
+// Запись атомарна: снимок пишется во временный файл, сбрасывается на диск
+// и переименовывается поверх основного. Перед заменой предыдущий снимок
+// сохраняется в ротируемые резервные копии <file>.1 ... <file>.N
 type FileCache struct {
-	filePath string
+	filePath    string
+	backups     int
+	compression string
+	mutex       sync.Mutex
 }
 
 // NewFileCache создает новый файловый кэш
-func NewFileCache(filePath string) *FileCache {
-	return &FileCache{
-		filePath: filePath,
+func NewFileCache(config FileConfig) (*FileCache, error) {
+	if config.Backups < 0 {
+		config.Backups = 0
+	}
+	if config.Compression == "" {
+		config.Compression = CompressionNone
+	}
+	if err := ValidateCompression(config.Compression); err != nil {
+		return nil, err
 	}
+
+	return &FileCache{
+		filePath:    config.Path,
+		backups:     config.Backups,
+		compression: config.Compression,
+	}, nil
 }
 
 // SaveNews сохраняет новости в файл
 func (f *FileCache) SaveNews(news domain.NewsList) error {
-	data, err := json.MarshalIndent(news, "", "  ")
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.save(news)
+}
+
+// GetLatestNews загружает новости из файла
+func (f *FileCache) GetLatestNews(limit int) (domain.NewsList, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	news, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return news.LimitTo(limit), nil
+}
+
+// UpsertNews добавляет или обновляет новости, сохраняя остальные записи файла.
+// Снимок не поддерживает дозапись, поэтому каждый вызов с изменениями читает
+// и переписывает файл целиком: стоимость растет с размером хранилища, а не
+// пачки. Агрегатор вызывает его на каждое поступление новостей, поэтому для
+// архива на десятки тысяч записей лучше подходят бэкенды wal или sqlite.
+// Если все новости уже сохранены без изменений, файл не переписывается
+func (f *FileCache) UpsertNews(news domain.NewsList) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	existing, err := f.load()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(f.filePath, data, 0644)
+	current := make(map[string]domain.News, len(existing))
+	for _, item := range existing {
+		current[item.ID] = item
+	}
+	changed := false
+	for _, item := range news {
+		if stored, ok := current[item.ID]; !ok || !newsEqual(stored, item) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return f.save(existing.Merge(news))
 }
 
-// GetLatestNews загружает новости из файла
-func (f *FileCache) GetLatestNews(limit int) (domain.NewsList, error) {
-	data, err := os.ReadFile(f.filePath)
+// DeleteNews удаляет новости с указанными ID. Файл перезаписывается, только
+// если что-то было удалено
+func (f *FileCache) DeleteNews(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	existing, err := f.load()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return domain.NewsList{}, nil
+		return err
+	}
+
+	deleted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		deleted[id] = struct{}{}
+	}
+
+	kept := make(domain.NewsList, 0, len(existing))
+	for _, item := range existing {
+		if _, ok := deleted[item.ID]; !ok {
+			kept = append(kept, item)
 		}
-		return nil, err
+	}
+	if len(kept) == len(existing) {
+		return nil
+	}
+
+	return f.save(kept)
+}
+
+// save атомарно записывает снимок и ротирует резервные копии
+func (f *FileCache) save(news domain.NewsList) error {
+	data, err := encodeSnapshot(news, f.compression, time.Now())
+	if err != nil {
+		return err
 	}
 
-	var news domain.NewsList
-	if err = json.Unmarshal(data, &news); err != nil {
+	dir := filepath.Dir(f.filePath)
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.filePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err = f.rotateBackups(); err != nil {
+		log.Printf("Failed to rotate news cache backups: %v", err)
+	}
+
+	if err = os.Rename(tmpPath, f.filePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	return syncDir(dir)
+}
+
+// rotateBackups сдвигает резервные копии и сохраняет текущий снимок как <file>.1.
+// Основной файл при этом не удаляется, поэтому он существует на протяжении всей записи
+func (f *FileCache) rotateBackups() error {
+	if f.backups == 0 {
+		return nil
+	}
+
+	if _, err := os.Stat(f.filePath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if err := os.Remove(f.backupPath(f.backups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := f.backups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backupPath(i), f.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// Жесткая ссылка не копирует данные; если ФС их не поддерживает, копируем
+	if err := os.Link(f.filePath, f.backupPath(1)); err != nil {
+		data, readErr := os.ReadFile(f.filePath)
+		if readErr != nil {
+			return readErr
+		}
+		return os.WriteFile(f.backupPath(1), data, 0644)
+	}
+
+	return nil
+}
+
+// load читает основной снимок, а если он отсутствует или поврежден -
+// самую свежую корректную резервную копию
+func (f *FileCache) load() (domain.NewsList, error) {
+	news, err := readSnapshot(f.filePath)
+	if err == nil {
+		return news, nil
+	}
+
+	primaryMissing := os.IsNotExist(err)
+	for i := 1; i <= f.backups; i++ {
+		backup, backupErr := readSnapshot(f.backupPath(i))
+		if backupErr != nil {
+			continue
+		}
+		if !primaryMissing {
+			log.Printf("News cache %s is unreadable (%v), restored from backup %s", f.filePath, err, f.backupPath(i))
+		}
+		return backup, nil
+	}
+
+	if primaryMissing {
+		return domain.NewsList{}, nil
+	}
+	return nil, err
+}
+
+func (f *FileCache) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", f.filePath, n)
+}
+
+// readSnapshot читает и декодирует файл снимка
+func readSnapshot(path string) (domain.NewsList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
 		return nil, err
 	}
 
-	return news.LimitTo(limit), nil
+	news, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return news, nil
 }
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/pah-an/infohub/internal/domain"
+)
+
+// Типы сжатия снимков
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// snapshotFormat идентифицирует файлы снимков
+const snapshotFormat = "infohub-snapshot"
+
+// snapshotVersion - текущая версия формата содержимого снимка.
+// Версия 1 - исходный формат: JSON массив новостей без заголовка
+const snapshotVersion = 2
+
+// snapshotMigrations переводят содержимое снимка версии N в версию N+1.
+// При изменении domain.News нужно увеличить snapshotVersion и добавить миграцию
+var snapshotMigrations = map[int]func(payload []byte) ([]byte, error){
+	// Версия 2 добавила только заголовок, содержимое не изменилось
+	1: func(payload []byte) ([]byte, error) { return payload, nil },
+}
+
+// snapshotHeader описывает содержимое снимка. Хранится первой строкой файла,
+// за ней следует (возможно сжатое) JSON содержимое
+type snapshotHeader struct {
+	Format      string    `json:"format"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	Count       int       `json:"count"`
+	Compression string    `json:"compression"`
+	Checksum    string    `json:"checksum"` // SHA-256 несжатого содержимого
+}
+
+// ValidateCompression проверяет тип сжатия
+func ValidateCompression(compression string) error {
+	switch compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+		return nil
+	default:
+		return fmt.Errorf("unsupported snapshot compression: %s", compression)
+	}
+}
+
+// encodeSnapshot сериализует новости в снимок
+func encodeSnapshot(news domain.NewsList, compression string, now time.Time) ([]byte, error) {
+	if compression == "" {
+		compression = CompressionNone
+	}
+
+	payload, err := json.Marshal(news)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(payload)
+
+	header, err := json.Marshal(snapshotHeader{
+		Format:      snapshotFormat,
+		Version:     snapshotVersion,
+		CreatedAt:   now.UTC(),
+		Count:       len(news),
+		Compression: compression,
+		Checksum:    hex.EncodeToString(sum[:]),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+
+	if err = compress(&buf, payload, compression); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeSnapshot разбирает снимок любой поддерживаемой версии
+func decodeSnapshot(data []byte) (domain.NewsList, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return decodePayload(trimmed, 1, -1)
+	}
+
+	line, body, found := bytes.Cut(data, []byte{'\n'})
+	if !found {
+		return nil, fmt.Errorf("snapshot header is truncated")
+	}
+
+	var header snapshotHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("invalid snapshot header: %w", err)
+	}
+	if header.Format != snapshotFormat {
+		return nil, fmt.Errorf("unknown snapshot format: %q", header.Format)
+	}
+	if header.Version < 1 || header.Version > snapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is not supported (current %d)", header.Version, snapshotVersion)
+	}
+
+	payload, err := decompress(body, header.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(payload)
+	if hex.EncodeToString(sum[:]) != header.Checksum {
+		return nil, fmt.Errorf("snapshot checksum mismatch")
+	}
+
+	return decodePayload(payload, header.Version, header.Count)
+}
+
+// decodePayload мигрирует содержимое до текущей версии и декодирует его.
+// Отрицательный count отключает проверку количества записей
+func decodePayload(payload []byte, version, count int) (domain.NewsList, error) {
+	for ; version < snapshotVersion; version++ {
+		migrate, ok := snapshotMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from snapshot version %d", version)
+		}
+
+		var err error
+		if payload, err = migrate(payload); err != nil {
+			return nil, fmt.Errorf("snapshot migration from version %d failed: %w", version, err)
+		}
+	}
+
+	var news domain.NewsList
+	if err := json.Unmarshal(payload, &news); err != nil {
+		return nil, err
+	}
+	if count >= 0 && len(news) != count {
+		return nil, fmt.Errorf("snapshot contains %d items, header declares %d", len(news), count)
+	}
+
+	return news, nil
+}
+
+func compress(w io.Writer, payload []byte, compression string) error {
+	switch compression {
+	case CompressionNone:
+		_, err := w.Write(payload)
+		return err
+	case CompressionGzip:
+		gz := gzip.NewWriter(w)
+		if _, err := gz.Write(payload); err != nil {
+			return err
+		}
+		return gz.Close()
+	case CompressionZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		if _, err = zw.Write(payload); err != nil {
+			zw.Close()
+			return err
+		}
+		return zw.Close()
+	default:
+		return fmt.Errorf("unsupported snapshot compression: %s", compression)
+	}
+}
+
+func decompress(body []byte, compression string) ([]byte, error) {
+	switch compression {
+	case CompressionNone, "":
+		return body, nil
+	case CompressionGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip snapshot: %w", err)
+		}
+		defer gz.Close()
+		return io.ReadAll(gz)
+	case CompressionZstd:
+		zr, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return zr.DecodeAll(body, nil)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot compression: %s", compression)
+	}
+}
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/pah-an/infohub/internal/domain"
+)
+
+// SQLConfig содержит настройки SQLite хранилища
+type SQLConfig struct {
+	Path        string        `yaml:"path" json:"path"`
+	BusyTimeout time.Duration `yaml:"busy_timeout" json:"busy_timeout"`
+}
+
+// sqlMigrations содержит миграции схемы. Номер версии равен индексу + 1;
+// уже примененные миграции менять нельзя, только добавлять новые
+var sqlMigrations = []string{
+	`CREATE TABLE news (
+		id           TEXT PRIMARY KEY,
+		title        TEXT NOT NULL,
+		description  TEXT NOT NULL DEFAULT '',
+		url          TEXT NOT NULL DEFAULT '',
+		source       TEXT NOT NULL DEFAULT '' COLLATE NOCASE,
+		category     TEXT NOT NULL DEFAULT '' COLLATE NOCASE,
+		published_at INTEGER NOT NULL
+	);
+	CREATE INDEX idx_news_published_at ON news (published_at DESC);
+	CREATE INDEX idx_news_source ON news (source, published_at DESC);
+	CREATE INDEX idx_news_category ON news (category, published_at DESC);
+	CREATE INDEX idx_news_url ON news (url);`,
+}
+
+// SQLStore реализует domain.NewsRepository поверх встроенной SQLite базы.
+// В отличие от файлового хранилища SaveNews не удаляет отсутствующие в списке
+// новости, поэтому база накапливает историю за пределами окна агрегатора
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore открывает базу и применяет недостающие миграции
+func NewSQLStore(config SQLConfig) (*SQLStore, error) {
+	if config.Path == "" {
+		config.Path = "data/infohub.db"
+	}
+	if config.BusyTimeout == 0 {
+		config.BusyTimeout = 5 * time.Second
+	}
+
+	if dir := filepath.Dir(config.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	// Путь экранируется как в file: URI, иначе "?" или "#" в нем были бы
+	// приняты за начало параметров
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)",
+		(&url.URL{Path: config.Path}).EscapedPath(), config.BusyTimeout.Milliseconds())
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite допускает только одного писателя
+	db.SetMaxOpenConns(1)
+
+	store := &SQLStore{db: db}
+	if err = store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// migrate применяет миграции, которые еще не были применены
+func (s *SQLStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	if current > len(sqlMigrations) {
+		return fmt.Errorf("database schema version %d is newer than supported %d", current, len(sqlMigrations))
+	}
+
+	for version := current + 1; version <= len(sqlMigrations); version++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(sqlMigrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %w", version, err)
+		}
+		if _, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			version, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// SaveNews добавляет или обновляет новости в одной транзакции
+func (s *SQLStore) SaveNews(news domain.NewsList) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO news (id, title, description, url, source, category, published_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			title = excluded.title,
+			description = excluded.description,
+			url = excluded.url,
+			source = excluded.source,
+			category = excluded.category,
+			published_at = excluded.published_at`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, item := range news {
+		if _, err = stmt.Exec(item.ID, item.Title, item.Description, item.URL,
+			item.Source, item.Category, unixNanos(item.PublishedAt)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to save news %s: %w", item.ID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpsertNews совпадает с SaveNews: база всегда хранит историю
+func (s *SQLStore) UpsertNews(news domain.NewsList) error {
+	return s.SaveNews(news)
+}
+
+// GetLatestNews возвращает последние новости
+func (s *SQLStore) GetLatestNews(limit int) (domain.NewsList, error) {
+	news, _, err := s.QueryNews(domain.NewsFilter{Limit: limit})
+	return news, err
+}
+
+// QueryNews возвращает страницу новостей по фильтру и общее количество совпадений
+func (s *SQLStore) QueryNews(filter domain.NewsFilter) (domain.NewsList, int, error) {
+	where, args := buildNewsWhere(filter)
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM news`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count news: %w", err)
+	}
+
+	query := `SELECT id, title, description, url, source, category, published_at FROM news` +
+		where + ` ORDER BY published_at DESC, id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit, filter.Offset)
+	} else if filter.Offset > 0 {
+		query += ` LIMIT -1 OFFSET ?`
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query news: %w", err)
+	}
+	defer rows.Close()
+
+	news := make(domain.NewsList, 0)
+	for rows.Next() {
+		var item domain.News
+		var publishedAt int64
+		if err = rows.Scan(&item.ID, &item.Title, &item.Description, &item.URL,
+			&item.Source, &item.Category, &publishedAt); err != nil {
+			return nil, 0, err
+		}
+		if publishedAt != 0 {
+			item.PublishedAt = time.Unix(0, publishedAt).UTC()
+		}
+		news = append(news, item)
+	}
+
+	return news, total, rows.Err()
+}
+
+// DeleteNewsBefore удаляет новости, опубликованные раньше указанного времени
+func (s *SQLStore) DeleteNewsBefore(t time.Time) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM news WHERE published_at < ?`, unixNanos(t))
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+// Ping проверяет доступность базы
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close закрывает базу
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// buildNewsWhere формирует условие WHERE по фильтру
+func buildNewsWhere(filter domain.NewsFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Source != "" {
+		conditions = append(conditions, "source = ?")
+		args = append(args, filter.Source)
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "category = ?")
+		args = append(args, filter.Category)
+	}
+	if filter.URL != "" {
+		conditions = append(conditions, "url = ?")
+		args = append(args, filter.URL)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "published_at >= ?")
+		args = append(args, filter.From.UnixNano())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "published_at <= ?")
+		args = append(args, filter.To.UnixNano())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// unixNanos переводит время в значение столбца published_at. Нулевое время
+// (дата публикации неизвестна) хранится как 0: UnixNano для него не
+// помещается в int64
+func unixNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
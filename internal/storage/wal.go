@@ -0,0 +1,710 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pah-an/infohub/internal/domain"
+)
+
+// Политики синхронизации журнала с диском
+const (
+	SyncAlways   = "always"   // fsync после каждой записи
+	SyncInterval = "interval" // fsync в фоне с периодом SyncInterval
+	SyncNever    = "never"    // сброс на диск остается на усмотрение ОС
+)
+
+const (
+	walSegmentPrefix = "wal-"
+	walSegmentSuffix = ".log"
+	walCompactSuffix = ".compact"
+
+	// walHeaderSize - длина payload (4 байта) + CRC32C payload (4 байта)
+	walHeaderSize = 8
+	// walMaxRecordSize защищает от чтения мусорной длины при повреждении
+	walMaxRecordSize = 16 << 20
+
+	walOpPut    = "put"
+	walOpDelete = "delete"
+	// walOpSnapshot открывает уплотненный сегмент: следующие за ней записи
+	// содержат полное состояние, а более старые сегменты устарели
+	walOpSnapshot = "snapshot"
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errWALCorrupted означает, что запись журнала не прошла проверку
+var errWALCorrupted = errors.New("corrupted WAL record")
+
+// WALConfig содержит настройки журнального хранилища
+type WALConfig struct {
+	Dir             string        `yaml:"dir" json:"dir"`
+	SegmentSize     int64         `yaml:"segment_size" json:"segment_size"`
+	SyncPolicy      string        `yaml:"sync_policy" json:"sync_policy"`
+	SyncInterval    time.Duration `yaml:"sync_interval" json:"sync_interval"`
+	CompactInterval time.Duration `yaml:"compact_interval" json:"compact_interval"`
+
+	// OpenSegment открывает сегмент на дозапись, по умолчанию os.OpenFile.
+	// Позволяет подменить файл в тестах
+	OpenSegment func(path string) (SegmentFile, error) `yaml:"-" json:"-"`
+}
+
+// SegmentFile - активный сегмент журнала, открытый на дозапись
+type SegmentFile interface {
+	io.WriteCloser
+	Sync() error
+	Stat() (os.FileInfo, error)
+	Truncate(size int64) error
+}
+
+// walRecord представляет одну запись журнала
+type walRecord struct {
+	Op   string       `json:"op"`
+	ID   string       `json:"id"`
+	News *domain.News `json:"news,omitempty"`
+}
+
+// WAL реализует domain.NewsRepository в виде append-only журнала из сегментов.
+// Каждое изменение дописывается в активный сегмент как отдельная запись с
+// контрольной суммой; при старте журнал воспроизводится, а оборванная запись
+// в конце последнего сегмента отбрасывается. Периодическое уплотнение
+// переписывает актуальное состояние в новый сегмент и удаляет старые
+type WAL struct {
+	config WALConfig
+
+	mutex       sync.Mutex
+	news        map[string]domain.News
+	segments    []uint64
+	active      SegmentFile
+	activeSize  int64
+	failed      error // ошибка, после которой журнал не принимает записи
+	dirty       bool
+	logRecords  int // записей во всех сегментах
+	liveRecords int // актуальных новостей
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewWAL открывает журнальное хранилище и восстанавливает состояние с диска
+func NewWAL(config WALConfig) (*WAL, error) {
+	if config.Dir == "" {
+		config.Dir = "data/wal"
+	}
+	if config.SegmentSize == 0 {
+		config.SegmentSize = 16 << 20
+	}
+	if config.SyncPolicy == "" {
+		config.SyncPolicy = SyncAlways
+	}
+	if config.SyncInterval == 0 {
+		config.SyncInterval = time.Second
+	}
+	if config.CompactInterval == 0 {
+		config.CompactInterval = 10 * time.Minute
+	}
+	if config.OpenSegment == nil {
+		config.OpenSegment = func(path string) (SegmentFile, error) {
+			return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		}
+	}
+
+	switch config.SyncPolicy {
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return nil, fmt.Errorf("unknown WAL sync policy: %s", config.SyncPolicy)
+	}
+
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+
+	w := &WAL{
+		config: config,
+		news:   make(map[string]domain.News),
+		done:   make(chan struct{}),
+	}
+
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+
+	if config.SyncPolicy == SyncInterval {
+		w.wg.Add(1)
+		go w.runPeriodic(config.SyncInterval, w.syncIfDirty)
+	}
+
+	w.wg.Add(1)
+	go w.runPeriodic(config.CompactInterval, func() {
+		if err := w.Compact(); err != nil {
+			log.Printf("WAL compaction failed: %v", err)
+		}
+	})
+
+	return w, nil
+}
+
+// SaveNews записывает в журнал разницу между текущим состоянием и news
+func (w *WAL) SaveNews(news domain.NewsList) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	incoming := make(map[string]struct{}, len(news))
+	var records []walRecord
+
+	for i := range news {
+		item := news[i]
+		incoming[item.ID] = struct{}{}
+		if current, ok := w.news[item.ID]; ok && newsEqual(current, item) {
+			continue
+		}
+		records = append(records, walRecord{Op: walOpPut, ID: item.ID, News: &item})
+	}
+
+	var deleted []string
+	for id := range w.news {
+		if _, ok := incoming[id]; !ok {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Strings(deleted)
+	for _, id := range deleted {
+		records = append(records, walRecord{Op: walOpDelete, ID: id})
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := w.appendRecords(records); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		w.apply(record)
+	}
+
+	return nil
+}
+
+// UpsertNews записывает в журнал только новые и измененные новости
+func (w *WAL) UpsertNews(news domain.NewsList) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var records []walRecord
+	for i := range news {
+		item := news[i]
+		if current, ok := w.news[item.ID]; ok && newsEqual(current, item) {
+			continue
+		}
+		records = append(records, walRecord{Op: walOpPut, ID: item.ID, News: &item})
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := w.appendRecords(records); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		w.apply(record)
+	}
+
+	return nil
+}
+
+// DeleteNews записывает в журнал удаление присутствующих новостей с указанными ID
+func (w *WAL) DeleteNews(ids []string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var records []walRecord
+	for _, id := range ids {
+		if _, ok := w.news[id]; ok {
+			records = append(records, walRecord{Op: walOpDelete, ID: id})
+		}
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := w.appendRecords(records); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		w.apply(record)
+	}
+
+	return nil
+}
+
+// GetLatestNews возвращает новости из журнала, отсортированные по дате
+func (w *WAL) GetLatestNews(limit int) (domain.NewsList, error) {
+	w.mutex.Lock()
+	news := make(domain.NewsList, 0, len(w.news))
+	for _, item := range w.news {
+		news = append(news, item)
+	}
+	w.mutex.Unlock()
+
+	return news.SortByDate().LimitTo(limit), nil
+}
+
+// Compact переписывает актуальное состояние в новый сегмент и удаляет старые
+func (w *WAL) Compact() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.active == nil {
+		return fmt.Errorf("WAL is closed")
+	}
+
+	// Уплотнение бессмысленно, если журнал почти не содержит устаревших
+	// записей. Сразу после уплотнения logRecords равен liveRecords, поэтому
+	// состояние не переписывается повторно, пока не накопятся изменения
+	if w.logRecords <= 2*w.liveRecords {
+		return nil
+	}
+
+	if err := w.syncActive(); err != nil {
+		return err
+	}
+
+	seq := w.segments[len(w.segments)-1] + 1
+	finalPath := w.segmentPath(seq)
+	tmpPath := finalPath + walCompactSuffix
+
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted segment: %w", err)
+	}
+
+	ids := make([]string, 0, len(w.news))
+	for id := range w.news {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	bw := bufio.NewWriter(file)
+	if _, err = writeRecord(bw, walRecord{Op: walOpSnapshot}); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	for _, id := range ids {
+		item := w.news[id]
+		if _, err = writeRecord(bw, walRecord{Op: walOpPut, ID: id, News: &item}); err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	if err = bw.Flush(); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write compacted segment: %w", err)
+	}
+
+	if err = os.Rename(tmpPath, finalPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to install compacted segment: %w", err)
+	}
+	if err = syncDir(w.config.Dir); err != nil {
+		return err
+	}
+
+	// С этого момента уплотненный сегмент содержит полное состояние. Старые
+	// сегменты, которые не удалось удалить, не воспроизводятся: восстановление
+	// начинается с последнего сегмента с отметкой снимка и удаляет более ранние
+	if err = w.active.Close(); err != nil {
+		log.Printf("WAL: failed to close segment before compaction cleanup: %v", err)
+	}
+	w.active = nil
+	w.removeSegments(w.segments)
+
+	w.segments = []uint64{seq}
+	w.logRecords = len(ids)
+
+	return w.openSegment(seq + 1)
+}
+
+// Close останавливает фоновые задачи и сбрасывает журнал на диск
+func (w *WAL) Close() error {
+	close(w.done)
+	w.wg.Wait()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.active == nil {
+		return nil
+	}
+
+	err := w.active.Sync()
+	if closeErr := w.active.Close(); err == nil {
+		err = closeErr
+	}
+	w.active = nil
+	return err
+}
+
+// recover воспроизводит сегменты журнала и открывает активный сегмент
+func (w *WAL) recover() error {
+	entries, err := os.ReadDir(w.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read WAL directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		// Незавершенное уплотнение: исходные сегменты еще на месте
+		if strings.HasSuffix(name, walCompactSuffix) {
+			os.Remove(filepath.Join(w.config.Dir, name))
+			continue
+		}
+		if seq, ok := parseSegmentName(name); ok {
+			w.segments = append(w.segments, seq)
+		}
+	}
+	sort.Slice(w.segments, func(i, j int) bool { return w.segments[i] < w.segments[j] })
+
+	// Сегменты старше последнего снимка остались от уплотнения, при котором
+	// не удалось их удалить. Их записи могли быть отменены удаленными позже
+	// сегментами, поэтому воспроизводить их нельзя
+	for i := len(w.segments) - 1; i > 0; i-- {
+		if w.isSnapshot(w.segments[i]) {
+			w.removeSegments(w.segments[:i])
+			w.segments = w.segments[i:]
+			break
+		}
+	}
+
+	for i, seq := range w.segments {
+		last := i == len(w.segments)-1
+		if err = w.replaySegment(seq, last); err != nil {
+			return err
+		}
+	}
+
+	if len(w.segments) == 0 {
+		w.segments = []uint64{1}
+	}
+	return w.openSegment(w.segments[len(w.segments)-1])
+}
+
+// isSnapshot проверяет, начинается ли сегмент с отметки снимка
+func (w *WAL) isSnapshot(seq uint64) bool {
+	file, err := os.Open(w.segmentPath(seq))
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	record, _, err := readRecord(bufio.NewReader(file))
+	return err == nil && record.Op == walOpSnapshot
+}
+
+// removeSegments удаляет файлы сегментов. Ошибки только логируются: оставшиеся
+// сегменты старше снимка пропускаются при восстановлении
+func (w *WAL) removeSegments(segments []uint64) {
+	for _, seq := range segments {
+		if err := os.Remove(w.segmentPath(seq)); err != nil && !os.IsNotExist(err) {
+			log.Printf("WAL: failed to remove compacted segment: %v", err)
+		}
+	}
+}
+
+// replaySegment применяет записи сегмента. Повреждение в конце последнего
+// сегмента считается оборванной записью и обрезается
+func (w *WAL) replaySegment(seq uint64, last bool) error {
+	path := w.segmentPath(seq)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		record, n, err := readRecord(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if !last {
+				return fmt.Errorf("segment %s at offset %d: %w", path, offset, err)
+			}
+			log.Printf("WAL: truncating segment %s at offset %d: %v", path, offset, err)
+			return os.Truncate(path, offset)
+		}
+
+		w.apply(record)
+		offset += int64(n)
+		// Отметка снимка не хранит новость и не учитывается, как и в Compact
+		if record.Op != walOpSnapshot {
+			w.logRecords++
+		}
+	}
+}
+
+// apply применяет запись к состоянию в памяти
+func (w *WAL) apply(record walRecord) {
+	switch record.Op {
+	case walOpPut:
+		if record.News != nil {
+			w.news[record.ID] = *record.News
+		}
+	case walOpDelete:
+		delete(w.news, record.ID)
+	case walOpSnapshot:
+		w.news = make(map[string]domain.News)
+	}
+	w.liveRecords = len(w.news)
+}
+
+// appendRecords дописывает записи в активный сегмент
+func (w *WAL) appendRecords(records []walRecord) error {
+	if w.active == nil {
+		return fmt.Errorf("WAL is closed")
+	}
+	if w.failed != nil {
+		return fmt.Errorf("WAL is unavailable after a failed write: %w", w.failed)
+	}
+
+	bw := bufio.NewWriter(w.active)
+	var written int64
+	for _, record := range records {
+		n, err := writeRecord(bw, record)
+		if err != nil {
+			return w.abortAppend(fmt.Errorf("failed to append to WAL: %w", err))
+		}
+		written += int64(n)
+	}
+	if err := bw.Flush(); err != nil {
+		return w.abortAppend(fmt.Errorf("failed to append to WAL: %w", err))
+	}
+
+	w.dirty = true
+	if w.config.SyncPolicy == SyncAlways {
+		if err := w.syncActive(); err != nil {
+			// После неудачного fsync неизвестно, что из ранее записанного
+			// дошло до диска, поэтому журнал больше не принимает записи
+			w.failed = err
+			return w.abortAppend(err)
+		}
+	}
+
+	w.activeSize += written
+	w.logRecords += len(records)
+
+	if w.activeSize >= w.config.SegmentSize {
+		return w.rollSegment()
+	}
+
+	return nil
+}
+
+// abortAppend отбрасывает частично записанные записи, обрезая сегмент до
+// размера перед дозаписью. Иначе следующие записи оказались бы после
+// оборванной и пропали бы при восстановлении. Если обрезать не удалось,
+// журнал перестает принимать записи
+func (w *WAL) abortAppend(cause error) error {
+	if err := w.active.Truncate(w.activeSize); err != nil {
+		if w.failed == nil {
+			w.failed = cause
+		}
+		return fmt.Errorf("%w (failed to truncate WAL segment: %v)", cause, err)
+	}
+	return cause
+}
+
+// rollSegment закрывает активный сегмент и открывает следующий
+func (w *WAL) rollSegment() error {
+	if err := w.syncActive(); err != nil {
+		return err
+	}
+	if err := w.active.Close(); err != nil {
+		return err
+	}
+
+	seq := w.segments[len(w.segments)-1] + 1
+	w.segments = append(w.segments, seq)
+	return w.openSegment(seq)
+}
+
+// openSegment открывает сегмент на дозапись и делает его активным
+func (w *WAL) openSegment(seq uint64) error {
+	file, err := w.config.OpenSegment(w.segmentPath(seq))
+	if err != nil {
+		return fmt.Errorf("failed to open WAL segment: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if w.segments[len(w.segments)-1] != seq {
+		w.segments = append(w.segments, seq)
+	}
+	w.active = file
+	w.activeSize = info.Size()
+	return syncDir(w.config.Dir)
+}
+
+// syncActive выполняет fsync активного сегмента, если были записи
+func (w *WAL) syncActive() error {
+	if !w.dirty || w.active == nil {
+		return nil
+	}
+	if err := w.active.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	w.dirty = false
+	return nil
+}
+
+// syncIfDirty используется фоновой синхронизацией
+func (w *WAL) syncIfDirty() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := w.syncActive(); err != nil {
+		log.Printf("WAL background sync failed: %v", err)
+	}
+}
+
+// runPeriodic выполняет fn с заданным периодом до закрытия журнала
+func (w *WAL) runPeriodic(interval time.Duration, fn func()) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			fn()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *WAL) segmentPath(seq uint64) string {
+	return filepath.Join(w.config.Dir, fmt.Sprintf("%s%020d%s", walSegmentPrefix, seq, walSegmentSuffix))
+}
+
+// parseSegmentName извлекает номер сегмента из имени файла
+func parseSegmentName(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, walSegmentPrefix) || !strings.HasSuffix(name, walSegmentSuffix) {
+		return 0, false
+	}
+	var seq uint64
+	if _, err := fmt.Sscanf(strings.TrimSuffix(strings.TrimPrefix(name, walSegmentPrefix), walSegmentSuffix), "%d", &seq); err != nil {
+		return 0, false
+	}
+	return seq, true
+}
+
+// writeRecord кодирует запись: длина payload, CRC32C payload, payload (JSON)
+func writeRecord(w io.Writer, record walRecord) (int, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode WAL record: %w", err)
+	}
+
+	var header [walHeaderSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, walCRCTable))
+
+	if _, err = w.Write(header[:]); err != nil {
+		return 0, err
+	}
+	if _, err = w.Write(payload); err != nil {
+		return 0, err
+	}
+	return walHeaderSize + len(payload), nil
+}
+
+// readRecord читает и проверяет одну запись. io.EOF возвращается только на границе записи
+func readRecord(r io.Reader) (walRecord, int, error) {
+	var record walRecord
+
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return record, 0, io.EOF
+		}
+		return record, 0, errWALCorrupted
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length == 0 || length > walMaxRecordSize {
+		return record, 0, errWALCorrupted
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return record, 0, errWALCorrupted
+	}
+	if crc32.Checksum(payload, walCRCTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return record, 0, errWALCorrupted
+	}
+
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, 0, errWALCorrupted
+	}
+
+	return record, walHeaderSize + int(length), nil
+}
+
+// syncDir выполняет fsync каталога, чтобы закрепить создание и переименование файлов
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// newsEqual сравнивает две новости по всем полям
+func newsEqual(a, b domain.News) bool {
+	return a.ID == b.ID &&
+		a.Title == b.Title &&
+		a.Description == b.Description &&
+		a.URL == b.URL &&
+		a.Source == b.Source &&
+		a.Category == b.Category &&
+		a.PublishedAt.Equal(b.PublishedAt)
+}
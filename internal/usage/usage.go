@@ -0,0 +1,315 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Форматы периодов учета (UTC)
+const (
+	dayFormat   = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+// ErrQuotaExceeded возвращается, если потребитель исчерпал квоту
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota задает лимиты потребителя. Нулевое значение - без ограничения
+type Quota struct {
+	DailyRequests   int64 `yaml:"daily_requests" json:"daily_requests"`
+	MonthlyRequests int64 `yaml:"monthly_requests" json:"monthly_requests"`
+	DailyBytes      int64 `yaml:"daily_bytes" json:"daily_bytes"`
+	MonthlyBytes    int64 `yaml:"monthly_bytes" json:"monthly_bytes"`
+}
+
+// Config содержит настройки учета использования API
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+	// FilePath - файл для сохранения счетчиков между перезапусками; пусто - только память
+	FilePath      string        `yaml:"file_path"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// DefaultQuota применяется к потребителям, не перечисленным в Quotas
+	DefaultQuota Quota `yaml:"default_quota"`
+	// Quotas - квоты по потребителю: идентификатору ключа или пользователя
+	Quotas map[string]Quota `yaml:"quotas"`
+}
+
+// Counter - количество запросов и отправленных байт за период
+type Counter struct {
+	Requests int64 `json:"requests" example:"120"`
+	Bytes    int64 `json:"bytes" example:"524288"`
+}
+
+// Usage описывает использование API потребителем в текущих периодах
+type Usage struct {
+	Consumer string    `json:"consumer" example:"3f9a1c0b7d2e"`
+	Day      string    `json:"day" example:"2026-10-18"`
+	Month    string    `json:"month" example:"2026-10"`
+	Daily    Counter   `json:"daily"`
+	Monthly  Counter   `json:"monthly"`
+	Quota    Quota     `json:"quota"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// DailyReset возвращает время обнуления дневных счетчиков
+func (u Usage) DailyReset() time.Time {
+	day, _ := time.Parse(dayFormat, u.Day)
+	return day.AddDate(0, 0, 1)
+}
+
+// MonthlyReset возвращает время обнуления месячных счетчиков
+func (u Usage) MonthlyReset() time.Time {
+	month, _ := time.Parse(monthFormat, u.Month)
+	return month.AddDate(0, 1, 0)
+}
+
+// Exceeded сообщает, исчерпана ли какая-либо из квот
+func (u Usage) Exceeded() bool {
+	return reached(u.Daily.Requests, u.Quota.DailyRequests) ||
+		reached(u.Monthly.Requests, u.Quota.MonthlyRequests) ||
+		reached(u.Daily.Bytes, u.Quota.DailyBytes) ||
+		reached(u.Monthly.Bytes, u.Quota.MonthlyBytes)
+}
+
+// ResetAt возвращает время, когда исчерпанные квоты снова станут доступны
+func (u Usage) ResetAt() time.Time {
+	if reached(u.Monthly.Requests, u.Quota.MonthlyRequests) || reached(u.Monthly.Bytes, u.Quota.MonthlyBytes) {
+		return u.MonthlyReset()
+	}
+	return u.DailyReset()
+}
+
+func reached(used, limit int64) bool {
+	return limit > 0 && used >= limit
+}
+
+// record - счетчики потребителя в сохраняемом виде
+type record struct {
+	Day      string    `json:"day"`
+	Month    string    `json:"month"`
+	Daily    Counter   `json:"daily"`
+	Monthly  Counter   `json:"monthly"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Tracker считает запросы и байты по потребителям и проверяет квоты.
+// Счетчики хранятся в памяти процесса и периодически сохраняются в файл,
+// поэтому при нескольких репликах квота действует на каждую реплику отдельно
+type Tracker struct {
+	config  Config
+	records map[string]*record
+	dirty   bool
+	mutex   sync.Mutex
+}
+
+// NewTracker создает учет использования и загружает сохраненные счетчики
+func NewTracker(config Config) (*Tracker, error) {
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Minute
+	}
+
+	tracker := &Tracker{
+		config:  config,
+		records: make(map[string]*record),
+	}
+
+	if config.FilePath == "" {
+		return tracker, nil
+	}
+
+	data, err := os.ReadFile(config.FilePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return tracker, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err = json.Unmarshal(data, &tracker.records); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file: %w", err)
+	}
+	return tracker, nil
+}
+
+// Acquire учитывает запрос потребителя, если квота не исчерпана. При
+// превышении запрос не учитывается и возвращается ErrQuotaExceeded.
+// Если enforce равен false, запрос учитывается без проверки квоты
+func (t *Tracker) Acquire(consumer string, enforce bool) (Usage, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	rec := t.current(consumer)
+	usage := t.usage(consumer, rec)
+	if enforce && usage.Exceeded() {
+		return usage, ErrQuotaExceeded
+	}
+
+	rec.Daily.Requests++
+	rec.Monthly.Requests++
+	rec.LastSeen = time.Now().UTC()
+	t.dirty = true
+
+	return t.usage(consumer, rec), nil
+}
+
+// AddBytes учитывает объем ответа, отправленного потребителю
+func (t *Tracker) AddBytes(consumer string, bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	rec := t.current(consumer)
+	rec.Daily.Bytes += bytes
+	rec.Monthly.Bytes += bytes
+	t.dirty = true
+}
+
+// Get возвращает использование одного потребителя
+func (t *Tracker) Get(consumer string) Usage {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if rec, ok := t.records[consumer]; ok {
+		return t.usage(consumer, t.rollover(rec))
+	}
+	return t.usage(consumer, t.rollover(&record{}))
+}
+
+// List возвращает использование всех потребителей, отсортированное по имени
+func (t *Tracker) List() []Usage {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	result := make([]Usage, 0, len(t.records))
+	for consumer, rec := range t.records {
+		result = append(result, t.usage(consumer, t.rollover(rec)))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Consumer < result[j].Consumer
+	})
+	return result
+}
+
+// Run периодически сохраняет счетчики до отмены контекста
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Flush(); err != nil {
+				fmt.Printf("Failed to save API usage: %v\n", err)
+			}
+		}
+	}
+}
+
+// Flush удаляет счетчики прошлых месяцев и сохраняет остальные в файл, если они изменились
+func (t *Tracker) Flush() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// Потребители, не обращавшиеся в текущем месяце, больше не нужны
+	month := time.Now().UTC().Format(monthFormat)
+	for consumer, rec := range t.records {
+		if rec.Month != month {
+			delete(t.records, consumer)
+		}
+	}
+
+	if !t.dirty || t.config.FilePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(t.records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(t.config.FilePath)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(t.config.FilePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), t.config.FilePath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+
+	t.dirty = false
+	return nil
+}
+
+// QuotaFor возвращает квоту потребителя
+func (t *Tracker) QuotaFor(consumer string) Quota {
+	if quota, ok := t.config.Quotas[consumer]; ok {
+		return quota
+	}
+	return t.config.DefaultQuota
+}
+
+// current возвращает запись потребителя за текущие периоды. Вызывается под блокировкой
+func (t *Tracker) current(consumer string) *record {
+	rec, ok := t.records[consumer]
+	if !ok {
+		rec = &record{}
+		t.records[consumer] = rec
+	}
+	*rec = *t.rollover(rec)
+	return rec
+}
+
+// rollover обнуляет счетчики истекших периодов
+func (t *Tracker) rollover(rec *record) *record {
+	now := time.Now().UTC()
+	day, month := now.Format(dayFormat), now.Format(monthFormat)
+
+	result := *rec
+	if result.Day != day {
+		result.Day = day
+		result.Daily = Counter{}
+	}
+	if result.Month != month {
+		result.Month = month
+		result.Monthly = Counter{}
+	}
+	return &result
+}
+
+// usage собирает ответ по записи потребителя
+func (t *Tracker) usage(consumer string, rec *record) Usage {
+	return Usage{
+		Consumer: consumer,
+		Day:      rec.Day,
+		Month:    rec.Month,
+		Daily:    rec.Daily,
+		Monthly:  rec.Monthly,
+		Quota:    t.QuotaFor(consumer),
+		LastSeen: rec.LastSeen,
+	}
+}
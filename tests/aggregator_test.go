@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pah-an/infohub/internal/aggregator"
+	"github.com/pah-an/infohub/internal/domain"
+	"github.com/pah-an/infohub/internal/storage"
+)
+
+// startAggregator запускает агрегатор и возвращает функцию доставки пачки
+// новостей, которая дожидается ее обработки
+func startAggregator(t *testing.T, agg *aggregator.Aggregator) func(domain.NewsList) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	newsChannel := make(chan domain.NewsList)
+	go agg.Start(ctx, newsChannel, make(chan error))
+
+	return func(news domain.NewsList) {
+		newsChannel <- news
+		// Следующая отправка принимается только после обработки предыдущей пачки
+		newsChannel <- domain.NewsList{}
+	}
+}
+
+func newsIDs(news domain.NewsList) map[string]bool {
+	ids := make(map[string]bool, len(news))
+	for _, item := range news {
+		ids[item.ID] = true
+	}
+	return ids
+}
+
+// TestRetentionLimits проверяет ограничения окна по возрасту, источнику и общему размеру
+func TestRetentionLimits(t *testing.T) {
+	agg, err := aggregator.New(nil, aggregator.RetentionConfig{
+		MaxItems:     3,
+		MaxPerSource: 2,
+		MaxAge:       24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+	deliver := startAggregator(t, agg)
+
+	now := time.Now()
+	item := func(id, source string, age time.Duration) domain.News {
+		return domain.News{ID: id, Title: id, Source: source, PublishedAt: now.Add(-age)}
+	}
+	deliver(domain.NewsList{
+		item("a1", "A", time.Minute),
+		item("a2", "A", 2*time.Minute),
+		item("a3", "A", 3*time.Minute), // третья новость источника A
+		item("b1", "B", 4*time.Minute),
+		item("b2", "B", 5*time.Minute), // не помещается в окно из трех
+		item("c1", "C", 48*time.Hour),  // старше max_age
+	})
+
+	news := agg.GetNews()
+	if len(news) != 3 || news[0].ID != "a1" || news[1].ID != "a2" || news[2].ID != "b1" {
+		t.Errorf("Unexpected window: %+v", news)
+	}
+
+	// Более свежая новость вытесняет самую старую
+	deliver(domain.NewsList{item("b0", "B", 0)})
+	news = agg.GetNews()
+	if len(news) != 3 || news[0].ID != "b0" || newsIDs(news)["b1"] {
+		t.Errorf("Unexpected window after new news: %+v", news)
+	}
+}
+
+// TestRetentionPersistModes проверяет, что без архива вытесненные новости
+// удаляются из хранилища, а в архивном режиме остаются в нем, в том числе
+// при вытеснении по возрасту без поступления новых новостей
+func TestRetentionPersistModes(t *testing.T) {
+	for _, archive := range []bool{false, true} {
+		name := "delete"
+		if archive {
+			name = "archive"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			store := newTestFileCache(t)
+			agg, err := aggregator.New(store, aggregator.RetentionConfig{
+				MaxItems:      2,
+				MaxAge:        500 * time.Millisecond,
+				CheckInterval: 10 * time.Millisecond,
+				Archive:       archive,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create aggregator: %v", err)
+			}
+			deliver := startAggregator(t, agg)
+
+			now := time.Now()
+			deliver(domain.NewsList{
+				{ID: "aging", Title: "Aging", Source: "A", PublishedAt: now.Add(-300 * time.Millisecond)},
+				{ID: "fresh", Title: "Fresh", Source: "A", PublishedAt: now.Add(time.Hour)},
+				{ID: "old", Title: "Old", Source: "A", PublishedAt: now.Add(-400 * time.Millisecond)},
+			})
+
+			// "old" не помещается в окно из двух новостей
+			stored, _ := store.GetLatestNews(10)
+			if ids := newsIDs(stored); ids["old"] != archive || !ids["aging"] || !ids["fresh"] {
+				t.Errorf("Unexpected storage after window overflow: %v", ids)
+			}
+
+			// "aging" вытесняется по возрасту фоновой проверкой
+			deadline := time.Now().Add(5 * time.Second)
+			for len(agg.GetNews()) != 1 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if news := agg.GetNews(); len(news) != 1 || news[0].ID != "fresh" {
+				t.Fatalf("Expected aging news to be evicted, got %+v", news)
+			}
+
+			// Запись в хранилище выполняется под той же блокировкой, что и
+			// обновление окна, поэтому к этому моменту она уже завершена
+			stored, _ = store.GetLatestNews(10)
+			if ids := newsIDs(stored); ids["aging"] != archive || ids["old"] != archive || !ids["fresh"] {
+				t.Errorf("Unexpected storage after age eviction: %v", ids)
+			}
+		})
+	}
+}
+
+// TestRetentionPrunesHistory проверяет, что без архива max_age ограничивает
+// и историю в хранилище sqlite, которая не удаляется вместе с окном
+func TestRetentionPrunesHistory(t *testing.T) {
+	store, err := storage.NewSQLStore(storage.SQLConfig{Path: filepath.Join(t.TempDir(), "news.db")})
+	if err != nil {
+		t.Fatalf("Failed to open SQL store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	if err = store.SaveNews(domain.NewsList{
+		{ID: "expired", Title: "Expired", Source: "A", PublishedAt: now.Add(-time.Hour)},
+		{ID: "recent", Title: "Recent", Source: "A", PublishedAt: now},
+	}); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+
+	agg, err := aggregator.New(store, aggregator.RetentionConfig{
+		MaxItems:      10,
+		MaxAge:        time.Minute,
+		CheckInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+	startAggregator(t, agg)
+
+	deadline := time.Now().Add(5 * time.Second)
+	var stored domain.NewsList
+	for time.Now().Before(deadline) {
+		if stored, _ = store.GetLatestNews(10); len(stored) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(stored) != 1 || stored[0].ID != "recent" {
+		t.Errorf("Expected expired news to be pruned from history, got %+v", stored)
+	}
+}
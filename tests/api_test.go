@@ -4,10 +4,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/pah-an/infohub/internal/aggregator"
+	"github.com/pah-an/infohub/internal/cache"
 	"github.com/pah-an/infohub/internal/domain"
+	v1 "github.com/pah-an/infohub/internal/server/v1"
+	"github.com/pah-an/infohub/internal/storage"
 )
 
 // MockNewsProvider для тестирования
@@ -294,3 +299,112 @@ func BenchmarkNewsRetrieval(b *testing.B) {
 		_ = provider.GetLatestNews(100)
 	}
 }
+
+// TestNewsConditionalRequests проверяет ETag и 304 Not Modified для списка новостей
+func TestNewsConditionalRequests(t *testing.T) {
+	repo := &countingRepository{news: testNews("a", "b")}
+	agg, err := aggregator.New(repo, aggregator.RetentionConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+	if err = agg.LoadFromRepository(); err != nil {
+		t.Fatalf("Failed to load news: %v", err)
+	}
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+
+	handlers := v1.NewHandlers(agg, repo, memoryCache)
+	handlers.SetResponseCache(v1.ResponseCacheOptions{MaxAge: time.Minute, CacheBodies: true})
+
+	request := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/news?limit=10", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		handlers.GetNews(rec, req)
+		return rec
+	}
+
+	first := request("", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with ETag, got %d %q", first.Code, etag)
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "private, max-age=60" {
+		t.Errorf("Unexpected Cache-Control: %q", cc)
+	}
+
+	if rec := request("If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected 304 for matching ETag, got %d", rec.Code)
+	}
+	if rec := request("If-Modified-Since", first.Header().Get("Last-Modified")); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for If-Modified-Since, got %d", rec.Code)
+	}
+
+	// Повторный ответ берется из кэша и совпадает с исходным
+	if rec := request("", ""); rec.Body.String() != first.Body.String() {
+		t.Errorf("Cached body differs from original")
+	}
+
+	// После изменения содержимого старый ETag перестает совпадать
+	repo.news = testNews("a", "b", "c")
+	agg.LoadFromRepository()
+	if rec := request("If-None-Match", etag); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 after content change, got %d", rec.Code)
+	}
+}
+
+// TestNewsHistoryPagination проверяет постраничную выборку истории из
+// хранилища и ее замену горячим окном для хранилищ без запросов
+func TestNewsHistoryPagination(t *testing.T) {
+	store, err := storage.NewSQLStore(storage.SQLConfig{Path: filepath.Join(t.TempDir(), "news.db")})
+	if err != nil {
+		t.Fatalf("Failed to open SQL store: %v", err)
+	}
+	defer store.Close()
+
+	history := testNews("a", "b", "c", "d", "e")
+	if err = store.SaveNews(history); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+
+	// В горячем окне только две новости
+	window := &MockNewsProvider{news: testNews("d", "e").SortByDate()}
+
+	request := func(handlers *v1.Handlers, query string) (int, v1.NewsResponse) {
+		rec := httptest.NewRecorder()
+		handlers.GetNews(rec, httptest.NewRequest(http.MethodGet, "/api/v1/news"+query, nil))
+		var page v1.NewsResponse
+		json.Unmarshal(rec.Body.Bytes(), &page)
+		return rec.Code, page
+	}
+
+	handlers := v1.NewHandlers(window, store, nil)
+
+	code, page := request(handlers, "?offset=1&limit=2")
+	if code != http.StatusOK || page.Total != 5 || len(page.News) != 2 || page.News[0].ID != "d" || page.News[1].ID != "c" {
+		t.Errorf("Unexpected history page: %d %+v", code, page)
+	}
+
+	code, page = request(handlers, "?from=2024-01-01T12:01:00Z&to=2024-01-01T12:02:00Z")
+	if code != http.StatusOK || page.Total != 2 || len(page.News) != 2 {
+		t.Errorf("Unexpected time range page: %d %+v", code, page)
+	}
+
+	// Без параметров истории ответ строится по горячему окну
+	if _, page = request(handlers, "?limit=10"); len(page.News) != 2 || page.Total != 0 {
+		t.Errorf("Expected the hot window without history parameters, got %+v", page)
+	}
+
+	if code, _ = request(handlers, "?offset=-1"); code != http.StatusBadRequest {
+		t.Errorf("Expected negative offset to be rejected, got %d", code)
+	}
+
+	// Хранилище без запросов: страница строится по окну
+	code, page = request(v1.NewHandlers(window, newTestFileCache(t), nil), "?offset=1")
+	if code != http.StatusOK || page.Total != 2 || len(page.News) != 1 || page.News[0].ID != "d" {
+		t.Errorf("Unexpected window page: %d %+v", code, page)
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pah-an/infohub/internal/audit"
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/cache"
+	"github.com/pah-an/infohub/internal/logger"
+	"github.com/pah-an/infohub/internal/server"
+)
+
+// TestAuditLog проверяет запись входов, ошибок аутентификации и действий администраторов
+func TestAuditLog(t *testing.T) {
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer auditLog.Close()
+
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret:   "secret",
+		Enabled:     true,
+		AdminAPIKey: "admin-key",
+		APIKeys:     map[string]string{"reader-key": "Reader"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+
+	handler := server.NewInfoHubServer(server.Config{
+		NewsProvider: NewMockNewsProvider(),
+		Cache:        memoryCache,
+		Logger:       logger.New(logger.Config{Level: "error"}),
+		AuthManager:  manager,
+		Audit:        auditLog,
+	}).Handler()
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	request(http.MethodGet, "/api/v1/news?api_key=wrong-key", "", "")
+	if rec := request(http.MethodPost, "/auth/login", "", `{"api_key":"reader-key"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d", rec.Code)
+	}
+	request(http.MethodPost, "/auth/login", "", `{"api_key":"wrong-key"}`)
+	if rec := request(http.MethodPost, "/api/v1/admin/cache/clear?pattern=news:*", "admin-key", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected cache clear to succeed, got %d", rec.Code)
+	}
+	request(http.MethodPost, "/api/v1/admin/cache/clear", "reader-key", "")
+	// Обычные запросы в журнал не попадают
+	request(http.MethodGet, "/api/v1/news", "reader-key", "")
+
+	query := func(path string) []audit.Event {
+		rec := request(http.MethodGet, path, "admin-key", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected audit query to succeed, got %d", rec.Code)
+		}
+		var response struct {
+			Count  int           `json:"count"`
+			Events []audit.Event `json:"events"`
+		}
+		json.NewDecoder(rec.Body).Decode(&response)
+		return response.Events
+	}
+
+	events := query("/api/v1/admin/audit")
+	want := []struct{ action, actor, outcome string }{
+		{"cache.clear", "Reader", audit.OutcomeDenied},
+		{"cache.clear", "admin", audit.OutcomeSuccess},
+		{"auth.login", "anonymous", audit.OutcomeDenied},
+		{"auth.login", "Reader", audit.OutcomeSuccess},
+		{"auth.failure", "anonymous", audit.OutcomeDenied},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d audit events, got %d: %+v", len(want), len(events), events)
+	}
+	for i, expected := range want {
+		event := events[i]
+		if event.Action != expected.action || event.Actor != expected.actor || event.Outcome != expected.outcome {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected, event)
+		}
+		if event.IP == "" || event.RequestID == "" || event.Time.IsZero() {
+			t.Errorf("Event %d lacks request metadata: %+v", i, event)
+		}
+	}
+	if strings.Contains(events[4].Target, "wrong-key") {
+		t.Errorf("Audit target must not contain API keys: %q", events[4].Target)
+	}
+	if events[1].Target != "/api/v1/admin/cache/clear?pattern=news%3A%2A" {
+		t.Errorf("Unexpected cache clear target: %q", events[1].Target)
+	}
+
+	// Фильтры по исполнителю и действию
+	events = query("/api/v1/admin/audit?actor=admin&action=cache.clear")
+	if len(events) != 1 || events[0].Outcome != audit.OutcomeSuccess {
+		t.Errorf("Unexpected filtered events: %+v", events)
+	}
+
+	if rec := request(http.MethodGet, "/api/v1/admin/audit", "reader-key", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected audit log to require admin:audit, got %d", rec.Code)
+	}
+	if rec := request(http.MethodGet, "/api/v1/admin/audit?since=yesterday", "admin-key", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected invalid since to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,1089 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/cache"
+	"github.com/pah-an/infohub/internal/logger"
+	"github.com/pah-an/infohub/internal/server"
+)
+
+// TestAPIKeyStore проверяет выпуск, проверку, истечение и отзыв ключей из хранилища
+func TestAPIKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	manager, err := auth.NewManager(auth.Config{JWTSecret: "secret", Enabled: true, KeyStorePath: path})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	key, info, err := manager.CreateAPIKey(auth.APIKeyRequest{Description: "Mobile app", Owner: "team-mobile"})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if !strings.HasPrefix(key, info.Prefix+"_") {
+		t.Errorf("Expected key to start with its prefix %q, got %q", info.Prefix, key)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read key store: %v", err)
+	}
+	if strings.Contains(string(data), key[len(info.Prefix)+1:]) {
+		t.Errorf("Key store must not contain the key secret")
+	}
+
+	user, err := manager.ValidateAPIKey(key)
+	if err != nil {
+		t.Fatalf("Expected issued key to be valid: %v", err)
+	}
+	if user.ID != "team-mobile" || user.APIKey != info.Prefix {
+		t.Errorf("Unexpected user for issued key: %+v", user)
+	}
+	if _, err = manager.ValidateAPIKey(info.Prefix + "_wrong"); err == nil {
+		t.Errorf("Expected key with a wrong secret to be rejected")
+	}
+
+	// Ключи переживают перезапуск
+	restarted, err := auth.NewManager(auth.Config{JWTSecret: "secret", Enabled: true, KeyStorePath: path})
+	if err != nil {
+		t.Fatalf("Failed to reopen key store: %v", err)
+	}
+	defer restarted.Close()
+	if _, err = restarted.ValidateAPIKey(key); err != nil {
+		t.Errorf("Expected key to be valid after restart: %v", err)
+	}
+
+	// Отзыв действует сразу, несмотря на запомненную проверку
+	if _, err = manager.RevokeAPIKey(info.ID); err != nil {
+		t.Fatalf("Failed to revoke API key: %v", err)
+	}
+	if _, err = manager.ValidateAPIKey(key); err == nil {
+		t.Errorf("Expected revoked key to be rejected")
+	}
+
+	expiresAt := time.Now().Add(-time.Minute)
+	expired, _, err := manager.CreateAPIKey(auth.APIKeyRequest{Owner: "ci", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if _, err = manager.ValidateAPIKey(expired); err == nil {
+		t.Errorf("Expected expired key to be rejected")
+	}
+
+	keys, err := manager.ListAPIKeys()
+	if err != nil {
+		t.Fatalf("Failed to list API keys: %v", err)
+	}
+	if len(keys) != 2 || keys[1].RevokedAt == nil || keys[1].LastUsedAt == nil {
+		t.Errorf("Unexpected key list: %+v", keys)
+	}
+}
+
+// TestKeyStoreRejectsGuessing проверяет, что подбор секрета к известному
+// префиксу не хэшируется повторно и не мешает проверке настоящего ключа
+func TestKeyStoreRejectsGuessing(t *testing.T) {
+	store, err := auth.NewKeyStore(filepath.Join(t.TempDir(), "api_keys.json"))
+	if err != nil {
+		t.Fatalf("Failed to open key store: %v", err)
+	}
+	key, info, err := store.Create(auth.APIKeyRequest{Owner: "team-mobile"})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	// Одновременные попытки с разными секретами отклоняются
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Verify(fmt.Sprintf("%s_guess%d", info.Prefix, i)); err == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted.Load() != 0 {
+		t.Errorf("Expected all guesses to be rejected, %d accepted", accepted.Load())
+	}
+
+	// Повтор отклоненного ключа отклоняется без хэширования
+	guess := info.Prefix + "_guess"
+	start := time.Now()
+	store.Verify(guess)
+	hashTime := time.Since(start)
+	start = time.Now()
+	for i := 0; i < 50; i++ {
+		if _, err = store.Verify(guess); !errors.Is(err, auth.ErrKeyNotFound) {
+			t.Fatalf("Expected repeated guess to be rejected, got %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 10*hashTime {
+		t.Errorf("Expected repeated guesses to skip hashing, 50 took %v (one hash %v)", elapsed, hashTime)
+	}
+
+	if _, err = store.Verify(key); err != nil {
+		t.Errorf("Expected the real key to stay valid: %v", err)
+	}
+}
+
+// TestKeyStoreSharedFile проверяет хранилище ключей, общее для нескольких реплик
+func TestKeyStoreSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	first, err := auth.NewKeyStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open key store: %v", err)
+	}
+	second, err := auth.NewKeyStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open key store: %v", err)
+	}
+
+	key, info, err := first.Create(auth.APIKeyRequest{Owner: "team-mobile"})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	// Ключ, выпущенный одной репликой, сразу действует на другой
+	if _, err = second.Verify(key); err != nil {
+		t.Fatalf("Expected key created by another replica to be valid: %v", err)
+	}
+
+	// Ключи, выпущенные репликами поочередно, не затирают друг друга
+	if _, _, err = second.Create(auth.APIKeyRequest{Owner: "team-web"}); err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if len(first.List()) != 2 {
+		t.Errorf("Expected both replicas' keys to be listed, got %+v", first.List())
+	}
+
+	// Вторая реплика копит время использования ключа в памяти
+	if _, err = second.Verify(key); err != nil {
+		t.Fatalf("Expected key to be valid: %v", err)
+	}
+
+	// Отзыв на одной реплике не отменяется записью другой и сразу действует на ней
+	if _, err = first.Revoke(info.ID); err != nil {
+		t.Fatalf("Failed to revoke API key: %v", err)
+	}
+	if err = second.Flush(); err != nil {
+		t.Fatalf("Failed to flush key store: %v", err)
+	}
+	if _, err = second.Verify(key); !errors.Is(err, auth.ErrKeyRevoked) {
+		t.Errorf("Expected revocation to reach the other replica, got %v", err)
+	}
+
+	reopened, err := auth.NewKeyStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen key store: %v", err)
+	}
+	if err = reopened.Status(info.ID); !errors.Is(err, auth.ErrKeyRevoked) {
+		t.Errorf("Expected revocation to survive writes of another replica, got %v", err)
+	}
+	for _, listed := range reopened.List() {
+		if listed.ID == info.ID && listed.LastUsedAt == nil {
+			t.Errorf("Expected last use recorded by the other replica to be kept")
+		}
+	}
+}
+
+// TestRouteScopes проверяет проверку областей доступа на маршрутах API
+func TestRouteScopes(t *testing.T) {
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret: "secret",
+		Enabled:   true,
+		APIKeys:   map[string]string{"reader": "Reader", "cache": "Cache operator"},
+		KeyScopes: map[string][]string{"cache": {"admin:*"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+
+	handler := server.NewInfoHubServer(server.Config{
+		NewsProvider: NewMockNewsProvider(),
+		Cache:        memoryCache,
+		Logger:       logger.New(logger.Config{Level: "error"}),
+		AuthManager:  manager,
+	}).Handler()
+
+	request := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request("/api/v1/news", "reader"); rec.Code != http.StatusOK {
+		t.Errorf("Expected reader to get news, got %d", rec.Code)
+	}
+
+	rec := request("/api/v1/admin/cache/keys", "reader")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for missing scope, got %d", rec.Code)
+	}
+	var response map[string]interface{}
+	if err = json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response["required_scope"] != auth.ScopeAdminCache {
+		t.Errorf("Expected scope-specific error, got %s", rec.Body.String())
+	}
+
+	if rec = request("/api/v1/admin/cache/keys", "cache"); rec.Code != http.StatusOK {
+		t.Errorf("Expected admin:* to grant admin:cache, got %d", rec.Code)
+	}
+	if rec = request("/api/v1/news", "cache"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected key without news:read to be rejected, got %d", rec.Code)
+	}
+	if rec = request("/api/v1/admin/cache/keys", "unknown"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for unknown key, got %d", rec.Code)
+	}
+}
+
+// TestTokenRefreshAndRevocation проверяет ротацию refresh токенов и отзыв по jti
+func TestTokenRefreshAndRevocation(t *testing.T) {
+	manager, err := auth.NewManager(auth.Config{JWTSecret: "secret", Enabled: true})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+	manager.SetTokenStore(memoryCache)
+
+	ctx := context.Background()
+	user := &auth.User{ID: "reader", Scopes: []string{auth.ScopeNewsRead}}
+
+	first, err := manager.IssueTokens(user)
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	if first.AccessExpiresIn != 15*time.Minute {
+		t.Errorf("Expected short-lived access token by default, got %v", first.AccessExpiresIn)
+	}
+	if _, err = manager.ValidateJWT(first.RefreshToken); err == nil {
+		t.Errorf("Refresh token must not be accepted as an access token")
+	}
+
+	second, _, err := manager.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to refresh tokens: %v", err)
+	}
+	if _, err = manager.ValidateJWT(second.AccessToken); err != nil {
+		t.Errorf("Expected refreshed access token to be valid: %v", err)
+	}
+
+	// Повторное использование refresh токена отзывает всю сессию
+	if _, _, err = manager.Refresh(ctx, first.RefreshToken); !errors.Is(err, auth.ErrRefreshTokenUsed) {
+		t.Fatalf("Expected reuse to be detected, got %v", err)
+	}
+	if _, err = manager.ValidateJWT(second.AccessToken); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("Expected session tokens to be revoked after reuse, got %v", err)
+	}
+	if _, _, err = manager.Refresh(ctx, second.RefreshToken); err == nil {
+		t.Errorf("Expected rotated refresh token to be revoked with its session")
+	}
+
+	// Выход отзывает отдельный access токен
+	other, err := manager.IssueTokens(user)
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	if err = manager.RevokeToken(ctx, other.AccessToken); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+	if _, err = manager.ValidateJWT(other.AccessToken); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("Expected revoked access token to be rejected, got %v", err)
+	}
+	if _, _, err = manager.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("Revoking an access token must not end the session: %v", err)
+	}
+
+	// Из одновременных обменов одного refresh токена проходит ровно один
+	racing, err := manager.IssueTokens(user)
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	var refreshed, reused atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := manager.Refresh(ctx, racing.RefreshToken)
+			switch {
+			case err == nil:
+				refreshed.Add(1)
+			case errors.Is(err, auth.ErrRefreshTokenUsed):
+				reused.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if refreshed.Load() != 1 || reused.Load() != 19 {
+		t.Errorf("Expected one refresh and 19 reuse detections, got %d and %d", refreshed.Load(), reused.Load())
+	}
+}
+
+// TestAuthStoreIsolation проверяет, что очистка и просмотр кэша данных через
+// admin API не затрагивают список отзыва токенов в Redis
+func TestAuthStoreIsolation(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	redisConfig := cache.Config{Address: server.Addr()}
+
+	dataCache, err := cache.NewRedisCache(redisConfig)
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer dataCache.Close()
+	authStore, err := cache.NewAuthStore(redisConfig, true)
+	if err != nil {
+		t.Fatalf("Failed to create auth store: %v", err)
+	}
+	defer authStore.Close()
+
+	manager, err := auth.NewManager(auth.Config{JWTSecret: "secret", Enabled: true})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+	manager.SetTokenStore(authStore)
+
+	pair, err := manager.IssueTokens(&auth.User{ID: "reader", Scopes: []string{auth.ScopeNewsRead}})
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	if err = manager.RevokeToken(ctx, pair.RefreshToken); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+
+	if keys, _ := dataCache.Keys(ctx, "*"); len(keys) != 0 {
+		t.Errorf("Expected auth state to be invisible in the data cache, got %v", keys)
+	}
+	if _, err = dataCache.DeleteByPattern(ctx, "*"); err != nil {
+		t.Fatalf("Failed to clear data cache: %v", err)
+	}
+	if _, err = manager.ValidateJWT(pair.AccessToken); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("Expected session to stay revoked after clearing the cache, got %v", err)
+	}
+}
+
+// TestRevocationSurvivesCacheFlood проверяет, что заполнение ограниченного кэша
+// данных и рост самого хранилища аутентификации не вытесняют отзыв токена
+func TestRevocationSurvivesCacheFlood(t *testing.T) {
+	ctx := context.Background()
+	dataCache, _ := cache.NewMemoryCache(cache.MemoryConfig{MaxEntries: 100})
+	defer dataCache.Close()
+	authStore, err := cache.NewAuthStore(cache.Config{}, false)
+	if err != nil {
+		t.Fatalf("Failed to create auth store: %v", err)
+	}
+	defer authStore.Close()
+
+	manager, err := auth.NewManager(auth.Config{JWTSecret: "secret", Enabled: true})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+	manager.SetTokenStore(authStore)
+
+	user := &auth.User{ID: "reader", Scopes: []string{auth.ScopeNewsRead}}
+	revoked, _ := manager.IssueTokens(user)
+	if err = manager.RevokeToken(ctx, revoked.AccessToken); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		dataCache.Set(ctx, fmt.Sprintf("news:%d", i), i, time.Minute)
+	}
+	// Записей в хранилище аутентификации больше, чем max_entries кэша по умолчанию
+	for i := 0; i < 10500; i++ {
+		token, _ := manager.GenerateJWT(user)
+		manager.RevokeToken(ctx, token)
+	}
+
+	if _, err = manager.ValidateJWT(revoked.AccessToken); !errors.Is(err, auth.ErrTokenRevoked) {
+		t.Errorf("Expected token to stay revoked after the cache was flooded, got %v", err)
+	}
+}
+
+// TestSessionLimits проверяет предельный возраст сессии и завершение сессий отозванного ключа
+func TestSessionLimits(t *testing.T) {
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret:     "secret",
+		Enabled:       true,
+		MaxSessionAge: time.Hour,
+		KeyStorePath:  filepath.Join(t.TempDir(), "api_keys.json"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+	ctx := context.Background()
+
+	// Сроки токенов не выходят за предельный возраст сессии
+	pair, err := manager.IssueTokens(&auth.User{ID: "reader", Scopes: []string{auth.ScopeNewsRead}})
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	if pair.RefreshExpiresIn > time.Hour {
+		t.Errorf("Expected refresh token to be capped by the session age, got %v", pair.RefreshExpiresIn)
+	}
+
+	// Сессия, начатая раньше предельного возраста, не продлевается
+	now := time.Now()
+	old, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
+		UserID:    "reader",
+		Scopes:    []string{auth.ScopeNewsRead},
+		TokenType: auth.TokenTypeRefresh,
+		Family:    "old-session",
+		AuthTime:  now.Add(-2 * time.Hour).Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "old-refresh",
+			IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			Issuer:    "infohub",
+		},
+	}).SignedString([]byte("secret"))
+	if _, _, err = manager.Refresh(ctx, old); !errors.Is(err, auth.ErrSessionExpired) {
+		t.Errorf("Expected session past its maximum age to be rejected, got %v", err)
+	}
+
+	// Отзыв ключа завершает выданные по нему сессии
+	key, info, err := manager.CreateAPIKey(auth.APIKeyRequest{Owner: "team-mobile"})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	user, err := manager.ValidateAPIKey(key)
+	if err != nil {
+		t.Fatalf("Expected issued key to be valid: %v", err)
+	}
+	pair, err = manager.IssueTokens(user)
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+	if _, err = manager.ValidateJWT(pair.AccessToken); err != nil {
+		t.Fatalf("Expected access token to be valid: %v", err)
+	}
+	if _, err = manager.RevokeAPIKey(info.ID); err != nil {
+		t.Fatalf("Failed to revoke API key: %v", err)
+	}
+	if _, err = manager.ValidateJWT(pair.AccessToken); !errors.Is(err, auth.ErrKeyRevoked) {
+		t.Errorf("Expected access token of a revoked key to be rejected, got %v", err)
+	}
+	if _, _, err = manager.Refresh(ctx, pair.RefreshToken); !errors.Is(err, auth.ErrKeyRevoked) {
+		t.Errorf("Expected refresh with a revoked key to be rejected, got %v", err)
+	}
+}
+
+// TestConfigKeySessions проверяет, что сессии ключей из конфигурации
+// завершаются при удалении ключа и получают его текущие права
+func TestConfigKeySessions(t *testing.T) {
+	ctx := context.Background()
+	authStore, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer authStore.Close()
+
+	newManager := func(apiKeys map[string]string, keyScopes map[string][]string, adminKey string) *auth.Manager {
+		manager, err := auth.NewManager(auth.Config{
+			JWTSecret:   "secret",
+			Enabled:     true,
+			APIKeys:     apiKeys,
+			KeyScopes:   keyScopes,
+			AdminAPIKey: adminKey,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create auth manager: %v", err)
+		}
+		manager.SetTokenStore(authStore)
+		t.Cleanup(func() { manager.Close() })
+		return manager
+	}
+	login := func(manager *auth.Manager, apiKey string) auth.TokenPair {
+		user, err := manager.ValidateAPIKey(apiKey)
+		if err != nil {
+			t.Fatalf("Expected key to be valid: %v", err)
+		}
+		pair, err := manager.IssueTokens(user)
+		if err != nil {
+			t.Fatalf("Failed to issue tokens: %v", err)
+		}
+		return pair
+	}
+
+	before := newManager(
+		map[string]string{"reader-key": "Reader"},
+		map[string][]string{"reader-key": {auth.ScopeNewsRead, auth.ScopeSourcesRead}},
+		"admin-key",
+	)
+	reader := login(before, "reader-key")
+	admin := login(before, "admin-key")
+
+	// После изменения конфигурации права ключа сужены, а admin ключ заменен
+	after := newManager(
+		map[string]string{"reader-key": "Reader"},
+		map[string][]string{"reader-key": {auth.ScopeNewsRead}},
+		"rotated-admin-key",
+	)
+	_, user, err := after.Refresh(ctx, reader.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected refresh with a configured key to succeed: %v", err)
+	}
+	if user.HasScope(auth.ScopeSourcesRead) {
+		t.Errorf("Expected refreshed session to get the current key scopes, got %v", user.Scopes)
+	}
+	if _, _, err = after.Refresh(ctx, admin.RefreshToken); !errors.Is(err, auth.ErrKeyNotFound) {
+		t.Errorf("Expected refresh with a rotated admin key to be rejected, got %v", err)
+	}
+	if _, err = after.ValidateJWT(admin.AccessToken); !errors.Is(err, auth.ErrKeyNotFound) {
+		t.Errorf("Expected access token of a rotated admin key to be rejected, got %v", err)
+	}
+
+	// Удаленный из конфигурации ключ больше не продлевает сессию
+	removed := newManager(nil, nil, "")
+	second := login(before, "reader-key")
+	if _, _, err = removed.Refresh(ctx, second.RefreshToken); !errors.Is(err, auth.ErrKeyNotFound) {
+		t.Errorf("Expected refresh with a removed key to be rejected, got %v", err)
+	}
+}
+
+// TestAsymmetricSigning проверяет подпись RS256, ES256 и EdDSA и публикацию ключей в JWKS
+func TestAsymmetricSigning(t *testing.T) {
+	for _, algorithm := range []string{auth.AlgorithmRS256, auth.AlgorithmES256, auth.AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			manager, err := auth.NewManager(auth.Config{
+				Enabled: true,
+				Signing: auth.SigningConfig{Algorithm: algorithm, KeysDir: t.TempDir()},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create auth manager: %v", err)
+			}
+			defer manager.Close()
+
+			token, err := manager.GenerateJWT(&auth.User{ID: "service"})
+			if err != nil {
+				t.Fatalf("Failed to sign token: %v", err)
+			}
+			if _, err = manager.ValidateJWT(token); err != nil {
+				t.Fatalf("Expected token to be valid: %v", err)
+			}
+
+			// Сторонний сервис проверяет токен только по опубликованному ключу
+			jwks := manager.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Algorithm != algorithm {
+				t.Fatalf("Unexpected JWKS: %+v", jwks)
+			}
+			parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+				if token.Header["kid"] != jwks.Keys[0].KeyID {
+					return nil, errors.New("unexpected kid")
+				}
+				return jwks.Keys[0].PublicKey()
+			})
+			if err != nil || !parsed.Valid {
+				t.Errorf("Expected token to verify with the JWKS key: %v", err)
+			}
+		})
+	}
+}
+
+// TestAllowedAlgorithms проверяет, что при асимметричной подписи токены HS256
+// принимаются, только если HS256 явно разрешен
+func TestAllowedAlgorithms(t *testing.T) {
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
+		UserID:    "reader",
+		Scopes:    []string{auth.ScopeNewsRead},
+		TokenType: auth.TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer:    "infohub",
+		},
+	}).SignedString([]byte("secret"))
+
+	newManager := func(allowed ...string) (*auth.Manager, error) {
+		return auth.NewManager(auth.Config{
+			JWTSecret: "secret",
+			Enabled:   true,
+			Signing:   auth.SigningConfig{Algorithm: auth.AlgorithmRS256, AllowedAlgorithms: allowed},
+		})
+	}
+
+	strict, err := newManager()
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer strict.Close()
+	if _, err = strict.ValidateJWT(legacy); err == nil {
+		t.Errorf("Expected HS256 token to be rejected with asymmetric signing")
+	}
+
+	migrating, err := newManager(auth.AlgorithmRS256, auth.AlgorithmHS256)
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer migrating.Close()
+	if _, err = migrating.ValidateJWT(legacy); err != nil {
+		t.Errorf("Expected explicitly allowed HS256 token to be accepted: %v", err)
+	}
+
+	if _, err = newManager("none"); err == nil {
+		t.Errorf("Expected unsupported allowed algorithm to be rejected")
+	}
+}
+
+// TestSigningKeyRotation проверяет, что после ротации старые токены остаются действительными
+func TestSigningKeyRotation(t *testing.T) {
+	config := auth.SigningConfig{Algorithm: auth.AlgorithmES256, KeysDir: t.TempDir(), RotationInterval: time.Hour}
+	ring, err := auth.NewKeyRing(config, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create key ring: %v", err)
+	}
+
+	claims := jwt.RegisteredClaims{Subject: "service", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	oldToken, err := ring.Sign(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if rotated, err := ring.Rotate(false); err != nil || rotated {
+		t.Fatalf("Expected no rotation before the interval, got %v %v", rotated, err)
+	}
+	if rotated, err := ring.Rotate(true); err != nil || !rotated {
+		t.Fatalf("Expected forced rotation, got %v %v", rotated, err)
+	}
+	newToken, err := ring.Sign(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	// Другая реплика с тем же каталогом знает оба ключа
+	replica, err := auth.NewKeyRing(config, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to load key ring: %v", err)
+	}
+	if keys := replica.JWKS().Keys; len(keys) != 2 {
+		t.Fatalf("Expected retired and active keys in JWKS, got %d", len(keys))
+	}
+
+	kids := make(map[string]bool)
+	for _, token := range []string{oldToken, newToken} {
+		parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			kids[kid] = true
+			return replica.VerificationKey(kid, token.Method.Alg())
+		})
+		if err != nil || !parsed.Valid {
+			t.Errorf("Expected token to verify after rotation: %v", err)
+		}
+	}
+	if len(kids) != 2 {
+		t.Errorf("Expected tokens to be signed by different keys")
+	}
+}
+
+// TestTrustedIssuers проверяет прием токенов стороннего издателя по JWKS и по статическим ключам
+func TestTrustedIssuers(t *testing.T) {
+	identity, err := auth.NewKeyRing(auth.SigningConfig{Algorithm: auth.AlgorithmRS256}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create issuer keys: %v", err)
+	}
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(identity.JWKS())
+	}))
+	defer jwksServer.Close()
+
+	// Второй издатель передает открытый ключ файлом
+	partner, err := auth.NewKeyRing(auth.SigningConfig{Algorithm: auth.AlgorithmEdDSA}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create partner keys: %v", err)
+	}
+	partnerJWK := partner.JWKS().Keys[0]
+	public, _ := partnerJWK.PublicKey()
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "partner.pem")
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret: "secret",
+		Enabled:   true,
+		TrustedIssuers: []auth.IssuerConfig{
+			{
+				Issuer:   "https://identity.internal",
+				Audience: "infohub",
+				JWKSURL:  jwksServer.URL,
+				ClaimMapping: auth.ClaimMapping{
+					GroupsClaim: "scope",
+					GroupScopes: map[string][]string{"infohub.read": {"news:read", "sources:read"}},
+				},
+			},
+			{
+				Issuer:     "https://partner.example.com",
+				Audience:   "infohub",
+				PublicKeys: map[string]string{partnerJWK.KeyID: keyPath},
+				ClaimMapping: auth.ClaimMapping{
+					DefaultScopes: []string{"news:read"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	claims := func(issuer string, mutate func(jwt.MapClaims)) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"iss":   issuer,
+			"aud":   "infohub",
+			"sub":   "billing-service",
+			"scope": "infohub.read openid",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		}
+		if mutate != nil {
+			mutate(claims)
+		}
+		return claims
+	}
+
+	token, _ := identity.Sign(claims("https://identity.internal", nil))
+	user, err := manager.ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("Expected identity service token to be accepted: %v", err)
+	}
+	if user.ID != "billing-service" || !user.HasScope(auth.ScopeSourcesRead) || user.HasScope(auth.ScopeAdminCache) {
+		t.Errorf("Unexpected mapped user: %+v", user)
+	}
+
+	token, _ = partner.Sign(claims("https://partner.example.com", nil))
+	if user, err = manager.ValidateJWT(token); err != nil || !user.HasScope(auth.ScopeNewsRead) {
+		t.Errorf("Expected partner token to be accepted: %+v %v", user, err)
+	}
+
+	rejected := map[string]string{
+		"wrong audience": mustSign(t, identity, claims("https://identity.internal", func(c jwt.MapClaims) { c["aud"] = "other" })),
+		"expired":        mustSign(t, identity, claims("https://identity.internal", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+		"no expiration":  mustSign(t, identity, claims("https://identity.internal", func(c jwt.MapClaims) { delete(c, "exp") })),
+		// Ключ одного издателя не подходит для токенов другого
+		"foreign key":    mustSign(t, partner, claims("https://identity.internal", nil)),
+		"unknown issuer": mustSign(t, identity, claims("https://unknown.example.com", nil)),
+	}
+
+	// Общий секрет InfoHub не должен позволять подделать токен стороннего издателя
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims("https://identity.internal", nil)).SignedString([]byte("secret"))
+	rejected["symmetric signature"] = forged
+
+	for name, token := range rejected {
+		if _, err := manager.ValidateJWT(token); err == nil {
+			t.Errorf("Expected %s token to be rejected", name)
+		}
+	}
+}
+
+// TestTrustedIssuerAlgorithms проверяет токены ES384, ES512, PS384 и PS512 с ключами из JWKS
+func TestTrustedIssuerAlgorithms(t *testing.T) {
+	encode := base64.RawURLEncoding.EncodeToString
+	ecKey := func(curve elliptic.Curve, name string) (crypto.Signer, auth.JWK) {
+		key, _ := ecdsa.GenerateKey(curve, rand.Reader)
+		size := (curve.Params().BitSize + 7) / 8
+		return key, auth.JWK{
+			KeyType: "EC",
+			Curve:   name,
+			X:       encode(key.X.FillBytes(make([]byte, size))),
+			Y:       encode(key.Y.FillBytes(make([]byte, size))),
+		}
+	}
+	rsaKey := func() (crypto.Signer, auth.JWK) {
+		key, _ := rsa.GenerateKey(rand.Reader, 2048)
+		return key, auth.JWK{KeyType: "RSA", N: encode(key.N.Bytes()), E: encode(big.NewInt(int64(key.E)).Bytes())}
+	}
+
+	for algorithm, generate := range map[string]func() (crypto.Signer, auth.JWK){
+		"ES384": func() (crypto.Signer, auth.JWK) { return ecKey(elliptic.P384(), "P-384") },
+		"ES512": func() (crypto.Signer, auth.JWK) { return ecKey(elliptic.P521(), "P-521") },
+		"PS384": rsaKey,
+		"PS512": rsaKey,
+	} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, jwk := generate()
+			jwk.KeyID, jwk.Algorithm = "key-1", algorithm
+			jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(auth.JWKS{Keys: []auth.JWK{jwk}})
+			}))
+			defer jwksServer.Close()
+
+			manager, err := auth.NewManager(auth.Config{
+				JWTSecret: "secret",
+				Enabled:   true,
+				TrustedIssuers: []auth.IssuerConfig{{
+					Issuer:       "https://identity.internal",
+					Audience:     "infohub",
+					JWKSURL:      jwksServer.URL,
+					ClaimMapping: auth.ClaimMapping{DefaultScopes: []string{"news:read"}},
+				}},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create auth manager: %v", err)
+			}
+			defer manager.Close()
+
+			token := jwt.NewWithClaims(jwt.GetSigningMethod(algorithm), jwt.MapClaims{
+				"iss": "https://identity.internal",
+				"aud": "infohub",
+				"sub": "billing-service",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+			token.Header["kid"] = "key-1"
+			signed, err := token.SignedString(key)
+			if err != nil {
+				t.Fatalf("Failed to sign token: %v", err)
+			}
+			if _, err = manager.ValidateJWT(signed); err != nil {
+				t.Errorf("Expected %s token to be accepted: %v", algorithm, err)
+			}
+		})
+	}
+
+	_, err := auth.NewManager(auth.Config{
+		JWTSecret: "secret",
+		TrustedIssuers: []auth.IssuerConfig{{
+			Issuer:     "https://identity.internal",
+			Audience:   "infohub",
+			JWKSURL:    "https://identity.internal/jwks.json",
+			Algorithms: []string{"HS512"},
+		}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unsupported algorithm HS512") {
+		t.Errorf("Expected unsupported issuer algorithm to be rejected, got %v", err)
+	}
+}
+
+// TestJWKSClientCoalescesRefreshes проверяет, что одновременные запросы
+// ключа объединяются в одно обращение к издателю
+func TestJWKSClientCoalescesRefreshes(t *testing.T) {
+	identity, err := auth.NewKeyRing(auth.SigningConfig{Algorithm: auth.AlgorithmRS256}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create issuer keys: %v", err)
+	}
+	var requests atomic.Int32
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		json.NewEncoder(w).Encode(identity.JWKS())
+	}))
+	defer jwksServer.Close()
+
+	client := auth.NewJWKSClient(jwksServer.URL, nil, time.Hour)
+	kid := identity.JWKS().Keys[0].KeyID
+
+	// Отмена контекста одного из запросов не прерывает общее обновление
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		ctx := context.Background()
+		if i == 0 {
+			ctx = canceled
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Key(ctx, kid, "RS256")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected key to be resolved: %v", err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Expected a single JWKS request, got %d", n)
+	}
+}
+
+func mustSign(t *testing.T, ring *auth.KeyRing, claims jwt.Claims) string {
+	t.Helper()
+	token, err := ring.Sign(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+// TestSignedRequests проверяет подпись запросов HMAC, защиту от повтора и запрет ключа в query
+func TestSignedRequests(t *testing.T) {
+	const secret = "partner-signing-secret-0123456789abcdef"
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret:          "secret",
+		Enabled:            true,
+		APIKeys:            map[string]string{"reader-key": "Reader"},
+		DisableQueryAPIKey: true,
+		RequestSigning: auth.RequestSigningConfig{
+			Enabled: true,
+			Keys: map[string]auth.SigningKeyConfig{
+				"partner-1": {Secret: secret, Owner: "Partner", Scopes: []string{auth.ScopeIngest}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	signed := func(method, target, body, nonce string, at time.Time) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if err := auth.SignRequest(req, "partner-1", secret, nonce, at); err != nil {
+			t.Fatalf("Failed to sign request: %v", err)
+		}
+		return req
+	}
+
+	req := signed(http.MethodPost, "/api/v1/admin/import?mode=merge&dry_run=true", `[{"id":"1"}]`, "nonce-0000000001", time.Now())
+	user, err := manager.AuthenticateRequest(req)
+	if err != nil {
+		t.Fatalf("Expected signed request to be accepted: %v", err)
+	}
+	if user.ID != "Partner" || user.KeyID != "partner-1" || !user.HasScope(auth.ScopeIngest) || user.HasScope(auth.ScopeNewsRead) {
+		t.Errorf("Unexpected signed request user: %+v", user)
+	}
+	// Тело после проверки подписи доступно обработчику
+	if body, _ := io.ReadAll(req.Body); string(body) != `[{"id":"1"}]` {
+		t.Errorf("Expected request body to be preserved, got %q", body)
+	}
+
+	// Тот же запрос повторно не принимается
+	replay := signed(http.MethodPost, "/api/v1/admin/import?mode=merge&dry_run=true", `[{"id":"1"}]`, "nonce-0000000001", time.Now())
+	if _, err = manager.AuthenticateRequest(replay); !errors.Is(err, auth.ErrSignatureReplay) {
+		t.Errorf("Expected replayed request to be rejected, got %v", err)
+	}
+
+	// Одновременные копии одного запроса проходят ровно один раз
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	at := time.Now()
+	for i := 0; i < 20; i++ {
+		req := signed(http.MethodGet, "/api/v1/news", "", "nonce-concurrent-01", at)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := manager.AuthenticateRequest(req); err == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted.Load() != 1 {
+		t.Errorf("Expected exactly one of the concurrent copies to be accepted, got %d", accepted.Load())
+	}
+
+	tampered := map[string]*http.Request{
+		"stale timestamp": signed(http.MethodGet, "/api/v1/news", "", "nonce-0000000002", time.Now().Add(-10*time.Minute)),
+		"short nonce":     signed(http.MethodGet, "/api/v1/news", "", "short", time.Now()),
+	}
+	req = signed(http.MethodPost, "/api/v1/admin/import", `[{"id":"1"}]`, "nonce-0000000003", time.Now())
+	req.Body = io.NopCloser(strings.NewReader(`[{"id":"2"}]`))
+	tampered["modified body"] = req
+	req = signed(http.MethodGet, "/api/v1/news?limit=1", "", "nonce-0000000004", time.Now())
+	req.URL.RawQuery = "limit=1000"
+	tampered["modified query"] = req
+	req = signed(http.MethodGet, "/api/v1/news", "", "nonce-0000000005", time.Now())
+	req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), "partner-1", "partner-2", 1))
+	tampered["unknown key"] = req
+
+	for name, req := range tampered {
+		if _, err := manager.AuthenticateRequest(req); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+
+	// Ключ в query запрещен, в заголовке по-прежнему принимается
+	if _, err = manager.AuthenticateRequest(httptest.NewRequest(http.MethodGet, "/api/v1/news?api_key=reader-key", nil)); err == nil {
+		t.Error("Expected api_key query parameter to be rejected")
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/news", nil)
+	req.Header.Set("X-API-Key", "reader-key")
+	if _, err = manager.AuthenticateRequest(req); err != nil {
+		t.Errorf("Expected X-API-Key header to be accepted: %v", err)
+	}
+}
+
+// TestNonceSurvivesFlood проверяет, что поток подписанных запросов не вытесняет
+// использованные nonce и повтор раннего запроса по-прежнему отклоняется
+func TestNonceSurvivesFlood(t *testing.T) {
+	const secret = "partner-signing-secret-0123456789abcdef"
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret: "secret",
+		Enabled:   true,
+		RequestSigning: auth.RequestSigningConfig{
+			Enabled: true,
+			Keys:    map[string]auth.SigningKeyConfig{"partner-1": {Secret: secret}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	authStore, err := cache.NewAuthStore(cache.Config{}, false)
+	if err != nil {
+		t.Fatalf("Failed to create auth store: %v", err)
+	}
+	defer authStore.Close()
+	manager.SetTokenStore(authStore)
+
+	at := time.Now()
+	signed := func(nonce string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/news", nil)
+		if err := auth.SignRequest(req, "partner-1", secret, nonce, at); err != nil {
+			t.Fatalf("Failed to sign request: %v", err)
+		}
+		return req
+	}
+
+	// Больше запросов, чем max_entries кэша по умолчанию
+	for i := 0; i < 10500; i++ {
+		if _, err = manager.AuthenticateRequest(signed(fmt.Sprintf("flood-nonce-%06d", i))); err != nil {
+			t.Fatalf("Expected signed request %d to be accepted: %v", i, err)
+		}
+	}
+	if _, err = manager.AuthenticateRequest(signed("flood-nonce-000000")); !errors.Is(err, auth.ErrSignatureReplay) {
+		t.Errorf("Expected the first request to stay a replay after the flood, got %v", err)
+	}
+}
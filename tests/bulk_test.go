@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pah-an/infohub/internal/aggregator"
+	"github.com/pah-an/infohub/internal/bulk"
+	"github.com/pah-an/infohub/internal/domain"
+	"github.com/pah-an/infohub/internal/storage"
+)
+
+func newTestFileCache(t *testing.T) *storage.FileCache {
+	t.Helper()
+	fileCache, err := storage.NewFileCache(storage.FileConfig{Path: filepath.Join(t.TempDir(), "news_cache.json")})
+	if err != nil {
+		t.Fatalf("Failed to create file cache: %v", err)
+	}
+	return fileCache
+}
+
+// TestBulkRoundTrip проверяет, что выгрузка и загрузка сохраняют все поля
+// и что выгрузка из базы читает ее страницами целиком
+func TestBulkRoundTrip(t *testing.T) {
+	source, err := storage.NewSQLStore(storage.SQLConfig{Path: filepath.Join(t.TempDir(), "news.db")})
+	if err != nil {
+		t.Fatalf("Failed to open SQL store: %v", err)
+	}
+	defer source.Close()
+
+	// Больше двух страниц выгрузки
+	ids := make([]string, 1201)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("news-%04d", i)
+	}
+	news := testNews(ids...)
+	news[0].Description = "Quotes \"inside\", commas\nand a new line"
+	news[0].Category = "technology"
+	news[0].URL = "https://example.com/news?id=1&lang=ru"
+	if err = source.SaveNews(news); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+
+	for _, format := range []bulk.Format{bulk.FormatNDJSON, bulk.FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			count, err := bulk.Export(&buf, source, format, bulk.TimeRange{})
+			if err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+			if count != len(news) {
+				t.Fatalf("Expected %d exported news, got %d", len(news), count)
+			}
+
+			target := newTestFileCache(t)
+			result, err := bulk.Import(&buf, target, format)
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if result.Imported != len(news) || result.Total != len(news) {
+				t.Fatalf("Unexpected import result: %+v", result)
+			}
+
+			imported, _ := target.GetLatestNews(len(news))
+			byID := make(map[string]domain.News, len(imported))
+			for _, item := range imported {
+				byID[item.ID] = item
+			}
+			for _, item := range news {
+				got, ok := byID[item.ID]
+				if !ok {
+					t.Fatalf("News %s is missing after round trip", item.ID)
+				}
+				if got.Title != item.Title || got.Description != item.Description || got.URL != item.URL ||
+					got.Source != item.Source || got.Category != item.Category || !got.PublishedAt.Equal(item.PublishedAt) {
+					t.Fatalf("News %s changed after round trip: %+v != %+v", item.ID, got, item)
+				}
+			}
+		})
+	}
+
+	// Диапазон дат ограничивает выгрузку
+	base := news[0].PublishedAt
+	var buf bytes.Buffer
+	count, err := bulk.Export(&buf, source, bulk.FormatNDJSON, bulk.TimeRange{
+		From: base.Add(10 * time.Minute),
+		To:   base.Add(19 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 10 {
+		t.Errorf("Expected 10 news in time range, got %d", count)
+	}
+}
+
+// TestBulkImportMerge проверяет объединение импортируемых новостей с хранилищем по ID
+func TestBulkImportMerge(t *testing.T) {
+	store := newTestFileCache(t)
+	if err := store.SaveNews(testNews("a", "b")); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+
+	input := strings.Join([]string{
+		`{"id":"b","title":"Updated b","source":"Test Source","published_at":"2024-01-01T12:01:00Z"}`,
+		`{"id":"c","title":"First c","source":"Test Source","published_at":"2024-01-01T12:02:00Z"}`,
+		`{"id":"c","title":"Second c","source":"Test Source","published_at":"2024-01-01T12:02:00Z"}`,
+	}, "\n")
+
+	result, err := bulk.Import(strings.NewReader(input), store, bulk.FormatNDJSON)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 3 || result.Total != 3 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	news, _ := store.GetLatestNews(10)
+	titles := make(map[string]string, len(news))
+	for _, item := range news {
+		titles[item.ID] = item.Title
+	}
+	want := map[string]string{"a": "Title a", "b": "Updated b", "c": "Second c"}
+	if len(titles) != len(want) {
+		t.Fatalf("Expected %d news after merge, got %+v", len(want), titles)
+	}
+	for id, title := range want {
+		if titles[id] != title {
+			t.Errorf("News %s: expected title %q, got %q", id, title, titles[id])
+		}
+	}
+}
+
+// TestBulkImportRejectsMalformed проверяет отказ от некорректных записей
+func TestBulkImportRejectsMalformed(t *testing.T) {
+	cases := []struct {
+		name   string
+		format bulk.Format
+		input  string
+		want   string
+	}{
+		{"invalid json", bulk.FormatNDJSON, `{"id":"x","title":`, "record 1"},
+		{"missing id", bulk.FormatNDJSON, `{"title":"No id","published_at":"2024-01-01T12:00:00Z"}`, "id is required"},
+		{"missing date", bulk.FormatNDJSON, `{"id":"x","title":"No date"}`, "published_at is required"},
+		{"csv header", bulk.FormatCSV, "id,title\nx,Title\n", "CSV header"},
+		{"csv columns", bulk.FormatCSV, "id,title,description,url,source,category,published_at\nx,Title\n", "line 2"},
+		{"csv date", bulk.FormatCSV, "id,title,description,url,source,category,published_at\nx,Title,,,,,yesterday\n", "invalid published_at"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newTestFileCache(t)
+			result, err := bulk.Import(strings.NewReader(tc.input), store, tc.format)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Expected error containing %q, got %v", tc.want, err)
+			}
+			if result.Imported != 0 {
+				t.Errorf("Expected nothing to be imported, got %d", result.Imported)
+			}
+			if news, _ := store.GetLatestNews(10); len(news) != 0 {
+				t.Errorf("Expected storage to stay empty, got %d news", len(news))
+			}
+		})
+	}
+
+	// Пачки до некорректной записи уже сохранены, и это отражено в результате
+	var input strings.Builder
+	for i := 0; i < 600; i++ {
+		fmt.Fprintf(&input, `{"id":"n%d","title":"Title","published_at":"2024-01-01T12:00:00Z"}`+"\n", i)
+	}
+	input.WriteString(`{"id":"broken"}` + "\n")
+
+	store := newTestFileCache(t)
+	result, err := bulk.Import(strings.NewReader(input.String()), store, bulk.FormatNDJSON)
+	if err == nil || !strings.Contains(err.Error(), "record 601") {
+		t.Fatalf("Expected error for record 601, got %v", err)
+	}
+	news, _ := store.GetLatestNews(1000)
+	if result.Imported != len(news) || len(news) != 500 {
+		t.Errorf("Expected the first batch of 500 to be saved, imported %d, stored %d", result.Imported, len(news))
+	}
+}
+
+// TestImportSurvivesAggregation проверяет, что без архива агрегатор удаляет из
+// хранилища только вытесненные им новости, а импортированные записи старше
+// окна остаются
+func TestImportSurvivesAggregation(t *testing.T) {
+	store := newTestFileCache(t)
+
+	agg, err := aggregator.New(store, aggregator.RetentionConfig{MaxItems: 2})
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	deliver := startAggregator(t, agg)
+	deliver(testNews("new-1", "new-2"))
+
+	input := strings.Join([]string{
+		`{"id":"old-1","title":"Old 1","source":"Archive","published_at":"2023-01-01T12:00:00Z"}`,
+		`{"id":"old-2","title":"Old 2","source":"Archive","published_at":"2023-01-02T12:00:00Z"}`,
+	}, "\n")
+	if _, err = bulk.Import(strings.NewReader(input), store, bulk.FormatNDJSON); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if err = agg.LoadFromRepository(); err != nil {
+		t.Fatalf("Failed to reload news: %v", err)
+	}
+
+	latest := testNews("new-3")
+	latest[0].PublishedAt = latest[0].PublishedAt.Add(time.Hour)
+	deliver(latest)
+
+	news, _ := store.GetLatestNews(10)
+	stored := newsIDs(news)
+	for _, id := range []string{"old-1", "old-2", "new-2", "new-3"} {
+		if !stored[id] {
+			t.Errorf("Expected %s to stay in storage, got %v", id, stored)
+		}
+	}
+	if stored["new-1"] {
+		t.Errorf("Expected news evicted from the window to be deleted")
+	}
+}
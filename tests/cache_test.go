@@ -0,0 +1,641 @@
+package tests
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	miniredisserver "github.com/alicebob/miniredis/v2/server"
+
+	"github.com/pah-an/infohub/internal/cache"
+	"github.com/pah-an/infohub/internal/domain"
+)
+
+// countingRepository считает обращения к хранилищу и отвечает с задержкой
+type countingRepository struct {
+	calls atomic.Int32
+	delay time.Duration
+	news  domain.NewsList
+}
+
+func (r *countingRepository) GetLatestNews(limit int) (domain.NewsList, error) {
+	r.calls.Add(1)
+	time.Sleep(r.delay)
+	return r.news.LimitTo(limit), nil
+}
+
+func (r *countingRepository) SaveNews(news domain.NewsList) error {
+	r.news = news
+	return nil
+}
+
+// TestCachedRepositoryCoalescing проверяет, что одновременные промахи дают один запрос к хранилищу
+func TestCachedRepositoryCoalescing(t *testing.T) {
+	repo := &countingRepository{delay: 50 * time.Millisecond, news: testNews("a", "b")}
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{TTL: time.Minute})
+	defer memoryCache.Close()
+
+	cached := cache.NewCachedNewsRepository(memoryCache, repo, cache.RepositoryConfig{TTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if news, err := cached.GetLatestNews(10); err != nil || len(news) != 2 {
+				t.Errorf("Unexpected result: %d news, err %v", len(news), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := repo.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 repository call, got %d", calls)
+	}
+}
+
+// TestCachedRepositoryStaleWhileRevalidate проверяет выдачу устаревшего значения с фоновым обновлением
+func TestCachedRepositoryStaleWhileRevalidate(t *testing.T) {
+	repo := &countingRepository{news: testNews("a")}
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{TTL: time.Minute})
+	defer memoryCache.Close()
+
+	cached := cache.NewCachedNewsRepository(memoryCache, repo, cache.RepositoryConfig{
+		TTL:      20 * time.Millisecond,
+		StaleTTL: time.Minute,
+	})
+
+	if _, err := cached.GetLatestNews(10); err != nil {
+		t.Fatalf("Failed to read news: %v", err)
+	}
+
+	repo.delay = 50 * time.Millisecond
+	repo.news = testNews("a", "b")
+	time.Sleep(30 * time.Millisecond)
+
+	// Значение устарело, но возвращается сразу, без ожидания хранилища
+	start := time.Now()
+	news, _ := cached.GetLatestNews(10)
+	if len(news) != 1 {
+		t.Errorf("Expected stale value with 1 news, got %d", len(news))
+	}
+	if elapsed := time.Since(start); elapsed >= repo.delay {
+		t.Errorf("Stale read waited for repository: %v", elapsed)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	news, _ = cached.GetLatestNews(10)
+	if len(news) != 2 {
+		t.Errorf("Expected refreshed value with 2 news, got %d", len(news))
+	}
+}
+
+// TestCachedRepositoryInvalidation проверяет, что запись инвалидирует выборки с любым лимитом
+func TestCachedRepositoryInvalidation(t *testing.T) {
+	repo := &countingRepository{news: testNews("a")}
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{TTL: time.Minute})
+	defer memoryCache.Close()
+
+	cached := cache.NewCachedNewsRepository(memoryCache, repo, cache.RepositoryConfig{TTL: time.Minute})
+
+	if news, _ := cached.GetLatestNews(20); len(news) != 1 {
+		t.Fatalf("Expected 1 news, got %d", len(news))
+	}
+	if err := cached.SaveNews(testNews("a", "b")); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+	if news, _ := cached.GetLatestNews(20); len(news) != 2 {
+		t.Errorf("Expected fresh result with 2 news after save, got %d", len(news))
+	}
+}
+
+// statsRecorder считает события кэша
+type statsRecorder struct {
+	hits, misses, evictions int
+}
+
+func (r *statsRecorder) RecordCacheHit(string)              { r.hits++ }
+func (r *statsRecorder) RecordCacheMiss(string)             { r.misses++ }
+func (r *statsRecorder) RecordCacheEviction(string, string) { r.evictions++ }
+
+// TestMemoryCacheEviction проверяет вытеснение по политикам LRU и LFU
+func TestMemoryCacheEviction(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		eviction string
+		evicted  string
+	}{
+		// "a" читается последним, поэтому LRU вытесняет "b"
+		{eviction: cache.EvictionLRU, evicted: "b"},
+		// "b" читается дважды, а "a" один раз, поэтому LFU вытесняет "a"
+		{eviction: cache.EvictionLFU, evicted: "a"},
+	}
+
+	for _, tt := range tests {
+		memoryCache, err := cache.NewMemoryCache(cache.MemoryConfig{MaxEntries: 2, Eviction: tt.eviction})
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		recorder := &statsRecorder{}
+		memoryCache.SetStatsRecorder(recorder)
+
+		var value string
+		memoryCache.Set(ctx, "a", "A", 0)
+		memoryCache.Set(ctx, "b", "B", 0)
+		memoryCache.Get(ctx, "b", &value)
+		memoryCache.Get(ctx, "b", &value)
+		memoryCache.Get(ctx, "a", &value)
+		memoryCache.Set(ctx, "c", "C", 0)
+
+		if err = memoryCache.Get(ctx, tt.evicted, &value); err != cache.ErrCacheMiss {
+			t.Errorf("%s: expected %q to be evicted", tt.eviction, tt.evicted)
+		}
+		if err = memoryCache.Get(ctx, "c", &value); err != nil || value != "C" {
+			t.Errorf("%s: expected new value to be cached, got %q (%v)", tt.eviction, value, err)
+		}
+		if recorder.hits != 4 || recorder.misses != 1 || recorder.evictions != 1 {
+			t.Errorf("%s: unexpected stats %+v", tt.eviction, *recorder)
+		}
+		memoryCache.Close()
+	}
+}
+
+// TestMemoryCacheStoresCopies проверяет, что изменение исходного значения не меняет кэш
+func TestMemoryCacheStoresCopies(t *testing.T) {
+	ctx := context.Background()
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{MaxBytes: 1024})
+	defer memoryCache.Close()
+
+	news := testNews("a")
+	memoryCache.Set(ctx, "news", news, 0)
+	news[0].Title = "changed"
+
+	var cached domain.NewsList
+	if err := memoryCache.Get(ctx, "news", &cached); err != nil {
+		t.Fatalf("Failed to read cached value: %v", err)
+	}
+	if cached[0].Title != "Title a" {
+		t.Errorf("Cached value was mutated: %q", cached[0].Title)
+	}
+
+	if err := memoryCache.Set(ctx, "large", make([]byte, 2048), 0); err != cache.ErrValueTooLarge {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+}
+
+// TestTieredCacheInvalidation проверяет, что запись на одной реплике сбрасывает L1 на другой
+func TestTieredCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	newReplica := func() *cache.TieredCache {
+		redisCache, err := cache.NewRedisCache(cache.Config{Address: server.Addr()})
+		if err != nil {
+			t.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+		tiered, err := cache.NewTieredCache(memoryCache, redisCache, cache.TieredConfig{L1TTL: time.Minute})
+		if err != nil {
+			t.Fatalf("Failed to create tiered cache: %v", err)
+		}
+		return tiered
+	}
+
+	first, second := newReplica(), newReplica()
+	defer first.Close()
+	defer second.Close()
+
+	var value string
+	first.Set(ctx, "key", "v1", time.Minute)
+	if err := second.Get(ctx, "key", &value); err != nil || value != "v1" {
+		t.Fatalf("Expected v1 from L2, got %q (%v)", value, err)
+	}
+
+	// Теперь "v1" лежит в L1 второй реплики
+	first.Set(ctx, "key", "v2", time.Minute)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if err := second.Get(ctx, "key", &value); err == nil && value == "v2" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Second replica still serves stale value %q", value)
+}
+
+// TestTieredCacheInvalidationDuringRead проверяет, что значение, прочитанное
+// из L2 до пришедшей во время чтения инвалидации, не остается в L1
+func TestTieredCacheInvalidationDuringRead(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	newReplica := func() *cache.TieredCache {
+		redisCache, err := cache.NewRedisCache(cache.Config{Address: server.Addr()})
+		if err != nil {
+			t.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+		tiered, err := cache.NewTieredCache(memoryCache, redisCache, cache.TieredConfig{L1TTL: time.Minute})
+		if err != nil {
+			t.Fatalf("Failed to create tiered cache: %v", err)
+		}
+		return tiered
+	}
+
+	writer, reader := newReplica(), newReplica()
+	defer writer.Close()
+	defer reader.Close()
+
+	writer.Set(ctx, "key", "v1", time.Minute)
+
+	// Get читает значение из L2, а затем его TTL. Задерживаем ответ на TTL,
+	// чтобы запись и инвалидация произошли между чтением и сохранением в L1
+	held, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	server.Server().SetPreHook(func(_ *miniredisserver.Peer, cmd string, _ ...string) bool {
+		if cmd == "TTL" {
+			once.Do(func() {
+				close(held)
+				<-release
+			})
+		}
+		return false
+	})
+
+	done := make(chan string)
+	go func() {
+		var value string
+		reader.Get(ctx, "key", &value)
+		done <- value
+	}()
+
+	<-held
+	writer.Set(ctx, "key", "v2", time.Minute)
+	// Инвалидация доставляется асинхронно
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	if value := <-done; value != "v1" {
+		t.Fatalf("Expected the in-flight read to return v1, got %q", value)
+	}
+
+	var value string
+	if err := reader.Get(ctx, "key", &value); err != nil || value != "v2" {
+		t.Errorf("Expected v2 after invalidation, got %q (%v)", value, err)
+	}
+}
+
+// TestRedisCacheBulkOperations проверяет пакетные операции и обход ключей через SCAN
+func TestRedisCacheBulkOperations(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	redisCache, err := cache.NewRedisCache(cache.Config{Address: server.Addr()})
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisCache.Close()
+
+	values := map[string]interface{}{"other": "x"}
+	for i := 0; i < 1200; i++ {
+		values[fmt.Sprintf("news:%d", i)] = i
+	}
+	if err = redisCache.MSet(ctx, values, time.Minute); err != nil {
+		t.Fatalf("MSet failed: %v", err)
+	}
+
+	keys, err := redisCache.Keys(ctx, "news:*")
+	if err != nil || len(keys) != 1200 {
+		t.Fatalf("Expected 1200 keys, got %d (%v)", len(keys), err)
+	}
+
+	found, err := redisCache.MGet(ctx, []string{"news:7", "missing"})
+	if err != nil || len(found) != 1 || string(found["news:7"]) != "7" {
+		t.Errorf("Unexpected MGet result: %v (%v)", found, err)
+	}
+
+	deleted, err := redisCache.DeleteByPattern(ctx, "news:*")
+	if err != nil || deleted != 1200 {
+		t.Errorf("Expected 1200 deleted keys, got %d (%v)", deleted, err)
+	}
+	if keys, _ = redisCache.Keys(ctx, "*"); len(keys) != 1 || keys[0] != "other" {
+		t.Errorf("Unexpected remaining keys: %v", keys)
+	}
+}
+
+// TestRedisCacheCodecs проверяет чтение значений, записанных с разными кодеками и сжатием
+func TestRedisCacheCodecs(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	reader, err := cache.NewRedisCache(cache.Config{Address: server.Addr()})
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer reader.Close()
+
+	news := testNews("a", "b", "c")
+	for _, codec := range []string{cache.CodecJSON, cache.CodecMsgPack, cache.CodecGob} {
+		for _, compression := range []string{cache.CompressionNone, cache.CompressionGzip, cache.CompressionZstd} {
+			writer, err := cache.NewRedisCache(cache.Config{
+				Address: server.Addr(),
+				Serialization: cache.SerializationConfig{
+					Codec:                codec,
+					Compression:          compression,
+					CompressionThreshold: 16,
+				},
+			})
+			if err != nil {
+				t.Fatalf("%s/%s: failed to create cache: %v", codec, compression, err)
+			}
+
+			if err = writer.Set(ctx, "news", news, time.Minute); err != nil {
+				t.Fatalf("%s/%s: failed to set value: %v", codec, compression, err)
+			}
+			writer.Close()
+
+			var cached domain.NewsList
+			if err = reader.Get(ctx, "news", &cached); err != nil {
+				t.Fatalf("%s/%s: failed to read value: %v", codec, compression, err)
+			}
+			if len(cached) != 3 || cached[1].ID != "b" || !cached[1].PublishedAt.Equal(news[1].PublishedAt) {
+				t.Errorf("%s/%s: unexpected value %+v", codec, compression, cached)
+			}
+		}
+	}
+
+	// Значения без заголовка, записанные до появления кодеков, читаются как JSON
+	server.Set("infohub:legacy", `["x","y"]`)
+	var legacy []string
+	if err = reader.Get(ctx, "legacy", &legacy); err != nil || len(legacy) != 2 {
+		t.Errorf("Failed to read legacy value: %v (%v)", legacy, err)
+	}
+}
+
+// TestCacheSetNX проверяет атомарную запись только отсутствующего ключа
+func TestCacheSetNX(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+	redisCache, err := cache.NewRedisCache(cache.Config{Address: server.Addr()})
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	defer redisCache.Close()
+
+	for name, store := range map[string]cache.Cache{"memory": memoryCache, "redis": redisCache} {
+		var stored atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ok, err := store.SetNX(ctx, "nonce", i, 50*time.Millisecond); err == nil && ok {
+					stored.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if stored.Load() != 1 {
+			t.Errorf("%s: expected exactly one SetNX to succeed, got %d", name, stored.Load())
+		}
+	}
+
+	// Истекший ключ снова можно занять
+	time.Sleep(60 * time.Millisecond)
+	server.FastForward(time.Second)
+	for name, store := range map[string]cache.Cache{"memory": memoryCache, "redis": redisCache} {
+		if ok, err := store.SetNX(ctx, "nonce", 1, time.Minute); err != nil || !ok {
+			t.Errorf("%s: expected expired key to be replaced, got %v (%v)", name, ok, err)
+		}
+	}
+}
+
+// TestRedisConnectionModes проверяет выбор режима подключения и ACL авторизацию
+func TestRedisConnectionModes(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := cache.NewRedisCache(cache.Config{Mode: "replica", Address: "127.0.0.1:1"}); err == nil || !strings.Contains(err.Error(), "unsupported redis mode") {
+		t.Errorf("Expected unknown mode to be rejected, got %v", err)
+	}
+	if _, err := cache.NewRedisCache(cache.Config{Mode: cache.RedisModeSentinel, Address: "127.0.0.1:1"}); err == nil || !strings.Contains(err.Error(), "master_name") {
+		t.Errorf("Expected sentinel mode without master_name to be rejected, got %v", err)
+	}
+
+	master := miniredis.RunT(t)
+	master.RequireUserAuth("infohub", "master-secret")
+
+	t.Run("single", func(t *testing.T) {
+		if _, err := cache.NewRedisCache(cache.Config{Address: master.Addr(), Username: "infohub", Password: "wrong"}); err == nil {
+			t.Fatalf("Expected wrong password to be rejected")
+		}
+
+		redisCache, err := cache.NewRedisCache(cache.Config{
+			Mode:     cache.RedisModeSingle,
+			Address:  master.Addr(),
+			Username: "infohub",
+			Password: "master-secret",
+		})
+		if err != nil {
+			t.Fatalf("Failed to connect with ACL credentials: %v", err)
+		}
+		defer redisCache.Close()
+
+		if err = redisCache.Set(ctx, "single", "value", time.Minute); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if !master.Exists("infohub:single") {
+			t.Errorf("Expected key to be written to the server")
+		}
+	})
+
+	t.Run("sentinel", func(t *testing.T) {
+		// Sentinel отвечает адресом мастера; остальные команды обрабатывает miniredis
+		sentinel := miniredis.RunT(t)
+		sentinel.RequireAuth("sentinel-secret")
+		var requested atomic.Value
+		sentinel.Server().SetPreHook(func(peer *miniredisserver.Peer, cmd string, args ...string) bool {
+			if cmd != "SENTINEL" || len(args) < 2 {
+				return false
+			}
+			switch strings.ToLower(args[0]) {
+			case "get-master-addr-by-name":
+				requested.Store(args[1])
+				peer.WriteStrings([]string{master.Host(), master.Port()})
+			default:
+				peer.WriteLen(0)
+			}
+			return true
+		})
+
+		redisCache, err := cache.NewRedisCache(cache.Config{
+			Mode:             cache.RedisModeSentinel,
+			Addresses:        []string{sentinel.Addr()},
+			MasterName:       "infohub-master",
+			Username:         "infohub",
+			Password:         "master-secret",
+			SentinelPassword: "sentinel-secret",
+		})
+		if err != nil {
+			t.Fatalf("Failed to connect through sentinel: %v", err)
+		}
+		defer redisCache.Close()
+
+		if requested.Load() != "infohub-master" {
+			t.Errorf("Expected master name to be requested from sentinel, got %v", requested.Load())
+		}
+		if err = redisCache.Set(ctx, "sentinel", "value", time.Minute); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+		if !master.Exists("infohub:sentinel") {
+			t.Errorf("Expected key to be written to the master")
+		}
+	})
+}
+
+// TestRedisTLS проверяет TLS подключение с CA, клиентским сертификатом и server_name из файлов
+func TestRedisTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert := ca.issue(t, func(c *x509.Certificate) {
+		c.DNSNames = []string{"redis.internal"}
+		c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	})
+	clientCert := ca.issue(t, func(c *x509.Certificate) {
+		c.Subject = pkix.Name{CommonName: "infohub"}
+		c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	})
+
+	caPath := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600)
+	certPath, keyPath := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, clientCert, certPath, keyPath)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server := miniredis.NewMiniRedis()
+	if err := server.StartTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}); err != nil {
+		t.Fatalf("Failed to start TLS server: %v", err)
+	}
+	defer server.Close()
+
+	config := cache.Config{
+		Address: server.Addr(),
+		TLS: cache.TLSConfig{
+			Enabled:    true,
+			CAFile:     caPath,
+			CertFile:   certPath,
+			KeyFile:    keyPath,
+			ServerName: "redis.internal",
+		},
+	}
+	redisCache, err := cache.NewRedisCache(config)
+	if err != nil {
+		t.Fatalf("Failed to connect over TLS: %v", err)
+	}
+	redisCache.Close()
+
+	withoutCert := config
+	withoutCert.TLS.CertFile, withoutCert.TLS.KeyFile = "", ""
+	if _, err = cache.NewRedisCache(withoutCert); err == nil {
+		t.Errorf("Expected connection without client certificate to be rejected")
+	}
+
+	wrongName := config
+	wrongName.TLS.ServerName = "other.internal"
+	if _, err = cache.NewRedisCache(wrongName); err == nil {
+		t.Errorf("Expected certificate for another server name to be rejected")
+	}
+
+	emptyCA := config
+	emptyCA.TLS.CAFile = keyPath
+	if _, err = cache.NewRedisCache(emptyCA); err == nil || !strings.Contains(err.Error(), "no certificates found") {
+		t.Errorf("Expected CA file without certificates to be rejected, got %v", err)
+	}
+}
+
+// TestRedisClusterScan проверяет, что в режиме кластера ключи обходятся на каждом мастере
+func TestRedisClusterScan(t *testing.T) {
+	ctx := context.Background()
+	nodes := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+
+	// Каждый узел отвечает на CLUSTER SLOTS одинаковой картой из двух половин
+	slots := func(peer *miniredisserver.Peer) {
+		peer.WriteLen(len(nodes))
+		for i, node := range nodes {
+			port, _ := strconv.Atoi(node.Port())
+			peer.WriteLen(3)
+			peer.WriteInt(i * 8192)
+			peer.WriteInt(i*8192 + 8191)
+			peer.WriteLen(2)
+			peer.WriteBulk(node.Host())
+			peer.WriteInt(port)
+		}
+	}
+	for _, node := range nodes {
+		node.Server().SetPreHook(func(peer *miniredisserver.Peer, cmd string, args ...string) bool {
+			if cmd == "CLUSTER" && len(args) > 0 && strings.EqualFold(args[0], "SLOTS") {
+				slots(peer)
+				return true
+			}
+			return false
+		})
+	}
+
+	redisCache, err := cache.NewRedisCache(cache.Config{
+		Mode:      cache.RedisModeCluster,
+		Addresses: []string{nodes[0].Addr(), nodes[1].Addr()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect to cluster: %v", err)
+	}
+	defer redisCache.Close()
+
+	values := make(map[string]interface{})
+	for i := 0; i < 200; i++ {
+		values[fmt.Sprintf("news:%d", i)] = i
+	}
+	if err = redisCache.MSet(ctx, values, time.Minute); err != nil {
+		t.Fatalf("MSet failed: %v", err)
+	}
+	for i, node := range nodes {
+		if len(node.Keys()) == 0 {
+			t.Fatalf("Expected keys to be spread across masters, node %d is empty", i)
+		}
+	}
+
+	keys, err := redisCache.Keys(ctx, "news:*")
+	if err != nil || len(keys) != 200 {
+		t.Fatalf("Expected 200 keys from all masters, got %d (%v)", len(keys), err)
+	}
+
+	deleted, err := redisCache.DeleteByPattern(ctx, "news:*")
+	if err != nil || deleted != 200 {
+		t.Errorf("Expected 200 deleted keys, got %d (%v)", deleted, err)
+	}
+	for i, node := range nodes {
+		if keys := node.Keys(); len(keys) != 0 {
+			t.Errorf("Expected node %d to be empty, got %v", i, keys)
+		}
+	}
+}
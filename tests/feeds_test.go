@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pah-an/infohub/internal/domain"
+	"github.com/pah-an/infohub/internal/logger"
+	"github.com/pah-an/infohub/internal/server"
+)
+
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title       string `xml:"title"`
+		Description string `xml:"description"`
+		// link канала и atom:link со ссылкой на саму ленту различаются пространством имен
+		Links []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+			Href    string `xml:"href,attr"`
+			Rel     string `xml:"rel,attr"`
+		} `xml:"link"`
+		Items []struct {
+			Title    string `xml:"title"`
+			Link     string `xml:"link"`
+			GUID     string `xml:"guid"`
+			PubDate  string `xml:"pubDate"`
+			Category string `xml:"category"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomDocument struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Links   []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Author  struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+	} `xml:"entry"`
+}
+
+type jsonFeedDocument struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	FeedURL string `json:"feed_url"`
+	Items   []struct {
+		ID            string   `json:"id"`
+		Title         string   `json:"title"`
+		ContentText   *string  `json:"content_text"`
+		DatePublished string   `json:"date_published"`
+		Tags          []string `json:"tags"`
+	} `json:"items"`
+}
+
+func newFeedServer(trustedProxies []netip.Prefix) http.Handler {
+	now := time.Now().UTC().Truncate(time.Second)
+	provider := &MockNewsProvider{news: domain.NewsList{
+		{ID: "a1", Title: "Alpha <1>", Description: "First & foremost", URL: "https://example.com/a1", Source: "Alpha", Category: "tech", PublishedAt: now},
+		{ID: "b1", Title: "Beta 1", Description: "Beta news", URL: "https://example.com/b1", Source: "Beta", Category: "sports", PublishedAt: now.Add(-time.Hour)},
+		{ID: "a2", Title: "Alpha 2", Source: "Alpha", Category: "sports", PublishedAt: now.Add(-2 * time.Hour)},
+	}}
+
+	return server.NewInfoHubServer(server.Config{
+		NewsProvider:   provider,
+		Logger:         logger.New(logger.Config{Level: "error"}),
+		TrustedProxies: trustedProxies,
+	}).Handler()
+}
+
+func getFeed(t *testing.T, handler http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestFeedFormats проверяет обязательные элементы RSS 2.0, Atom 1.0 и JSON Feed 1.1
+func TestFeedFormats(t *testing.T) {
+	handler := newFeedServer(nil)
+
+	t.Run("rss", func(t *testing.T) {
+		rec := getFeed(t, handler, "/api/v1/feeds/rss", nil)
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/rss+xml") {
+			t.Fatalf("Unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+
+		var doc rssFeed
+		if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("Invalid RSS document: %v", err)
+		}
+		channel := doc.Channel
+		var link, self string
+		for _, l := range channel.Links {
+			if l.XMLName.Space == "" {
+				link = l.Value
+			} else if l.Rel == "self" {
+				self = l.Href
+			}
+		}
+		if doc.Version != "2.0" || channel.Title == "" || link == "" || channel.Description == "" {
+			t.Errorf("RSS channel lacks required elements: %+v", doc)
+		}
+		if self != "http://example.com/api/v1/feeds/rss" {
+			t.Errorf("Unexpected self link: %q", self)
+		}
+		if len(channel.Items) != 3 {
+			t.Fatalf("Expected 3 items, got %d", len(channel.Items))
+		}
+		first := channel.Items[0]
+		if first.Title != "Alpha <1>" || first.Link != "https://example.com/a1" || first.GUID != "urn:infohub:news:a1" {
+			t.Errorf("Unexpected first item: %+v", first)
+		}
+		for _, item := range channel.Items {
+			if _, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil {
+				t.Errorf("Invalid pubDate %q: %v", item.PubDate, err)
+			}
+		}
+	})
+
+	t.Run("atom", func(t *testing.T) {
+		rec := getFeed(t, handler, "/api/v1/feeds/atom", nil)
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/atom+xml") {
+			t.Fatalf("Unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+
+		var doc atomDocument
+		if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("Invalid Atom document: %v", err)
+		}
+		if doc.ID == "" || doc.Title == "" {
+			t.Errorf("Atom feed lacks id or title: %+v", doc)
+		}
+		if _, err := time.Parse(time.RFC3339, doc.Updated); err != nil {
+			t.Errorf("Invalid feed updated %q: %v", doc.Updated, err)
+		}
+		hasSelf := false
+		for _, link := range doc.Links {
+			hasSelf = hasSelf || link.Rel == "self"
+		}
+		if !hasSelf {
+			t.Errorf("Atom feed lacks a self link: %+v", doc.Links)
+		}
+		if len(doc.Entries) != 3 {
+			t.Fatalf("Expected 3 entries, got %d", len(doc.Entries))
+		}
+		for _, entry := range doc.Entries {
+			if entry.ID == "" || entry.Title == "" || entry.Author.Name == "" {
+				t.Errorf("Atom entry lacks required elements: %+v", entry)
+			}
+			if _, err := time.Parse(time.RFC3339, entry.Updated); err != nil {
+				t.Errorf("Invalid entry updated %q: %v", entry.Updated, err)
+			}
+		}
+	})
+
+	t.Run("jsonfeed", func(t *testing.T) {
+		rec := getFeed(t, handler, "/api/v1/feeds/jsonfeed", nil)
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/feed+json") {
+			t.Fatalf("Unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+
+		var doc jsonFeedDocument
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("Invalid JSON Feed document: %v", err)
+		}
+		if doc.Version != "https://jsonfeed.org/version/1.1" || doc.Title == "" {
+			t.Errorf("JSON Feed lacks version or title: %+v", doc)
+		}
+		if len(doc.Items) != 3 {
+			t.Fatalf("Expected 3 items, got %d", len(doc.Items))
+		}
+		for _, item := range doc.Items {
+			// Каждый элемент обязан содержать id и content_text или content_html
+			if item.ID == "" || item.ContentText == nil {
+				t.Errorf("JSON Feed item lacks required fields: %+v", item)
+			}
+			if _, err := time.Parse(time.RFC3339, item.DatePublished); err != nil {
+				t.Errorf("Invalid date_published %q: %v", item.DatePublished, err)
+			}
+		}
+	})
+
+	if rec := getFeed(t, handler, "/api/v1/feeds/yaml", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown format to return 404, got %d", rec.Code)
+	}
+}
+
+// TestFeedRoutes проверяет ленты по источнику и по категории
+func TestFeedRoutes(t *testing.T) {
+	handler := newFeedServer(nil)
+
+	rec := getFeed(t, handler, "/api/v1/feeds/rss/sources/Alpha", nil)
+	var doc rssFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid RSS document: %v", err)
+	}
+	if doc.Channel.Title != "InfoHub: Alpha" || len(doc.Channel.Items) != 2 {
+		t.Errorf("Unexpected source feed: %q with %d items", doc.Channel.Title, len(doc.Channel.Items))
+	}
+	for _, item := range doc.Channel.Items {
+		if !strings.HasPrefix(item.GUID, "urn:infohub:news:a") {
+			t.Errorf("Source feed contains foreign item %s", item.GUID)
+		}
+	}
+
+	rec = getFeed(t, handler, "/api/v1/feeds/jsonfeed/categories/sports?limit=1", nil)
+	var feed jsonFeedDocument
+	if err := json.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("Invalid JSON Feed document: %v", err)
+	}
+	if feed.Title != "InfoHub: sports" || len(feed.Items) != 1 || feed.Items[0].ID != "urn:infohub:news:b1" {
+		t.Errorf("Unexpected category feed: %+v", feed)
+	}
+	if feed.FeedURL != "http://example.com/api/v1/feeds/jsonfeed/categories/sports?limit=1" {
+		t.Errorf("Unexpected feed_url: %q", feed.FeedURL)
+	}
+}
+
+// TestFeedForwardedProto проверяет, что схема из X-Forwarded-Proto
+// принимается только от доверенного прокси
+func TestFeedForwardedProto(t *testing.T) {
+	forwarded := http.Header{"X-Forwarded-Proto": {"https"}}
+
+	selfLink := func(handler http.Handler, header http.Header) string {
+		var doc jsonFeedDocument
+		json.Unmarshal(getFeed(t, handler, "/api/v1/feeds/jsonfeed", header).Body.Bytes(), &doc)
+		return doc.FeedURL
+	}
+
+	if link := selfLink(newFeedServer(nil), forwarded); !strings.HasPrefix(link, "http://") {
+		t.Errorf("Expected X-Forwarded-Proto from an untrusted client to be ignored, got %q", link)
+	}
+
+	// httptest.NewRequest использует адрес клиента 192.0.2.1
+	trusted := newFeedServer([]netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")})
+	if link := selfLink(trusted, forwarded); !strings.HasPrefix(link, "https://") {
+		t.Errorf("Expected X-Forwarded-Proto from a trusted proxy to be honoured, got %q", link)
+	}
+	if link := selfLink(trusted, http.Header{"X-Forwarded-Proto": {"javascript"}}); !strings.HasPrefix(link, "http://") {
+		t.Errorf("Expected unknown scheme to be ignored, got %q", link)
+	}
+}
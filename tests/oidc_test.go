@@ -0,0 +1,275 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/cache"
+	"github.com/pah-an/infohub/internal/logger"
+	"github.com/pah-an/infohub/internal/server"
+)
+
+const (
+	oidcClientID    = "infohub-admin"
+	oidcRedirectURL = "http://example.com/admin/callback"
+)
+
+// mockIssuer - минимальный OpenID Connect провайдер для тестов
+type mockIssuer struct {
+	*httptest.Server
+	t      *testing.T
+	keys   *auth.KeyRing
+	groups []string
+	codes  map[string]url.Values
+	mutex  sync.Mutex
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	keys, err := auth.NewKeyRing(auth.SigningConfig{Algorithm: auth.AlgorithmES256}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create issuer keys: %v", err)
+	}
+
+	issuer := &mockIssuer{t: t, keys: keys, codes: make(map[string]url.Values)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(issuer.keys.JWKS())
+	})
+	mux.HandleFunc("/authorize", issuer.authorize)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+
+	return issuer
+}
+
+// authorize сразу "входит" пользователем и возвращает код
+func (m *mockIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != oidcClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	m.mutex.Lock()
+	code := "code-" + query.Get("state")
+	m.codes[code] = query
+	m.mutex.Unlock()
+
+	http.Redirect(w, r, query.Get("redirect_uri")+"?code="+code+"&state="+query.Get("state"), http.StatusFound)
+}
+
+// token проверяет PKCE verifier и выдает подписанный ID токен
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	m.mutex.Lock()
+	request, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mutex.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != request.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := m.keys.Sign(jwt.MapClaims{
+		"iss":    m.URL,
+		"aud":    oidcClientID,
+		"sub":    "user-1",
+		"email":  "staff@example.com",
+		"groups": m.groups,
+		"nonce":  request.Get("nonce"),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		m.t.Errorf("Failed to sign id token: %v", err)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "access_token": "opaque"})
+}
+
+// TestOIDCAdminLogin проверяет вход в админ-панель через OIDC с PKCE и доступ по cookie сессии
+func TestOIDCAdminLogin(t *testing.T) {
+	issuer := newMockIssuer(t)
+
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret: "secret",
+		Enabled:   true,
+		OIDC: auth.OIDCConfig{
+			Enabled:     true,
+			Issuer:      issuer.URL,
+			ClientID:    oidcClientID,
+			RedirectURL: oidcRedirectURL,
+			ClaimMapping: auth.ClaimMapping{
+				GroupScopes: map[string][]string{"operators": {"admin:panel", "admin:cache"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+	authStore, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer authStore.Close()
+	manager.SetTokenStore(authStore)
+
+	handler := server.NewInfoHubServer(server.Config{
+		NewsProvider: NewMockNewsProvider(),
+		Cache:        memoryCache,
+		Logger:       logger.New(logger.Config{Level: "error"}),
+		AuthManager:  manager,
+	}).Handler()
+
+	request := func(method, target string, cookies []*http.Cookie, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// begin проходит вход до возврата от провайдера
+	begin := func(groups ...string) (string, []*http.Cookie) {
+		issuer.groups = groups
+
+		rec := request(http.MethodGet, "/admin/", nil, nil)
+		if rec.Code != http.StatusFound || !strings.HasPrefix(rec.Header().Get("Location"), "/admin/login") {
+			t.Fatalf("Expected redirect to login, got %d %q", rec.Code, rec.Header().Get("Location"))
+		}
+
+		rec = request(http.MethodGet, rec.Header().Get("Location"), nil, nil)
+		if rec.Code != http.StatusFound {
+			t.Fatalf("Expected redirect to issuer, got %d", rec.Code)
+		}
+		stateCookies := rec.Result().Cookies()
+
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.Get(rec.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("Authorization request failed: %v", err)
+		}
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+		return callback.RequestURI(), stateCookies
+	}
+
+	login := func(groups ...string) *http.Cookie {
+		callback, stateCookies := begin(groups...)
+		rec := request(http.MethodGet, callback, stateCookies, nil)
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/admin/" {
+			t.Fatalf("Expected redirect back to panel, got %d %s", rec.Code, rec.Body.String())
+		}
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == auth.SessionCookieName && cookie.Value != "" {
+				if !cookie.HttpOnly {
+					t.Errorf("Session cookie must be HttpOnly")
+				}
+				return cookie
+			}
+		}
+		t.Fatalf("No session cookie after login")
+		return nil
+	}
+
+	session := []*http.Cookie{login("operators")}
+	if rec := request(http.MethodGet, "/admin/", session, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected admin panel with session, got %d", rec.Code)
+	}
+	if rec := request(http.MethodGet, "/api/v1/admin/cache/keys", session, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected group scope to allow cache inspection, got %d", rec.Code)
+	}
+	if rec := request(http.MethodGet, "/api/v1/admin/keys", session, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected missing scope to be rejected, got %d", rec.Code)
+	}
+
+	// Изменяющие запросы по cookie принимаются только со своего origin
+	if rec := request(http.MethodPost, "/api/v1/admin/cache/clear", session, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected POST without Origin to be rejected, got %d", rec.Code)
+	}
+	origin := http.Header{"Origin": {"http://example.com"}}
+	if rec := request(http.MethodPost, "/api/v1/admin/cache/clear", session, origin); rec.Code != http.StatusOK {
+		t.Errorf("Expected same-origin POST to be accepted, got %d", rec.Code)
+	}
+
+	// Хранилище содержит только хэш идентификатора сессии
+	keys, _ := authStore.Keys(context.Background(), "*")
+	for _, key := range keys {
+		if strings.Contains(key, session[0].Value) {
+			t.Errorf("Session ID is stored in plain text: %s", key)
+		}
+	}
+
+	// Из одновременных возвратов с одним state проходит один, остальные
+	// отклоняются еще до обмена кода у провайдера
+	callback, stateCookies := begin("operators")
+	var completed, rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch request(http.MethodGet, callback, stateCookies, nil).Code {
+			case http.StatusFound:
+				completed.Add(1)
+			case http.StatusBadRequest:
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if completed.Load() != 1 || rejected.Load() != 9 {
+		t.Errorf("Expected one callback to complete and 9 to be rejected, got %d and %d", completed.Load(), rejected.Load())
+	}
+
+	// Выход только POST со своего origin
+	request(http.MethodGet, "/admin/logout", session, nil)
+	request(http.MethodPost, "/admin/logout", session, http.Header{"Origin": {"http://evil.example"}})
+	if rec := request(http.MethodGet, "/admin/", session, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected GET and cross-origin logout to be ignored, got %d", rec.Code)
+	}
+	request(http.MethodPost, "/admin/logout", session, origin)
+	if rec := request(http.MethodGet, "/admin/", session, nil); rec.Code != http.StatusFound {
+		t.Errorf("Expected session to end after logout, got %d", rec.Code)
+	}
+
+	// Пользователь без групп входит, но не получает доступа к панели
+	if rec := request(http.MethodGet, "/admin/", []*http.Cookie{login()}, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected user without groups to be forbidden, got %d", rec.Code)
+	}
+
+	// Состояние входа одноразовое и привязано к браузеру
+	rec := request(http.MethodGet, "/admin/callback?code=code-x&state=x", nil, nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected callback without state cookie to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,434 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pah-an/infohub/internal/domain"
+	"github.com/pah-an/infohub/internal/storage"
+)
+
+func testNews(ids ...string) domain.NewsList {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	news := make(domain.NewsList, 0, len(ids))
+	for i, id := range ids {
+		news = append(news, domain.News{
+			ID:          id,
+			Title:       "Title " + id,
+			Source:      "Test Source",
+			PublishedAt: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return news
+}
+
+// TestWALRecovery проверяет восстановление состояния журнала после перезапуска
+func TestWALRecovery(t *testing.T) {
+	dir := t.TempDir()
+	config := storage.WALConfig{Dir: dir, SyncPolicy: storage.SyncAlways, CompactInterval: time.Hour}
+
+	wal, err := storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+
+	if err = wal.SaveNews(testNews("a", "b", "c")); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+	// Второе сохранение удаляет "a" и добавляет "d"
+	if err = wal.SaveNews(testNews("b", "c", "d")); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatalf("Failed to close WAL: %v", err)
+	}
+
+	wal, err = storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	defer wal.Close()
+
+	news, err := wal.GetLatestNews(10)
+	if err != nil {
+		t.Fatalf("Failed to read news: %v", err)
+	}
+
+	if len(news) != 3 {
+		t.Fatalf("Expected 3 news after recovery, got %d", len(news))
+	}
+	for _, item := range news {
+		if item.ID == "a" {
+			t.Errorf("Deleted news should not be recovered")
+		}
+	}
+}
+
+// TestWALTornWrite проверяет, что оборванная запись в конце журнала отбрасывается
+func TestWALTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	config := storage.WALConfig{Dir: dir, CompactInterval: time.Hour}
+
+	wal, err := storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	if err = wal.SaveNews(testNews("a", "b")); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+	wal.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if len(segments) != 1 {
+		t.Fatalf("Expected 1 segment, got %d", len(segments))
+	}
+
+	// Имитируем падение посреди записи: дописываем неполный заголовок
+	file, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open segment: %v", err)
+	}
+	file.Write([]byte{0, 0, 1})
+	file.Close()
+
+	wal, err = storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to recover WAL with torn write: %v", err)
+	}
+	defer wal.Close()
+
+	news, _ := wal.GetLatestNews(10)
+	if len(news) != 2 {
+		t.Errorf("Expected 2 news after recovery, got %d", len(news))
+	}
+
+	// После восстановления журнал снова принимает записи
+	if err = wal.SaveNews(testNews("a", "b", "c")); err != nil {
+		t.Errorf("Failed to append after recovery: %v", err)
+	}
+}
+
+// TestWALCompaction проверяет, что уплотнение сохраняет актуальное состояние
+func TestWALCompaction(t *testing.T) {
+	dir := t.TempDir()
+	config := storage.WALConfig{Dir: dir, SegmentSize: 256, CompactInterval: time.Hour}
+
+	wal, err := storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+
+	for _, ids := range [][]string{{"a", "b"}, {"b", "c"}, {"c", "d"}, {"d", "e"}} {
+		if err = wal.SaveNews(testNews(ids...)); err != nil {
+			t.Fatalf("Failed to save news: %v", err)
+		}
+	}
+
+	if err = wal.Compact(); err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+	wal.Close()
+
+	wal, err = storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	defer wal.Close()
+
+	news, _ := wal.GetLatestNews(10)
+	if len(news) != 2 || news[0].ID != "e" || news[1].ID != "d" {
+		t.Errorf("Unexpected state after compaction: %+v", news)
+	}
+}
+
+// TestWALCompactionLeftoverSegment проверяет, что сегмент, который не удалось
+// удалить при уплотнении, не возвращает удаленные новости
+func TestWALCompactionLeftoverSegment(t *testing.T) {
+	dir := t.TempDir()
+	config := storage.WALConfig{Dir: dir, SegmentSize: 256, CompactInterval: time.Hour}
+
+	wal, err := storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+
+	for _, ids := range [][]string{{"a", "b"}, {"b", "c"}, {"c", "d"}, {"d", "e"}} {
+		if err = wal.SaveNews(testNews(ids...)); err != nil {
+			t.Fatalf("Failed to save news: %v", err)
+		}
+	}
+
+	// Первый сегмент содержит "a", а удаление "a" записано в более новый
+	first := filepath.Join(dir, "wal-00000000000000000001.log")
+	leftover, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatalf("Failed to read first segment: %v", err)
+	}
+
+	if err = wal.Compact(); err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+	wal.Close()
+
+	// Имитируем неудачное удаление самого старого сегмента
+	if err = os.WriteFile(first, leftover, 0644); err != nil {
+		t.Fatalf("Failed to restore segment: %v", err)
+	}
+
+	wal, err = storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	defer wal.Close()
+
+	news, _ := wal.GetLatestNews(10)
+	if len(news) != 2 || news[0].ID != "e" || news[1].ID != "d" {
+		t.Errorf("Deleted news resurrected from leftover segment: %+v", news)
+	}
+	if _, err = os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("Expected leftover segment to be removed on recovery")
+	}
+}
+
+// failingSegment обрывает запись после заданного числа байт
+type failingSegment struct {
+	*os.File
+	budget int
+}
+
+func (f *failingSegment) Write(p []byte) (int, error) {
+	if f.budget <= 0 {
+		return 0, errors.New("disk full")
+	}
+	if len(p) > f.budget {
+		n, _ := f.File.Write(p[:f.budget])
+		f.budget = 0
+		return n, errors.New("disk full")
+	}
+	f.budget -= len(p)
+	return f.File.Write(p)
+}
+
+// TestWALFailedWrite проверяет, что неудачная дозапись не оставляет оборванную
+// запись, из-за которой при восстановлении потерялись бы последующие записи
+func TestWALFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	var segment *failingSegment
+	config := storage.WALConfig{
+		Dir:             dir,
+		CompactInterval: time.Hour,
+		OpenSegment: func(path string) (storage.SegmentFile, error) {
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			segment = &failingSegment{File: file, budget: 1 << 20}
+			return segment, nil
+		},
+	}
+
+	wal, err := storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	if err = wal.SaveNews(testNews("a")); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+
+	// Запись обрывается посреди payload
+	segment.budget = 20
+	if err = wal.UpsertNews(testNews("a", "b")); err == nil {
+		t.Fatalf("Expected append to fail")
+	}
+
+	segment.budget = 1 << 20
+	if err = wal.UpsertNews(testNews("a", "b", "c")); err != nil {
+		t.Fatalf("Failed to append after failed write: %v", err)
+	}
+	wal.Close()
+
+	config.OpenSegment = nil
+	wal, err = storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	defer wal.Close()
+
+	news, _ := wal.GetLatestNews(10)
+	if len(news) != 3 {
+		t.Errorf("Expected 3 acknowledged news after recovery, got %d", len(news))
+	}
+}
+
+// TestWALCompactionIdle проверяет, что уплотнение не переписывает состояние,
+// если с прошлого уплотнения ничего не изменилось
+func TestWALCompactionIdle(t *testing.T) {
+	dir := t.TempDir()
+	config := storage.WALConfig{Dir: dir, SegmentSize: 256, CompactInterval: time.Hour}
+
+	wal, err := storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	for _, ids := range [][]string{{"a", "b"}, {"b", "c"}, {"c", "d"}, {"d", "e"}} {
+		if err = wal.SaveNews(testNews(ids...)); err != nil {
+			t.Fatalf("Failed to save news: %v", err)
+		}
+	}
+	if err = wal.Compact(); err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+
+	compacted, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if err = wal.Compact(); err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+	wal.Close()
+
+	// После восстановления уплотнение тоже не требуется
+	wal, err = storage.NewWAL(config)
+	if err != nil {
+		t.Fatalf("Failed to reopen WAL: %v", err)
+	}
+	defer wal.Close()
+	if err = wal.Compact(); err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	if len(segments) != len(compacted) || segments[0] != compacted[0] {
+		t.Errorf("Idle compaction rewrote segments: %v -> %v", compacted, segments)
+	}
+}
+
+// TestSQLStoreQuery проверяет сохранение истории и выборку с фильтрами
+func TestSQLStoreQuery(t *testing.T) {
+	store, err := storage.NewSQLStore(storage.SQLConfig{Path: filepath.Join(t.TempDir(), "news.db")})
+	if err != nil {
+		t.Fatalf("Failed to open SQL store: %v", err)
+	}
+	defer store.Close()
+
+	first := testNews("a", "b", "c")
+	first[0].Source = "Other Source"
+	if err = store.SaveNews(first); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+	// Новости, отсутствующие в следующем сохранении, остаются в истории
+	if err = store.SaveNews(testNews("d")); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+
+	latest, err := store.GetLatestNews(10)
+	if err != nil {
+		t.Fatalf("Failed to read news: %v", err)
+	}
+	if len(latest) != 4 {
+		t.Fatalf("Expected 4 news in history, got %d", len(latest))
+	}
+
+	page, total, err := store.QueryNews(domain.NewsFilter{Source: "test source", Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("Failed to query news: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 matches for source filter, got %d", total)
+	}
+	if len(page) != 1 || page[0].ID != "b" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+}
+
+// TestSQLStorePathAndUndatedNews проверяет путь к базе со специальными символами
+// и новости без даты публикации
+func TestSQLStorePathAndUndatedNews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "news?v=1#main.db")
+	store, err := storage.NewSQLStore(storage.SQLConfig{Path: path})
+	if err != nil {
+		t.Fatalf("Failed to open SQL store: %v", err)
+	}
+	defer store.Close()
+
+	news := testNews("dated", "undated")
+	news[1].PublishedAt = time.Time{}
+	if err = store.SaveNews(news); err != nil {
+		t.Fatalf("Failed to save news: %v", err)
+	}
+	if _, err = os.Stat(path); err != nil {
+		t.Errorf("Expected database at the configured path: %v", err)
+	}
+
+	latest, err := store.GetLatestNews(10)
+	if err != nil {
+		t.Fatalf("Failed to read news: %v", err)
+	}
+	if len(latest) != 2 || latest[0].ID != "dated" || !latest[1].PublishedAt.IsZero() {
+		t.Errorf("Expected undated news to keep a zero date and sort last: %+v", latest)
+	}
+}
+
+// TestFileCacheBackupFallback проверяет чтение резервной копии при поврежденном основном файле
+func TestFileCacheBackupFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "news_cache.json")
+	fileCache, err := storage.NewFileCache(storage.FileConfig{Path: path, Backups: 2})
+	if err != nil {
+		t.Fatalf("Failed to create file cache: %v", err)
+	}
+
+	for _, ids := range [][]string{{"a"}, {"a", "b"}, {"a", "b", "c"}} {
+		if err := fileCache.SaveNews(testNews(ids...)); err != nil {
+			t.Fatalf("Failed to save news: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups to be kept")
+	}
+
+	// Имитируем запись, оборванную на середине
+	if err := os.WriteFile(path, []byte(`[{"id": "a", "ti`), 0644); err != nil {
+		t.Fatalf("Failed to corrupt cache file: %v", err)
+	}
+
+	news, err := fileCache.GetLatestNews(10)
+	if err != nil {
+		t.Fatalf("Expected fallback to backup, got error: %v", err)
+	}
+	if len(news) != 2 {
+		t.Errorf("Expected 2 news from newest backup, got %d", len(news))
+	}
+}
+
+// TestFileCacheSnapshotFormats проверяет сжатые снимки и чтение старого формата без заголовка
+func TestFileCacheSnapshotFormats(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, compression := range []string{storage.CompressionNone, storage.CompressionGzip, storage.CompressionZstd} {
+		path := filepath.Join(dir, compression+".json")
+		fileCache, err := storage.NewFileCache(storage.FileConfig{Path: path, Compression: compression})
+		if err != nil {
+			t.Fatalf("Failed to create file cache: %v", err)
+		}
+		if err = fileCache.SaveNews(testNews("a", "b")); err != nil {
+			t.Fatalf("%s: failed to save news: %v", compression, err)
+		}
+		news, err := fileCache.GetLatestNews(10)
+		if err != nil || len(news) != 2 {
+			t.Errorf("%s: unexpected result %d news, err %v", compression, len(news), err)
+		}
+	}
+
+	// Версия 1: JSON массив без заголовка
+	legacyPath := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(legacyPath, []byte(`[{"id": "a", "title": "Title a", "published_at": "2024-01-01T12:00:00Z"}]`), 0644); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+	fileCache, _ := storage.NewFileCache(storage.FileConfig{Path: legacyPath})
+	news, err := fileCache.GetLatestNews(10)
+	if err != nil || len(news) != 1 || news[0].ID != "a" {
+		t.Errorf("Failed to read legacy snapshot: %+v, err %v", news, err)
+	}
+}
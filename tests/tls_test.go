@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/cache"
+	"github.com/pah-an/infohub/internal/config"
+	"github.com/pah-an/infohub/internal/logger"
+	"github.com/pah-an/infohub/internal/server"
+)
+
+// testCA выпускает сертификаты для тестов TLS
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "InfoHub Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, serial: 1}
+}
+
+// issue выпускает сертификат; mutate задает имена и назначение
+func (ca *testCA) issue(t *testing.T, mutate func(*x509.Certificate)) tls.Certificate {
+	ca.serial++
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	mutate(template)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writePEM сохраняет сертификат и ключ в файлы
+func writePEM(t *testing.T, cert tls.Certificate, certPath, keyPath string) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+// TestMutualTLS проверяет HTTPS с перезагрузкой сертификата и аутентификацию по клиентскому сертификату
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert := func() tls.Certificate {
+		return ca.issue(t, func(c *x509.Certificate) {
+			c.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+			c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		})
+	}
+	clientCert := func(identity string) tls.Certificate {
+		return ca.issue(t, func(c *x509.Certificate) {
+			uri, _ := url.Parse(identity)
+			c.URIs = []*url.URL{uri}
+			c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		})
+	}
+
+	certPath, keyPath, caPath := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt")
+	writePEM(t, serverCert(), certPath, keyPath)
+	os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600)
+
+	tlsConfig, err := server.NewTLSConfig(config.TLSConfig{
+		Enabled:        true,
+		CertFile:       certPath,
+		KeyFile:        keyPath,
+		ClientCAFile:   caPath,
+		ReloadInterval: time.Millisecond,
+	}, logger.New(logger.Config{Level: "error"}))
+	if err != nil {
+		t.Fatalf("Failed to create TLS config: %v", err)
+	}
+
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret: "secret",
+		Enabled:   true,
+		ClientCerts: auth.ClientCertConfig{
+			Enabled:    true,
+			Identities: map[string][]string{"spiffe://internal/reader": {"news:read"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+
+	ts := httptest.NewUnstartedServer(server.NewInfoHubServer(server.Config{
+		NewsProvider: NewMockNewsProvider(),
+		Cache:        memoryCache,
+		Logger:       logger.New(logger.Config{Level: "error"}),
+		AuthManager:  manager,
+	}).Handler())
+	ts.TLS = tlsConfig
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certificates ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certificates},
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get(ts.URL + "/api/v1/news")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	resp, err := get(clientCert("spiffe://internal/reader"))
+	if err != nil {
+		t.Fatalf("TLS request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected client certificate to authenticate, got %d", resp.StatusCode)
+	}
+
+	if resp, err = get(clientCert("spiffe://internal/unknown")); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected unknown certificate identity to be rejected, got %v %v", resp, err)
+	}
+	if resp, err = get(); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected request without certificate to require other credentials, got %v %v", resp, err)
+	}
+
+	// Сертификат другого CA не проходит TLS рукопожатие
+	foreign := newTestCA(t).issue(t, func(c *x509.Certificate) {
+		c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	})
+	if _, err = get(foreign); err == nil {
+		t.Errorf("Expected certificate from unknown CA to be rejected")
+	}
+
+	// Новый сертификат подхватывается без перезапуска
+	renewed := serverCert()
+	writePEM(t, renewed, certPath, keyPath)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certPath, future, future)
+	time.Sleep(5 * time.Millisecond)
+
+	resp, err = get(clientCert("spiffe://internal/reader"))
+	if err != nil {
+		t.Fatalf("TLS request after reload failed: %v", err)
+	}
+	if served := resp.TLS.PeerCertificates[0]; served.SerialNumber.Cmp(renewed.Leaf.SerialNumber) != 0 {
+		t.Errorf("Expected renewed certificate %v, got %v", renewed.Leaf.SerialNumber, served.SerialNumber)
+	}
+}
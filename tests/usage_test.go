@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/pah-an/infohub/internal/auth"
+	"github.com/pah-an/infohub/internal/cache"
+	"github.com/pah-an/infohub/internal/logger"
+	"github.com/pah-an/infohub/internal/server"
+	"github.com/pah-an/infohub/internal/usage"
+)
+
+// TestUsageQuotas проверяет учет запросов по ключам, квоты и endpoints использования
+func TestUsageQuotas(t *testing.T) {
+	usagePath := filepath.Join(t.TempDir(), "usage.json")
+	usageConfig := usage.Config{
+		Enabled:  true,
+		FilePath: usagePath,
+		Quotas:   map[string]usage.Quota{auth.ConfigKeyID("partner-key"): {DailyRequests: 2}},
+	}
+	tracker, err := usage.NewTracker(usageConfig)
+	if err != nil {
+		t.Fatalf("Failed to create usage tracker: %v", err)
+	}
+
+	manager, err := auth.NewManager(auth.Config{
+		JWTSecret:   "secret",
+		Enabled:     true,
+		AdminAPIKey: "admin-key",
+		// Ключи с одинаковым описанием учитываются раздельно
+		APIKeys: map[string]string{"partner-key": "Partner", "other-partner-key": "Partner"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth manager: %v", err)
+	}
+	defer manager.Close()
+
+	memoryCache, _ := cache.NewMemoryCache(cache.MemoryConfig{})
+	defer memoryCache.Close()
+
+	handler := server.NewInfoHubServer(server.Config{
+		NewsProvider: NewMockNewsProvider(),
+		Cache:        memoryCache,
+		Logger:       logger.New(logger.Config{Level: "error"}),
+		AuthManager:  manager,
+		Usage:        tracker,
+	}).Handler()
+
+	request := func(key, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := request("partner-key", "/api/v1/news")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, rec.Code)
+		}
+		if rec.Header().Get("X-Quota-Daily-Limit") != "2" || rec.Header().Get("X-Quota-Daily-Remaining") != remaining {
+			t.Errorf("Request %d: unexpected quota headers %v", i+1, rec.Header())
+		}
+	}
+
+	rec := request("partner-key", "/api/v1/news")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After after the quota, got %d %v", rec.Code, rec.Header())
+	}
+
+	// Свое использование доступно и после исчерпания квоты
+	rec = request("partner-key", "/api/v1/me/usage")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected own usage, got %d", rec.Code)
+	}
+	var own usage.Usage
+	json.NewDecoder(rec.Body).Decode(&own)
+	if own.Consumer != auth.ConfigKeyID("partner-key") || own.Daily.Requests != 2 || own.Daily.Bytes == 0 || own.Quota.DailyRequests != 2 {
+		t.Errorf("Unexpected own usage: %+v", own)
+	}
+
+	if rec = request("other-partner-key", "/api/v1/news"); rec.Code != http.StatusOK {
+		t.Errorf("Expected another key with the same description to keep its own quota, got %d", rec.Code)
+	}
+
+	// Администратор учитывается, но не ограничивается квотой по умолчанию
+	if rec = request("admin-key", "/api/v1/news"); rec.Code != http.StatusOK {
+		t.Errorf("Expected admin request to pass, got %d", rec.Code)
+	}
+	if rec = request("partner-key", "/api/v1/admin/usage"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected usage report to require admin:usage, got %d", rec.Code)
+	}
+	rec = request("admin-key", "/api/v1/admin/usage")
+	var report struct {
+		Count int           `json:"count"`
+		Usage []usage.Usage `json:"usage"`
+	}
+	json.NewDecoder(rec.Body).Decode(&report)
+	if rec.Code != http.StatusOK || report.Count != 3 {
+		t.Errorf("Unexpected usage report: %d %+v", rec.Code, report)
+	}
+
+	// Счетчики переживают перезапуск
+	if err = tracker.Flush(); err != nil {
+		t.Fatalf("Failed to save usage: %v", err)
+	}
+	restored, err := usage.NewTracker(usageConfig)
+	if err != nil {
+		t.Fatalf("Failed to reload usage: %v", err)
+	}
+	if _, err = restored.Acquire(auth.ConfigKeyID("partner-key"), true); !errors.Is(err, usage.ErrQuotaExceeded) {
+		t.Errorf("Expected restored counters to keep the quota exhausted, got %v", err)
+	}
+}